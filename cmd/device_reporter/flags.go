@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
 	"time"
 
 	"github.com/kurochkinivan/device_reporter/internal/app"
-	"github.com/kurochkinivan/device_reporter/internal/config"
+	appconfig "github.com/kurochkinivan/device_reporter/internal/config"
 	altsrc "github.com/urfave/cli-altsrc/v3"
 	"github.com/urfave/cli-altsrc/v3/yaml"
 	"github.com/urfave/cli/v3"
@@ -30,7 +32,10 @@ func cmd() *cli.Command {
 				return errors.New("failed to get logger from context")
 			}
 
-			cfg := config.Load(cmd)
+			cfg, err := appconfig.Load(cmd)
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
 
 			return app.New(log, cfg).Run(ctx)
 		},
@@ -54,7 +59,6 @@ func flags() []cli.Flag {
 			Usage:     "Set directory to watch for new files",
 			Value:     "input",
 			Sources:   cli.NewValueSourceChain(yaml.YAML("app.watch_dir", altsrc.NewStringPtrSourcer(&config))),
-			Required:  true,
 			Validator: validateDirectory,
 		},
 		&cli.StringFlag{
@@ -63,7 +67,6 @@ func flags() []cli.Flag {
 			Usage:     "Set directory to write reports to",
 			Value:     "output",
 			Sources:   cli.NewValueSourceChain(yaml.YAML("app.reports_dir", altsrc.NewStringPtrSourcer(&config))),
-			Required:  true,
 			Validator: validateDirectory,
 		},
 		&cli.DurationFlag{
@@ -74,6 +77,208 @@ func flags() []cli.Flag {
 			Sources:  cli.NewValueSourceChain(yaml.YAML("app.scan_interval", altsrc.NewStringPtrSourcer(&config))),
 			Required: true,
 		},
+		&cli.DurationFlag{
+			Name:      "reconcile-interval",
+			Usage:     "Set how often the directory is fully rescanned in notify mode",
+			Value:     5 * time.Minute,
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.reconcile_interval", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateInterval,
+		},
+		&cli.StringFlag{
+			Name:      "watch-mode",
+			Usage:     "Set directory watch mode: poll or notify",
+			Value:     string(appconfig.WatchModePoll),
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.watch_mode", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateWatchMode,
+		},
+		&cli.StringSliceFlag{
+			Name:      "include",
+			Usage:     "Process only files matching glob `PATTERN`",
+			Value:     []string{"*.tsv"},
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.include", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validatePatterns,
+		},
+		&cli.StringSliceFlag{
+			Name:      "exclude",
+			Usage:     "Skip files matching glob `PATTERN`",
+			Value:     []string{"*.tmp", "*.part"},
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.exclude", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validatePatterns,
+		},
+		&cli.BoolFlag{
+			Name:    "ignore-hidden",
+			Usage:   "Skip dotfiles",
+			Value:   true,
+			Sources: cli.NewValueSourceChain(yaml.YAML("app.ignore_hidden", altsrc.NewStringPtrSourcer(&config))),
+		},
+		&cli.BoolFlag{
+			Name:    "recursive",
+			Usage:   "Watch nested directories too",
+			Sources: cli.NewValueSourceChain(yaml.YAML("app.recursive", altsrc.NewStringPtrSourcer(&config))),
+		},
+		&cli.StringFlag{
+			Name:      "on-change",
+			Usage:     "Set what to do when a processed file gets new content: ignore or reingest",
+			Value:     string(appconfig.ChangePolicyReingest),
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.on_change", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateChangePolicy,
+		},
+		&cli.IntFlag{
+			Name:    "settle-observations",
+			Usage:   "Set number of consecutive scans a file must stay unchanged before it is processed",
+			Value:   2,
+			Sources: cli.NewValueSourceChain(yaml.YAML("app.settle.observations", altsrc.NewStringPtrSourcer(&config))),
+		},
+		&cli.DurationFlag{
+			Name:      "settle-interval",
+			Usage:     "Set how often unsettled files are re-checked in notify mode",
+			Value:     1 * time.Second,
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.settle.interval", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateInterval,
+		},
+		&cli.BoolFlag{
+			Name:    "require-done-marker",
+			Usage:   "Process a file only after its sidecar <file>.done marker appears",
+			Sources: cli.NewValueSourceChain(yaml.YAML("app.settle.require_done_marker", altsrc.NewStringPtrSourcer(&config))),
+		},
+		&cli.StringFlag{
+			Name:      "delimiter",
+			Usage:     "Set field delimiter of CSV input, a single character, \\t or auto",
+			Value:     "auto",
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.delimiter", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateDelimiter,
+		},
+		&cli.StringFlag{
+			Name:      "encoding",
+			Usage:     "Set encoding of text input: auto, utf-8, windows-1251, utf-16le or utf-16be",
+			Value:     string(appconfig.EncodingAuto),
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.encoding", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateEncoding,
+		},
+		&cli.BoolFlag{
+			Name:    "collect-row-errors",
+			Usage:   "Validate every row of a file and store all row errors instead of stopping at the first one",
+			Sources: cli.NewValueSourceChain(yaml.YAML("app.collect_row_errors", altsrc.NewStringPtrSourcer(&config))),
+		},
+		&cli.StringFlag{
+			Name:      "row-policy",
+			Usage:     "Set what to do with a file that has invalid rows: reject_file or accept_valid",
+			Value:     string(appconfig.RowPolicyRejectFile),
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.row_policy", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateRowPolicy,
+		},
+		&cli.IntFlag{
+			Name:      "parse-workers",
+			Usage:     "Set number of files parsed at once",
+			Value:     1,
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.workers.parse", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateWorkers,
+		},
+		&cli.IntFlag{
+			Name:      "write-workers",
+			Usage:     "Set number of files saved to the database at once",
+			Value:     1,
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.workers.write", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateWorkers,
+		},
+		&cli.IntFlag{
+			Name:      "report-workers",
+			Usage:     "Set number of unit reports of a file rendered at once, in all formats",
+			Value:     runtime.NumCPU(),
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.workers.report", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateWorkers,
+		},
+		&cli.StringFlag{
+			Name:      "report-mode",
+			Usage:     "Set records a unit report covers: file (the processed file) or cumulative (every stored file of the unit)",
+			Value:     string(appconfig.ReportModeFile),
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.reports.mode", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateReportMode,
+		},
+		&cli.DurationFlag{
+			Name:      "report-window",
+			Usage:     "Limit cumulative reports to records stored within the window, 0 for all records",
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.reports.window", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateReportWindow,
+		},
+		&cli.StringSliceFlag{
+			Name:      "report-formats",
+			Usage:     "Render every report in `FORMATS`: pdf, html, xlsx, csv and json",
+			Value:     []string{appconfig.ReportFormatPDF},
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.reports.formats", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateReportFormats,
+		},
+		&cli.StringFlag{
+			Name:      "report-name",
+			Usage:     "Set report path `TEMPLATE` within the reports directory: {guid}, {file}, {timestamp} and {version} are substituted",
+			Value:     "{guid}/v{version}_{file}_{timestamp}",
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.reports.name", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateReportName,
+		},
+		&cli.BoolFlag{
+			Name:    "report-latest-link",
+			Usage:   "Keep <unit_guid>.<format> in the reports directory as a symlink to the latest report of the unit",
+			Value:   true,
+			Sources: cli.NewValueSourceChain(yaml.YAML("app.reports.latest_link", altsrc.NewStringPtrSourcer(&config))),
+		},
+		&cli.BoolFlag{
+			Name:    "report-fleet",
+			Usage:   "Write fleet reports listing the units of every processed file and of its inventory IDs",
+			Value:   true,
+			Sources: cli.NewValueSourceChain(yaml.YAML("app.reports.fleet", altsrc.NewStringPtrSourcer(&config))),
+		},
+		&cli.StringFlag{
+			Name:      "report-layouts-dir",
+			Usage:     "Load report layouts from `DIR`, one <layout>.yaml per layout",
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.reports.layouts_dir", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateDirectory,
+		},
+		&cli.StringFlag{
+			Name:    "report-layout",
+			Usage:   "Set `LAYOUT` of PDF and HTML reports, the built-in one or one from the layouts directory",
+			Value:   "default",
+			Sources: cli.NewValueSourceChain(yaml.YAML("app.reports.layout", altsrc.NewStringPtrSourcer(&config))),
+		},
+		&cli.StringFlag{
+			Name:      "report-locale",
+			Usage:     "Set language of report labels and of number and date formats: en or ru",
+			Value:     appconfig.ReportLocaleEN,
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.reports.locale", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateReportLocale,
+		},
+		&cli.StringFlag{
+			Name:      "report-timezone",
+			Usage:     "Show report times in `ZONE`, an IANA time zone such as Europe/Moscow",
+			Value:     "UTC",
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.reports.timezone", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validateTimeZone,
+		},
+		&cli.StringFlag{
+			Name:      "on-done",
+			Usage:     "Set what to do with a processed or duplicate file: keep, move or delete",
+			Value:     string(appconfig.PostActionKeep),
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.archive.on_done", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validatePostAction,
+		},
+		&cli.StringFlag{
+			Name:      "on-error",
+			Usage:     "Set what to do with a file that failed to process: keep, move or delete",
+			Value:     string(appconfig.PostActionKeep),
+			Sources:   cli.NewValueSourceChain(yaml.YAML("app.archive.on_error", altsrc.NewStringPtrSourcer(&config))),
+			Validator: validatePostAction,
+		},
+		&cli.StringFlag{
+			Name:    "done-dir",
+			Usage:   "Set directory processed files are moved to",
+			Value:   "done",
+			Sources: cli.NewValueSourceChain(yaml.YAML("app.archive.done_dir", altsrc.NewStringPtrSourcer(&config))),
+		},
+		&cli.StringFlag{
+			Name:    "error-dir",
+			Usage:   "Set quarantine directory failed files are moved to",
+			Value:   "error",
+			Sources: cli.NewValueSourceChain(yaml.YAML("app.archive.error_dir", altsrc.NewStringPtrSourcer(&config))),
+		},
 		&cli.StringFlag{
 			Name:     "pg-host",
 			Usage:    "Set PostgreSQL host",
@@ -156,6 +361,111 @@ func validateDirectory(dir string) error {
 	return nil
 }
 
+func validateWatchMode(mode string) error {
+	switch appconfig.WatchMode(mode) {
+	case appconfig.WatchModePoll, appconfig.WatchModeNotify:
+		return nil
+	default:
+		return fmt.Errorf("unknown watch mode %q", mode)
+	}
+}
+
+func validateRowPolicy(policy string) error {
+	switch appconfig.RowPolicy(policy) {
+	case appconfig.RowPolicyRejectFile, appconfig.RowPolicyAcceptValid:
+		return nil
+	default:
+		return fmt.Errorf("unknown row policy %q", policy)
+	}
+}
+
+func validateChangePolicy(policy string) error {
+	switch appconfig.ChangePolicy(policy) {
+	case appconfig.ChangePolicyIgnore, appconfig.ChangePolicyReingest:
+		return nil
+	default:
+		return fmt.Errorf("unknown change policy %q", policy)
+	}
+}
+
+func validateDelimiter(delimiter string) error {
+	_, err := appconfig.ParseDelimiter(delimiter)
+	return err
+}
+
+func validateEncoding(encoding string) error {
+	return appconfig.ValidateEncoding(appconfig.Encoding(encoding))
+}
+
+func validateWorkers(workers int) error {
+	if workers < 1 {
+		return fmt.Errorf("number of workers must be positive, got %d", workers)
+	}
+
+	return nil
+}
+
+func validateReportMode(mode string) error {
+	switch appconfig.ReportMode(mode) {
+	case appconfig.ReportModeFile, appconfig.ReportModeCumulative:
+		return nil
+	default:
+		return fmt.Errorf("unknown report mode %q", mode)
+	}
+}
+
+func validateInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("interval must be positive, got %s", interval)
+	}
+
+	return nil
+}
+
+func validateReportWindow(window time.Duration) error {
+	if window < 0 {
+		return fmt.Errorf("report window must not be negative, got %s", window)
+	}
+
+	return nil
+}
+
+func validateReportFormats(formats []string) error {
+	return appconfig.ValidateReportFormats(formats)
+}
+
+func validateReportName(template string) error {
+	return appconfig.ValidateReportName(template)
+}
+
+func validateReportLocale(locale string) error {
+	return appconfig.ValidateReportLocale(locale)
+}
+
+func validateTimeZone(zone string) error {
+	_, err := time.LoadLocation(zone)
+	return err
+}
+
+func validatePostAction(action string) error {
+	switch appconfig.PostAction(action) {
+	case appconfig.PostActionKeep, appconfig.PostActionMove, appconfig.PostActionDelete:
+		return nil
+	default:
+		return fmt.Errorf("unknown post action %q", action)
+	}
+}
+
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
 func validateConfig(config string) error {
 	info, err := os.Stat(config)
 	if err != nil {
@@ -0,0 +1,41 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_devices_file_name;
+
+ALTER TABLE devices DROP COLUMN IF EXISTS file_name;
+
+DROP INDEX IF EXISTS idx_files_sha256;
+
+DELETE FROM files WHERE status = 'duplicate';
+
+ALTER TABLE files
+    DROP CONSTRAINT files_processed_at_check,
+    DROP CONSTRAINT files_error_message_check,
+    DROP COLUMN IF EXISTS size,
+    DROP COLUMN IF EXISTS modified_at,
+    DROP COLUMN IF EXISTS sha256,
+    DROP COLUMN IF EXISTS duplicate_of;
+
+ALTER TYPE files_status RENAME TO files_status_old;
+
+CREATE TYPE files_status AS ENUM (
+    'pending',
+    'processing',
+    'done',
+    'error'
+);
+
+ALTER TABLE files
+    ALTER COLUMN status DROP DEFAULT,
+    ALTER COLUMN status TYPE files_status USING status::TEXT::files_status,
+    ALTER COLUMN status SET DEFAULT 'pending';
+
+DROP TYPE files_status_old;
+
+ALTER TABLE files
+    ADD CONSTRAINT files_error_message_check
+        CHECK (error_message IS NULL OR error_message = '' OR status = 'error'),
+    ADD CONSTRAINT files_processed_at_check
+        CHECK (processed_at IS NULL OR status IN ('done', 'error'));
+
+COMMIT;
@@ -0,0 +1,25 @@
+BEGIN;
+
+ALTER TYPE files_status ADD VALUE IF NOT EXISTS 'duplicate';
+
+COMMIT;
+
+BEGIN;
+
+ALTER TABLE files
+    ADD COLUMN size         BIGINT      NOT NULL DEFAULT 0,
+    ADD COLUMN modified_at  TIMESTAMPTZ,
+    ADD COLUMN sha256       TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN duplicate_of TEXT        NOT NULL DEFAULT '';
+
+ALTER TABLE files DROP CONSTRAINT files_processed_at_check;
+ALTER TABLE files ADD CONSTRAINT files_processed_at_check
+    CHECK (processed_at IS NULL OR status IN ('done', 'error', 'duplicate'));
+
+CREATE INDEX idx_files_sha256 ON files(sha256);
+
+ALTER TABLE devices ADD COLUMN file_name TEXT;
+
+CREATE INDEX idx_devices_file_name ON devices(file_name);
+
+COMMIT;
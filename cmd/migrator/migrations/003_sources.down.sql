@@ -0,0 +1,19 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_devices_source_file_name;
+CREATE INDEX idx_devices_file_name ON devices(file_name);
+
+ALTER TABLE devices DROP COLUMN IF EXISTS source;
+
+DROP INDEX IF EXISTS idx_files_source_sha256;
+CREATE INDEX idx_files_sha256 ON files(sha256);
+
+-- без источника имена файлов из разных директорий могут совпасть
+DELETE FROM files WHERE source <> 'default';
+
+ALTER TABLE files DROP CONSTRAINT files_pkey;
+ALTER TABLE files ADD PRIMARY KEY (name);
+
+ALTER TABLE files DROP COLUMN IF EXISTS source;
+
+COMMIT;
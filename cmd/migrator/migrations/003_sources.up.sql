@@ -0,0 +1,16 @@
+BEGIN;
+
+ALTER TABLE files ADD COLUMN source TEXT NOT NULL DEFAULT 'default';
+
+ALTER TABLE files DROP CONSTRAINT files_pkey;
+ALTER TABLE files ADD PRIMARY KEY (source, name);
+
+DROP INDEX IF EXISTS idx_files_sha256;
+CREATE INDEX idx_files_source_sha256 ON files(source, sha256);
+
+ALTER TABLE devices ADD COLUMN source TEXT NOT NULL DEFAULT 'default';
+
+DROP INDEX IF EXISTS idx_devices_file_name;
+CREATE INDEX idx_devices_source_file_name ON devices(source, file_name);
+
+COMMIT;
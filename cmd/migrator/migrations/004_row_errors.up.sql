@@ -0,0 +1,16 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS row_errors (
+    id          BIGSERIAL PRIMARY KEY,
+    source      TEXT      NOT NULL,
+    file_name   TEXT      NOT NULL,
+    line        INTEGER   NOT NULL,
+    column_name TEXT      NOT NULL DEFAULT '',
+    value       TEXT      NOT NULL DEFAULT '',
+    reason      TEXT      NOT NULL,
+    FOREIGN KEY (source, file_name) REFERENCES files(source, name) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_row_errors_source_file_name ON row_errors(source, file_name, line);
+
+COMMIT;
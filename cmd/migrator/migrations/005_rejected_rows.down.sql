@@ -0,0 +1,35 @@
+BEGIN;
+
+DROP TABLE IF EXISTS rejected_rows;
+
+-- валидные строки таких файлов сохранены, поэтому они считаются обработанными
+UPDATE files SET status = 'done', error_message = '' WHERE status = 'done_with_errors';
+
+ALTER TABLE files
+    DROP CONSTRAINT files_processed_at_check,
+    DROP CONSTRAINT files_error_message_check;
+
+ALTER TYPE files_status RENAME TO files_status_old;
+
+CREATE TYPE files_status AS ENUM (
+    'pending',
+    'processing',
+    'done',
+    'error',
+    'duplicate'
+);
+
+ALTER TABLE files
+    ALTER COLUMN status DROP DEFAULT,
+    ALTER COLUMN status TYPE files_status USING status::TEXT::files_status,
+    ALTER COLUMN status SET DEFAULT 'pending';
+
+DROP TYPE files_status_old;
+
+ALTER TABLE files
+    ADD CONSTRAINT files_error_message_check
+        CHECK (error_message IS NULL OR error_message = '' OR status = 'error'),
+    ADD CONSTRAINT files_processed_at_check
+        CHECK (processed_at IS NULL OR status IN ('done', 'error', 'duplicate'));
+
+COMMIT;
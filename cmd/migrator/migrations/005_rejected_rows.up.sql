@@ -0,0 +1,29 @@
+BEGIN;
+
+ALTER TYPE files_status ADD VALUE IF NOT EXISTS 'done_with_errors';
+
+COMMIT;
+
+BEGIN;
+
+ALTER TABLE files DROP CONSTRAINT files_error_message_check;
+ALTER TABLE files ADD CONSTRAINT files_error_message_check
+    CHECK (error_message IS NULL OR error_message = '' OR status IN ('error', 'done_with_errors'));
+
+ALTER TABLE files DROP CONSTRAINT files_processed_at_check;
+ALTER TABLE files ADD CONSTRAINT files_processed_at_check
+    CHECK (processed_at IS NULL OR status IN ('done', 'done_with_errors', 'error', 'duplicate'));
+
+CREATE TABLE IF NOT EXISTS rejected_rows (
+    id        BIGSERIAL PRIMARY KEY,
+    source    TEXT      NOT NULL,
+    file_name TEXT      NOT NULL,
+    line      INTEGER   NOT NULL,
+    raw       TEXT      NOT NULL,
+    reason    TEXT      NOT NULL,
+    FOREIGN KEY (source, file_name) REFERENCES files(source, name) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_rejected_rows_source_file_name ON rejected_rows(source, file_name, line);
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE files ADD COLUMN rows_processed BIGINT NOT NULL DEFAULT 0;
+
+COMMIT;
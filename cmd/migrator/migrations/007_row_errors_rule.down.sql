@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE row_errors DROP COLUMN IF EXISTS rule;
+
+COMMIT;
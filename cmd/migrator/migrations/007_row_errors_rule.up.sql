@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE row_errors ADD COLUMN rule TEXT NOT NULL DEFAULT '';
+
+COMMIT;
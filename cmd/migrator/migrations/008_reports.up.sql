@@ -0,0 +1,17 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS reports (
+    source        TEXT        NOT NULL,
+    file_name     TEXT        NOT NULL,
+    unit_guid     UUID        NOT NULL,
+    path          TEXT        NOT NULL,
+    status        TEXT        NOT NULL CHECK (status IN ('done', 'error')),
+    error_message TEXT        NOT NULL DEFAULT '',
+    generated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (source, file_name, unit_guid),
+    FOREIGN KEY (source, file_name) REFERENCES files(source, name) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_reports_status ON reports(status);
+
+COMMIT;
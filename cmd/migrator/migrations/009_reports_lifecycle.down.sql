@@ -0,0 +1,19 @@
+BEGIN;
+
+DELETE FROM reports WHERE status = 'pending';
+
+ALTER TABLE reports ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE reports DROP CONSTRAINT reports_status_check;
+ALTER TABLE reports ADD CONSTRAINT reports_status_check
+    CHECK (status IN ('done', 'error'));
+
+UPDATE reports SET generated_at = NOW() WHERE generated_at IS NULL;
+ALTER TABLE reports ALTER COLUMN generated_at SET DEFAULT NOW();
+ALTER TABLE reports ALTER COLUMN generated_at SET NOT NULL;
+ALTER TABLE reports ALTER COLUMN path DROP DEFAULT;
+
+ALTER TABLE reports DROP COLUMN IF EXISTS sha256;
+ALTER TABLE reports DROP COLUMN IF EXISTS size;
+ALTER TABLE reports DROP COLUMN IF EXISTS format;
+
+COMMIT;
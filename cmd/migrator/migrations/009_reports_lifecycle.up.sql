@@ -0,0 +1,17 @@
+BEGIN;
+
+ALTER TABLE reports ADD COLUMN format TEXT   NOT NULL DEFAULT '';
+ALTER TABLE reports ADD COLUMN size   BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE reports ADD COLUMN sha256 TEXT   NOT NULL DEFAULT '';
+
+-- строка отчёта создаётся вместе с сохранением файла, путь и время генерации заполняет Reporter
+ALTER TABLE reports ALTER COLUMN path SET DEFAULT '';
+ALTER TABLE reports ALTER COLUMN generated_at DROP NOT NULL;
+ALTER TABLE reports ALTER COLUMN generated_at DROP DEFAULT;
+
+ALTER TABLE reports DROP CONSTRAINT reports_status_check;
+ALTER TABLE reports ADD CONSTRAINT reports_status_check
+    CHECK (status IN ('pending', 'done', 'error'));
+ALTER TABLE reports ALTER COLUMN status SET DEFAULT 'pending';
+
+COMMIT;
@@ -0,0 +1,20 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS report_jobs (
+    id           BIGSERIAL   PRIMARY KEY,
+    source       TEXT        NOT NULL,
+    file_name    TEXT        NOT NULL,
+    attempts     INTEGER     NOT NULL DEFAULT 0,
+    last_error   TEXT        NOT NULL DEFAULT '',
+    available_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (source, file_name) REFERENCES files(source, name) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_report_jobs_available_at ON report_jobs(available_at, id);
+
+-- отчёты, не построенные до обновления, получают задания, чтобы их не потерять
+INSERT INTO report_jobs (source, file_name)
+SELECT DISTINCT source, file_name FROM reports WHERE status IN ('pending', 'error');
+
+COMMIT;
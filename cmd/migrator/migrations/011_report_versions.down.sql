@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS report_versions;
+
+ALTER TABLE reports DROP COLUMN IF EXISTS version;
+
+COMMIT;
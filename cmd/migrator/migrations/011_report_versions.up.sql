@@ -0,0 +1,30 @@
+BEGIN;
+
+ALTER TABLE reports ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS report_versions (
+    source       TEXT        NOT NULL,
+    unit_guid    UUID        NOT NULL,
+    version      INTEGER     NOT NULL,
+    file_name    TEXT        NOT NULL,
+    path         TEXT        NOT NULL,
+    format       TEXT        NOT NULL,
+    size         BIGINT      NOT NULL,
+    sha256       TEXT        NOT NULL,
+    generated_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (source, unit_guid, version)
+);
+
+-- раньше отчёт устройства перезаписывался, на диске остался только последний из них
+INSERT INTO report_versions (source, unit_guid, version, file_name, path, format, size, sha256, generated_at)
+SELECT DISTINCT ON (source, unit_guid)
+    source, unit_guid, 1, file_name, path, format, size, sha256, generated_at
+FROM reports
+WHERE status = 'done'
+ORDER BY source, unit_guid, generated_at DESC;
+
+UPDATE reports r SET version = 1
+FROM report_versions v
+WHERE r.source = v.source AND r.unit_guid = v.unit_guid AND r.file_name = v.file_name;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_devices_source_unit_guid_created_at;
+
+COMMIT;
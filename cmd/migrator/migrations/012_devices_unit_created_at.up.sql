@@ -0,0 +1,6 @@
+BEGIN;
+
+-- накопительный отчёт читает все записи устройства в источнике в порядке сохранения
+CREATE INDEX IF NOT EXISTS idx_devices_source_unit_guid_created_at ON devices(source, unit_guid, created_at);
+
+COMMIT;
@@ -0,0 +1,33 @@
+BEGIN;
+
+ALTER TABLE reports
+    ADD COLUMN path   TEXT   NOT NULL DEFAULT '',
+    ADD COLUMN format TEXT   NOT NULL DEFAULT '',
+    ADD COLUMN size   BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN sha256 TEXT   NOT NULL DEFAULT '';
+
+ALTER TABLE report_versions
+    ADD COLUMN path   TEXT   NOT NULL DEFAULT '',
+    ADD COLUMN format TEXT   NOT NULL DEFAULT '',
+    ADD COLUMN size   BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN sha256 TEXT   NOT NULL DEFAULT '';
+
+-- остаётся только первый файл отчёта
+UPDATE reports
+SET path   = files->0->>'path',
+    format = files->0->>'format',
+    size   = (files->0->>'size')::BIGINT,
+    sha256 = files->0->>'sha256'
+WHERE jsonb_array_length(files) > 0;
+
+UPDATE report_versions
+SET path   = files->0->>'path',
+    format = files->0->>'format',
+    size   = (files->0->>'size')::BIGINT,
+    sha256 = files->0->>'sha256'
+WHERE jsonb_array_length(files) > 0;
+
+ALTER TABLE reports DROP COLUMN files;
+ALTER TABLE report_versions DROP COLUMN files;
+
+COMMIT;
@@ -0,0 +1,26 @@
+BEGIN;
+
+-- отчёт устройства строится в нескольких форматах, файлы хранятся списком
+ALTER TABLE reports ADD COLUMN files JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE report_versions ADD COLUMN files JSONB NOT NULL DEFAULT '[]';
+
+UPDATE reports
+SET files = jsonb_build_array(jsonb_build_object('format', format, 'path', path, 'size', size, 'sha256', sha256))
+WHERE path <> '';
+
+UPDATE report_versions
+SET files = jsonb_build_array(jsonb_build_object('format', format, 'path', path, 'size', size, 'sha256', sha256));
+
+ALTER TABLE reports
+    DROP COLUMN path,
+    DROP COLUMN format,
+    DROP COLUMN size,
+    DROP COLUMN sha256;
+
+ALTER TABLE report_versions
+    DROP COLUMN path,
+    DROP COLUMN format,
+    DROP COLUMN size,
+    DROP COLUMN sha256;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_devices_source_inv_id;
+
+COMMIT;
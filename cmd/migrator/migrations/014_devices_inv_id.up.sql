@@ -0,0 +1,6 @@
+BEGIN;
+
+-- отчёт по инвентарному номеру собирает устройства номера по всем файлам источника
+CREATE INDEX IF NOT EXISTS idx_devices_source_inv_id ON devices(source, inv_id);
+
+COMMIT;
@@ -12,6 +12,8 @@ import (
 	"github.com/kurochkinivan/device_reporter/internal/config"
 	v1 "github.com/kurochkinivan/device_reporter/internal/controller/http/v1"
 	"github.com/kurochkinivan/device_reporter/internal/domain"
+	"github.com/kurochkinivan/device_reporter/internal/infrastructure/archiver"
+	"github.com/kurochkinivan/device_reporter/internal/infrastructure/formats"
 	"github.com/kurochkinivan/device_reporter/internal/infrastructure/report_generator"
 	"github.com/kurochkinivan/device_reporter/internal/pipeline"
 	"github.com/kurochkinivan/device_reporter/internal/repository/postgresql"
@@ -22,6 +24,8 @@ const (
 	filesBuffer        = 100
 	parseResultsBuffer = 50
 	reportsBuffer      = 100
+	// reservedConns are left for scanners, the reporter and the HTTP server.
+	reservedConns = 4
 )
 
 type App struct {
@@ -37,11 +41,32 @@ func New(log *slog.Logger, cfg *config.Config) *App {
 }
 
 func (a *App) Run(ctx context.Context) error {
-	a.log.InfoContext(ctx, "starting app",
-		slog.String("watch_dir", a.cfg.App.WatchDirectory),
-		slog.String("reports_dir", a.cfg.App.ReportsDirectory),
-		slog.Duration("scan_interval", a.cfg.App.DirectoryScanInterval),
-	)
+	a.log.InfoContext(ctx, "starting app", slog.Int("sources", len(a.cfg.Sources)))
+
+	for _, source := range a.cfg.Sources {
+		a.log.InfoContext(ctx, "configured source",
+			slog.String("source", source.Name),
+			slog.String("watch_dir", source.Scanner.WatchDirectory),
+			slog.String("watch_mode", string(source.Scanner.WatchMode)),
+			slog.String("reports_dir", source.ReportsDirectory),
+			slog.String("report_layout", source.ReportStyle.Layout),
+			slog.String("report_locale", source.ReportStyle.Locale),
+			slog.String("report_timezone", source.ReportStyle.TimeZone.String()),
+			slog.Duration("scan_interval", source.Scanner.ScanInterval),
+			slog.Duration("reconcile_interval", source.Scanner.ReconcileInterval),
+		)
+	}
+
+	layouts, err := report_generator.LoadLayouts(a.cfg.Reports.LayoutsDirectory)
+	if err != nil {
+		return fmt.Errorf("failed to load report layouts: %w", err)
+	}
+
+	for _, source := range a.cfg.Sources {
+		if _, ok := layouts[source.ReportStyle.Layout]; !ok {
+			return fmt.Errorf("source %q: unknown report layout %q", source.Name, source.ReportStyle.Layout)
+		}
+	}
 
 	a.log.InfoContext(ctx, "establishing postgresql connection",
 		slog.String("postgresql_host", a.cfg.PostgreSQL.Host),
@@ -49,7 +74,11 @@ func (a *App) Run(ctx context.Context) error {
 		slog.String("postgresql_dbname", a.cfg.PostgreSQL.DBName),
 	)
 
-	pool, err := postgresql.NewConnection(ctx, a.log, a.cfg.PostgreSQL)
+	// воркер записи держит соединение транзакции и берёт ещё одно, чтобы записать прогресс,
+	// поэтому пул меньше двух соединений на воркер может заблокировать запись
+	poolSize := int32(2*a.cfg.Workers.Write + reservedConns)
+
+	pool, err := postgresql.NewConnection(ctx, a.log, a.cfg.PostgreSQL, poolSize)
 	if err != nil {
 		return fmt.Errorf("failed to create db connection: %w", err)
 	}
@@ -57,57 +86,118 @@ func (a *App) Run(ctx context.Context) error {
 
 	filesRepository := postgresql.NewFilesRepository(pool)
 	devicesRepository := postgresql.NewDevicesRepository(pool)
+	rowErrorsRepository := postgresql.NewRowErrorsRepository(pool)
+	rejectedRowsRepository := postgresql.NewRejectedRowsRepository(pool)
+	reportsRepository := postgresql.NewReportsRepository(pool)
 	txManager := postgresql.NewTxManager(pool)
 
 	if err := filesRepository.ResetProcessingFiles(ctx); err != nil {
 		return fmt.Errorf("failed to reset processing files: %w", err)
 	}
 
-	return a.startPipeline(ctx, filesRepository, devicesRepository, txManager)
+	return a.startPipeline(ctx, layouts, filesRepository, devicesRepository, rowErrorsRepository, rejectedRowsRepository, reportsRepository, txManager)
 }
 
 func (a *App) startPipeline(
 	ctx context.Context,
+	layouts report_generator.Layouts,
 	filesRepo *postgresql.FilesRepository,
 	devicesRepo *postgresql.DevicesRepository,
+	rowErrorsRepo *postgresql.RowErrorsRepository,
+	rejectedRowsRepo *postgresql.RejectedRowsRepository,
+	reportsRepo *postgresql.ReportsRepository,
 	txManager *postgresql.TxManager,
 ) error {
-	files := make(chan string, filesBuffer)
+	files := make(chan *domain.File, filesBuffer)
 	parseResults := make(chan *domain.ParseResult, parseResultsBuffer)
 	reports := make(chan *domain.ParseResult, reportsBuffer)
 
-	scanner := pipeline.NewScanner(
+	fileArchiver := archiver.New(a.cfg.Archive)
+
+	scanners := make([]*pipeline.Scanner, 0, len(a.cfg.Sources))
+	parserSettings := make(map[string]config.Parser, len(a.cfg.Sources))
+	reportsDirs := make(map[string]string, len(a.cfg.Sources))
+	reportStyles := make(map[string]config.ReportStyle, len(a.cfg.Sources))
+
+	for _, source := range a.cfg.Sources {
+		log := a.log.With(slog.String("source", source.Name))
+		scanners = append(scanners, pipeline.NewScanner(log, source.Name, source.Scanner, files, filesRepo, filesRepo, fileArchiver))
+		parserSettings[source.Name] = source.Parser
+		reportsDirs[source.Name] = source.ReportsDirectory
+		reportStyles[source.Name] = source.ReportStyle
+	}
+
+	inputFormats := []pipeline.Format{
+		formats.NewJSONL(),
+		formats.NewXLSX(),
+		formats.NewCSV(), // распознаёт любой файл, поэтому последний
+	}
+
+	parser := pipeline.NewParser(a.log, a.cfg.Workers.Parse, parserSettings, inputFormats, files, parseResults)
+	writer := pipeline.NewWriter(
 		a.log,
-		a.cfg.WatchDirectory,
-		a.cfg.DirectoryScanInterval,
-		files,
-		filesRepo,
+		a.cfg.Workers.Write,
+		parseResults,
+		reports,
 		filesRepo,
+		devicesRepo,
+		rowErrorsRepo,
+		rejectedRowsRepo,
+		reportsRepo,
+		txManager,
+		fileArchiver,
 	)
-	parser := pipeline.NewParser(a.log, files, parseResults)
-	writer := pipeline.NewWriter(a.log, parseResults, reports, filesRepo, devicesRepo, txManager)
-	reporter := pipeline.NewReporter(a.log, a.cfg.ReportsDirectory, reports, report_generator.New())
-	server := v1.NewServer(a.cfg.HTTP, devicesRepo)
+	reporter := pipeline.NewReporter(
+		a.log,
+		a.cfg.Workers.Report,
+		a.cfg.Reports,
+		reportsDirs,
+		reportStyles,
+		reports,
+		devicesRepo,
+		devicesRepo,
+		reportsRepo,
+		reportsRepo,
+		[]pipeline.ReportGenerator{
+			report_generator.NewPDF(layouts),
+			report_generator.NewHTML(layouts),
+			report_generator.NewXLSX(),
+			report_generator.NewCSV(),
+			report_generator.NewJSON(),
+		},
+	)
+	server := v1.NewServer(a.cfg.HTTP, devicesRepo, rowErrorsRepo, reportsRepo)
 
 	erg, ctx := errgroup.WithContext(ctx)
 
 	erg.Go(func() error {
-		a.log.InfoContext(ctx, "scanner started")
-		return scanner.Run(ctx)
+		// все сканеры пишут в один канал, закрываем его после остановки последнего
+		defer close(files)
+
+		scg, ctx := errgroup.WithContext(ctx)
+		for _, scanner := range scanners {
+			scg.Go(func() error {
+				return scanner.Run(ctx)
+			})
+		}
+
+		a.log.InfoContext(ctx, "scanners started", slog.Int("count", len(scanners)))
+
+		return scg.Wait()
 	})
 
 	erg.Go(func() error {
-		a.log.InfoContext(ctx, "parser started")
+		a.log.InfoContext(ctx, "parser started", slog.Int("workers", a.cfg.Workers.Parse))
 		return parser.Run(ctx)
 	})
 
 	erg.Go(func() error {
-		a.log.InfoContext(ctx, "writer started")
+		a.log.InfoContext(ctx, "writer started", slog.Int("workers", a.cfg.Workers.Write))
 		return writer.Run(ctx)
 	})
 
 	erg.Go(func() error {
-		a.log.InfoContext(ctx, "reporter started")
+		a.log.InfoContext(ctx, "reporter started", slog.Int("workers", a.cfg.Workers.Report))
 		return reporter.Run(ctx)
 	})
 
@@ -1,21 +1,229 @@
 package config
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/urfave/cli/v3"
 )
 
 type Config struct {
-	App
+	Sources []Source
+	Workers
+	Reports
+	Archive
 	PostgreSQL
 	HTTP
 }
 
-type App struct {
-	WatchDirectory        string
-	ReportsDirectory      string
-	DirectoryScanInterval time.Duration
+// Source is a watched directory together with the settings its files are processed with.
+type Source struct {
+	Name             string
+	ReportsDirectory string
+	ReportStyle      ReportStyle
+	Scanner          Scanner
+	Parser           Parser
+}
+
+type WatchMode string
+
+const (
+	WatchModePoll   WatchMode = "poll"
+	WatchModeNotify WatchMode = "notify"
+)
+
+// ChangePolicy decides what happens when a processed file reappears with new content.
+type ChangePolicy string
+
+const (
+	ChangePolicyIgnore   ChangePolicy = "ignore"
+	ChangePolicyReingest ChangePolicy = "reingest"
+)
+
+// RowPolicy decides what happens to a file that has invalid rows.
+type RowPolicy string
+
+const (
+	// RowPolicyRejectFile fails the whole file.
+	RowPolicyRejectFile RowPolicy = "reject_file"
+	// RowPolicyAcceptValid saves the valid rows and quarantines the invalid ones.
+	RowPolicyAcceptValid RowPolicy = "accept_valid"
+)
+
+type Scanner struct {
+	WatchDirectory string
+	WatchMode      WatchMode
+	// ScanInterval is the polling period in poll mode.
+	ScanInterval time.Duration
+	// ReconcileInterval is the period of the full scan in notify mode, which picks up
+	// files whose events were lost. It is much longer than ScanInterval, since events
+	// deliver new files right away.
+	ReconcileInterval time.Duration
+	// Include and Exclude are glob patterns matched against the base name,
+	// or against the relative path if the pattern contains a slash.
+	Include      []string
+	Exclude      []string
+	IgnoreHidden bool
+	Recursive    bool
+	OnChange     ChangePolicy
+	Settle       Settle
+}
+
+// Settle describes when a file is considered fully written and may be claimed.
+type Settle struct {
+	// Observations is the number of consecutive scans that must see
+	// the same size and mtime. Values below 2 disable the check.
+	Observations int
+	// Interval is how often unsettled files are re-observed in notify mode.
+	Interval          time.Duration
+	RequireDoneMarker bool
+}
+
+// Encoding names the character encoding of text input.
+type Encoding string
+
+const (
+	// EncodingAuto detects the encoding by the byte order mark or, without one, by the content.
+	EncodingAuto        Encoding = "auto"
+	EncodingUTF8        Encoding = "utf-8"
+	EncodingWindows1251 Encoding = "windows-1251"
+	EncodingUTF16LE     Encoding = "utf-16le"
+	EncodingUTF16BE     Encoding = "utf-16be"
+)
+
+type Parser struct {
+	// Delimiter separates CSV fields; 0 means it is sniffed from the header row.
+	Delimiter rune
+	// Columns is keyed by the canonical column name, i.e. the csv tag of domain.Device.
+	Columns map[string]Column
+	// CollectRowErrors makes the parser validate every row and report all row errors
+	// instead of stopping at the first invalid record.
+	CollectRowErrors bool
+	// RowPolicy RowPolicyAcceptValid implies CollectRowErrors.
+	RowPolicy RowPolicy
+	// Encoding of CSV and JSON Lines input, converted to UTF-8 before decoding.
+	Encoding Encoding
+	// Rules are checked on every decoded row after the built-in checks.
+	Rules []Rule
+}
+
+// Column describes how a canonical column may appear in input headers.
+// Headers are matched case-insensitively.
+type Column struct {
+	Aliases  []string `yaml:"aliases"`
+	Required bool     `yaml:"required"`
+	// Default is used for every row when the column is missing from the file.
+	Default string `yaml:"default"`
+}
+
+// RuleKind names the check a validation rule performs.
+type RuleKind string
+
+const (
+	RuleUUID   RuleKind = "uuid"
+	RuleOneOf  RuleKind = "one_of"
+	RuleRange  RuleKind = "range"
+	RuleRegex  RuleKind = "regex"
+	RuleUnique RuleKind = "unique"
+)
+
+// Rule is a declarative check of device columns. Empty values are not checked,
+// except by RuleUnique when only some of its columns are empty.
+type Rule struct {
+	// Name is reported with every violation of the rule.
+	Name string   `yaml:"name"`
+	Kind RuleKind `yaml:"kind"`
+	// Columns holds the checked column. RuleUnique accepts several columns
+	// and checks their combination within a file.
+	Columns []string `yaml:"columns"`
+	// Values lists the allowed values of RuleOneOf.
+	Values []string `yaml:"values"`
+	// Min and Max bound RuleRange inclusively; either may be omitted.
+	Min *int `yaml:"min"`
+	Max *int `yaml:"max"`
+	// Pattern is the RuleRegex expression. It is not anchored implicitly.
+	Pattern string `yaml:"pattern"`
+}
+
+// Workers sets how many files the parse and write stages handle at once.
+// Every file is still handled by a single worker of each stage.
+type Workers struct {
+	Parse int
+	Write int
+	// Report is how many unit reports of a file are rendered at once; a unit is rendered
+	// in every configured format by the same worker.
+	Report int
+}
+
+// ReportMode decides which records of a unit its report covers.
+type ReportMode string
+
+const (
+	// ReportModeFile reports the records of the file that triggered the report.
+	ReportModeFile ReportMode = "file"
+	// ReportModeCumulative reports every stored record of the unit in the source,
+	// with a section per source file.
+	ReportModeCumulative ReportMode = "cumulative"
+)
+
+// Report formats a unit report can be rendered in. The format name is also the file extension.
+const (
+	ReportFormatPDF  = "pdf"
+	ReportFormatHTML = "html"
+	ReportFormatXLSX = "xlsx"
+	ReportFormatCSV  = "csv"
+	ReportFormatJSON = "json"
+)
+
+// Reports sets what generated reports cover and where they are written
+// within the reports directory of a source.
+type Reports struct {
+	Mode ReportMode
+	// Window limits ReportModeCumulative to the records stored within it; 0 means all records.
+	Window time.Duration
+	// Formats lists the formats every report is rendered in.
+	Formats []string
+	// NameTemplate is the report path relative to the reports directory, without the extension.
+	// See the ReportName* placeholders.
+	NameTemplate string
+	// LatestLink keeps <unit_guid>.<ext> in the reports directory as a symlink
+	// to the latest report of the unit.
+	LatestLink bool
+	// Fleet adds the reports listing the units of every processed file and of every
+	// inventory ID of the file, under fleet/ in the reports directory.
+	Fleet bool
+	// LayoutsDirectory holds the report layouts sources may select in addition
+	// to the built-in one; empty means the built-in layout only.
+	LayoutsDirectory string
+}
+
+// ReportStyle is how the reports of a source are presented.
+type ReportStyle struct {
+	// Layout names the layout of the PDF and HTML reports.
+	Layout string
+	// Locale is the language of the report labels and of the number and date formats.
+	Locale string
+	// TimeZone the report times are shown in; nil means UTC.
+	TimeZone *time.Location
+}
+
+// PostAction is applied to a source file once its outcome is committed.
+type PostAction string
+
+const (
+	PostActionKeep   PostAction = "keep"
+	PostActionMove   PostAction = "move"
+	PostActionDelete PostAction = "delete"
+)
+
+type Archive struct {
+	// OnDone also applies to duplicates: their content has already been processed.
+	OnDone  PostAction
+	OnError PostAction
+	// DoneDirectory and ErrorDirectory receive moved files under
+	// <source>/<path relative to the watch directory>.
+	DoneDirectory  string
+	ErrorDirectory string
 }
 
 type PostgreSQL struct {
@@ -34,12 +242,85 @@ type HTTP struct {
 	WriteTimeout time.Duration
 }
 
-func Load(cmd *cli.Command) *Config {
+func Load(cmd *cli.Command) (*Config, error) {
+	delimiter, err := ParseDelimiter(cmd.String("delimiter"))
+	if err != nil {
+		return nil, err
+	}
+
+	timeZone, err := time.LoadLocation(cmd.String("report-timezone"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load report time zone: %w", err)
+	}
+
+	// флаги задают источник по умолчанию и значения, которые наследуют источники из конфиг-файла
+	base := Source{
+		Name:             DefaultSource,
+		ReportsDirectory: cmd.String("reports-dir"),
+		ReportStyle: ReportStyle{
+			Layout:   cmd.String("report-layout"),
+			Locale:   cmd.String("report-locale"),
+			TimeZone: timeZone,
+		},
+		Scanner: Scanner{
+			WatchDirectory:    cmd.String("watch-dir"),
+			WatchMode:         WatchMode(cmd.String("watch-mode")),
+			ScanInterval:      cmd.Duration("scan-interval"),
+			ReconcileInterval: cmd.Duration("reconcile-interval"),
+			Include:           cmd.StringSlice("include"),
+			Exclude:           cmd.StringSlice("exclude"),
+			IgnoreHidden:      cmd.Bool("ignore-hidden"),
+			Recursive:         cmd.Bool("recursive"),
+			OnChange:          ChangePolicy(cmd.String("on-change")),
+			Settle: Settle{
+				Observations:      cmd.Int("settle-observations"),
+				Interval:          cmd.Duration("settle-interval"),
+				RequireDoneMarker: cmd.Bool("require-done-marker"),
+			},
+		},
+		Parser: Parser{
+			Delimiter:        delimiter,
+			CollectRowErrors: cmd.Bool("collect-row-errors"),
+			RowPolicy:        RowPolicy(cmd.String("row-policy")),
+			Encoding:         Encoding(cmd.String("encoding")),
+		},
+	}
+
+	var sources []Source
+	if path := cmd.String("config"); path != "" {
+		sources, err = loadSources(path, base)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// валидаторы флагов не проверяют значения по умолчанию, поэтому источник проверяется целиком
+		if err := base.validate(); err != nil {
+			return nil, fmt.Errorf("source %q: %w", base.Name, err)
+		}
+		sources = []Source{base}
+	}
+
 	return &Config{
-		App: App{
-			WatchDirectory:        cmd.String("watch-dir"),
-			ReportsDirectory:      cmd.String("reports-dir"),
-			DirectoryScanInterval: cmd.Duration("scan-interval"),
+		Sources: sources,
+		Workers: Workers{
+			Parse:  cmd.Int("parse-workers"),
+			Write:  cmd.Int("write-workers"),
+			Report: cmd.Int("report-workers"),
+		},
+		Reports: Reports{
+			Mode:             ReportMode(cmd.String("report-mode")),
+			Window:           cmd.Duration("report-window"),
+			Formats:          cmd.StringSlice("report-formats"),
+			NameTemplate:     cmd.String("report-name"),
+			LatestLink:       cmd.Bool("report-latest-link"),
+			Fleet:            cmd.Bool("report-fleet"),
+			LayoutsDirectory: cmd.String("report-layouts-dir"),
+		},
+		Archive: Archive{
+			OnDone:         PostAction(cmd.String("on-done")),
+			OnError:        PostAction(cmd.String("on-error")),
+			DoneDirectory:  cmd.String("done-dir"),
+			ErrorDirectory: cmd.String("error-dir"),
 		},
 		PostgreSQL: PostgreSQL{
 			Host:     cmd.String("pg-host"),
@@ -55,5 +336,5 @@ func Load(cmd *cli.Command) *Config {
 			ReadTimeout:  cmd.Duration("http-read-timeout"),
 			WriteTimeout: cmd.Duration("http-write-timeout"),
 		},
-	}
+	}, nil
 }
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Placeholders of Reports.NameTemplate.
+const (
+	// ReportNameGUID is the unit_guid of the report.
+	ReportNameGUID = "{guid}"
+	// ReportNameFile is the base name of the source file without its extension.
+	ReportNameFile = "{file}"
+	// ReportNameTimestamp is the generation time in UTC, e.g. 20250131T150405Z.
+	ReportNameTimestamp = "{timestamp}"
+	// ReportNameVersion is the sequence number of the report among the reports of the unit.
+	ReportNameVersion = "{version}"
+)
+
+var reportNamePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// ValidateReportName checks that a name template uses known placeholders only and
+// yields a distinct relative path for every report of a unit.
+func ValidateReportName(template string) error {
+	if template == "" {
+		return fmt.Errorf("report name template is empty")
+	}
+
+	known := []string{ReportNameGUID, ReportNameFile, ReportNameTimestamp, ReportNameVersion}
+	for _, placeholder := range reportNamePlaceholder.FindAllString(template, -1) {
+		if !slices.Contains(known, placeholder) {
+			return fmt.Errorf("unknown placeholder %s in report name %q", placeholder, template)
+		}
+	}
+
+	if !strings.Contains(template, ReportNameGUID) {
+		return fmt.Errorf("report name %q must contain %s", template, ReportNameGUID)
+	}
+
+	// без версии или времени новый отчёт устройства перезапишет предыдущий
+	if !strings.Contains(template, ReportNameVersion) && !strings.Contains(template, ReportNameTimestamp) {
+		return fmt.Errorf("report name %q must contain %s or %s", template, ReportNameVersion, ReportNameTimestamp)
+	}
+
+	if !filepath.IsLocal(filepath.FromSlash(template)) {
+		return fmt.Errorf("report name %q must be a path within the reports directory", template)
+	}
+
+	return nil
+}
+
+// Report locales, the languages of the report labels.
+const (
+	ReportLocaleEN = "en"
+	ReportLocaleRU = "ru"
+)
+
+func ValidateReportLocale(locale string) error {
+	switch locale {
+	case ReportLocaleEN, ReportLocaleRU:
+		return nil
+	default:
+		return fmt.Errorf("unknown report locale %q", locale)
+	}
+}
+
+// ValidateReportFormats checks that every format is known and listed once.
+func ValidateReportFormats(formats []string) error {
+	if len(formats) == 0 {
+		return fmt.Errorf("no report formats")
+	}
+
+	known := []string{ReportFormatPDF, ReportFormatHTML, ReportFormatXLSX, ReportFormatCSV, ReportFormatJSON}
+	for i, format := range formats {
+		if !slices.Contains(known, format) {
+			return fmt.Errorf("unknown report format %q", format)
+		}
+
+		if slices.Contains(formats[:i], format) {
+			return fmt.Errorf("report format %q is listed twice", format)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,356 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jszwec/csvutil"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultSource names the source built from flags when the config file lists no sources.
+const DefaultSource = "default"
+
+type configFile struct {
+	App struct {
+		Columns map[string]Column `yaml:"columns"`
+		Rules   []Rule            `yaml:"rules"`
+		Sources []sourceOverrides `yaml:"sources"`
+	} `yaml:"app"`
+}
+
+// sourceOverrides holds the settings of one app.sources entry. Nil fields
+// are inherited from the flags.
+type sourceOverrides struct {
+	Name              string         `yaml:"name"`
+	WatchDir          *string        `yaml:"watch_dir"`
+	ReportsDir        *string        `yaml:"reports_dir"`
+	ReportLayout      *string        `yaml:"report_layout"`
+	ReportLocale      *string        `yaml:"report_locale"`
+	ReportTZ          *string        `yaml:"report_timezone"`
+	WatchMode         *string        `yaml:"watch_mode"`
+	ScanInterval      *time.Duration `yaml:"scan_interval"`
+	ReconcileInterval *time.Duration `yaml:"reconcile_interval"`
+	Include           []string       `yaml:"include"`
+	Exclude           []string       `yaml:"exclude"`
+	IgnoreHidden      *bool          `yaml:"ignore_hidden"`
+	Recursive         *bool          `yaml:"recursive"`
+	OnChange          *string        `yaml:"on_change"`
+	Settle            struct {
+		Observations      *int           `yaml:"observations"`
+		Interval          *time.Duration `yaml:"interval"`
+		RequireDoneMarker *bool          `yaml:"require_done_marker"`
+	} `yaml:"settle"`
+	Delimiter        *string           `yaml:"delimiter"`
+	Columns          map[string]Column `yaml:"columns"`
+	CollectRowErrors *bool             `yaml:"collect_row_errors"`
+	RowPolicy        *string           `yaml:"row_policy"`
+	Encoding         *string           `yaml:"encoding"`
+	Rules            []Rule            `yaml:"rules"`
+}
+
+func loadSources(filename string, base Source) ([]Source, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var file configFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	if err := validateColumns(file.App.Columns); err != nil {
+		return nil, err
+	}
+	base.Parser.Columns = file.App.Columns
+
+	if err := validateRules(file.App.Rules); err != nil {
+		return nil, err
+	}
+	base.Parser.Rules = file.App.Rules
+
+	if len(file.App.Sources) == 0 {
+		if err := base.validate(); err != nil {
+			return nil, fmt.Errorf("source %q: %w", base.Name, err)
+		}
+
+		return []Source{base}, nil
+	}
+
+	sources := make([]Source, 0, len(file.App.Sources))
+	names := make(map[string]struct{}, len(file.App.Sources))
+
+	for i, overrides := range file.App.Sources {
+		source, err := overrides.apply(base)
+		if err != nil {
+			return nil, fmt.Errorf("source #%d: %w", i+1, err)
+		}
+
+		if _, ok := names[source.Name]; ok {
+			return nil, fmt.Errorf("source %q is defined twice", source.Name)
+		}
+		names[source.Name] = struct{}{}
+
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+func (o sourceOverrides) apply(base Source) (Source, error) {
+	if o.Name == "" {
+		return Source{}, errors.New("name is required")
+	}
+
+	source := base
+	source.Name = o.Name
+
+	setIfNotNil(&source.Scanner.WatchDirectory, o.WatchDir)
+	setIfNotNil(&source.ReportsDirectory, o.ReportsDir)
+	setIfNotNil(&source.ReportStyle.Layout, o.ReportLayout)
+	setIfNotNil(&source.ReportStyle.Locale, o.ReportLocale)
+	setIfNotNil(&source.Scanner.ScanInterval, o.ScanInterval)
+	setIfNotNil(&source.Scanner.ReconcileInterval, o.ReconcileInterval)
+	setIfNotNil(&source.Scanner.IgnoreHidden, o.IgnoreHidden)
+	setIfNotNil(&source.Scanner.Recursive, o.Recursive)
+	setIfNotNil(&source.Scanner.Settle.Observations, o.Settle.Observations)
+	setIfNotNil(&source.Scanner.Settle.Interval, o.Settle.Interval)
+	setIfNotNil(&source.Scanner.Settle.RequireDoneMarker, o.Settle.RequireDoneMarker)
+	setIfNotNil(&source.Parser.CollectRowErrors, o.CollectRowErrors)
+
+	if o.WatchMode != nil {
+		source.Scanner.WatchMode = WatchMode(*o.WatchMode)
+	}
+	if o.OnChange != nil {
+		source.Scanner.OnChange = ChangePolicy(*o.OnChange)
+	}
+	if o.Include != nil {
+		source.Scanner.Include = o.Include
+	}
+	if o.Exclude != nil {
+		source.Scanner.Exclude = o.Exclude
+	}
+	if o.Encoding != nil {
+		source.Parser.Encoding = Encoding(*o.Encoding)
+	}
+	if o.RowPolicy != nil {
+		source.Parser.RowPolicy = RowPolicy(*o.RowPolicy)
+	}
+	if o.Columns != nil {
+		source.Parser.Columns = o.Columns
+	}
+	if o.Rules != nil {
+		source.Parser.Rules = o.Rules
+	}
+	if o.ReportTZ != nil {
+		timeZone, err := time.LoadLocation(*o.ReportTZ)
+		if err != nil {
+			return Source{}, fmt.Errorf("failed to load report time zone: %w", err)
+		}
+		source.ReportStyle.TimeZone = timeZone
+	}
+	if o.Delimiter != nil {
+		delimiter, err := ParseDelimiter(*o.Delimiter)
+		if err != nil {
+			return Source{}, err
+		}
+		source.Parser.Delimiter = delimiter
+	}
+
+	if err := source.validate(); err != nil {
+		return Source{}, fmt.Errorf("source %q: %w", source.Name, err)
+	}
+
+	return source, nil
+}
+
+func (s Source) validate() error {
+	for _, dir := range []string{s.Scanner.WatchDirectory, s.ReportsDirectory} {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("failed to stat %q: %w", dir, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%q is not a directory", dir)
+		}
+	}
+
+	switch s.Scanner.WatchMode {
+	case WatchModePoll, WatchModeNotify:
+	default:
+		return fmt.Errorf("unknown watch mode %q", s.Scanner.WatchMode)
+	}
+
+	switch s.Scanner.OnChange {
+	case ChangePolicyIgnore, ChangePolicyReingest:
+	default:
+		return fmt.Errorf("unknown change policy %q", s.Scanner.OnChange)
+	}
+
+	for _, pattern := range slices.Concat(s.Scanner.Include, s.Scanner.Exclude) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	switch s.Parser.RowPolicy {
+	case RowPolicyRejectFile, RowPolicyAcceptValid:
+	default:
+		return fmt.Errorf("unknown row policy %q", s.Parser.RowPolicy)
+	}
+
+	if err := ValidateEncoding(s.Parser.Encoding); err != nil {
+		return err
+	}
+
+	if err := ValidateReportLocale(s.ReportStyle.Locale); err != nil {
+		return err
+	}
+
+	if s.Scanner.ScanInterval <= 0 {
+		return errors.New("scan interval must be positive")
+	}
+
+	if s.Scanner.ReconcileInterval <= 0 {
+		return errors.New("reconcile interval must be positive")
+	}
+
+	// в режиме notify интервал задаёт тикер, а time.NewTicker паникует на неположительном
+	if s.Scanner.Settle.Interval <= 0 {
+		return errors.New("settle interval must be positive")
+	}
+
+	if err := validateColumns(s.Parser.Columns); err != nil {
+		return err
+	}
+
+	return validateRules(s.Parser.Rules)
+}
+
+func validateColumns(columns map[string]Column) error {
+	known, err := csvutil.Header(domain.Device{}, "csv")
+	if err != nil {
+		return fmt.Errorf("failed to get device columns: %w", err)
+	}
+
+	for name, column := range columns {
+		if !slices.Contains(known, name) {
+			return fmt.Errorf("unknown column %q, expected one of %v", name, known)
+		}
+
+		if slices.Contains(column.Aliases, "") {
+			return fmt.Errorf("column %q has an empty alias", name)
+		}
+	}
+
+	return nil
+}
+
+func validateRules(rules []Rule) error {
+	known, err := csvutil.Header(domain.Device{}, "csv")
+	if err != nil {
+		return fmt.Errorf("failed to get device columns: %w", err)
+	}
+
+	names := make(map[string]struct{}, len(rules))
+
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rule #%d: name is required", i+1)
+		}
+
+		if _, ok := names[rule.Name]; ok {
+			return fmt.Errorf("rule %q is defined twice", rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+
+		if err := rule.validate(known); err != nil {
+			return fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (r Rule) validate(known []string) error {
+	if len(r.Columns) == 0 {
+		return errors.New("columns are required")
+	}
+
+	for _, column := range r.Columns {
+		if !slices.Contains(known, column) {
+			return fmt.Errorf("unknown column %q, expected one of %v", column, known)
+		}
+	}
+
+	if r.Kind != RuleUnique && len(r.Columns) > 1 {
+		return fmt.Errorf("%s rule checks a single column", r.Kind)
+	}
+
+	switch r.Kind {
+	case RuleUUID, RuleUnique:
+	case RuleOneOf:
+		if len(r.Values) == 0 {
+			return errors.New("values are required")
+		}
+	case RuleRange:
+		if r.Min == nil && r.Max == nil {
+			return errors.New("min or max is required")
+		}
+		if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+			return fmt.Errorf("min %d is greater than max %d", *r.Min, *r.Max)
+		}
+	case RuleRegex:
+		if r.Pattern == "" {
+			return errors.New("pattern is required")
+		}
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown rule kind %q", r.Kind)
+	}
+
+	return nil
+}
+
+func ValidateEncoding(encoding Encoding) error {
+	switch encoding {
+	case EncodingAuto, EncodingUTF8, EncodingWindows1251, EncodingUTF16LE, EncodingUTF16BE:
+		return nil
+	default:
+		return fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
+
+// ParseDelimiter accepts a single character or "\t"/"tab" for a tab.
+// An empty string or "auto" yields 0, which means the delimiter is sniffed from the header.
+func ParseDelimiter(delimiter string) (rune, error) {
+	switch delimiter {
+	case "", "auto":
+		return 0, nil
+	case `\t`, "tab":
+		return '\t', nil
+	}
+
+	r, size := utf8.DecodeRuneInString(delimiter)
+	if r == utf8.RuneError || size != len(delimiter) {
+		return 0, fmt.Errorf("delimiter must be a single character, got %q", delimiter)
+	}
+
+	return r, nil
+}
+
+func setIfNotNil[T any](dst *T, src *T) {
+	if src != nil {
+		*dst = *src
+	}
+}
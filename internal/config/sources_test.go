@@ -0,0 +1,389 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSources(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		// config is the config file; {dir} is replaced with the directory of the test.
+		config string
+		// want returns the expected sources for the source built from flags.
+		want func(base Source, dir string) []Source
+	}{
+		{
+			name:   "no sources",
+			config: "app: {}\n",
+			want: func(base Source, _ string) []Source {
+				return []Source{base}
+			},
+		},
+		{
+			// Колонки и правила app применяются и к источнику из флагов
+			name: "no sources with columns and rules",
+			config: `
+app:
+  columns:
+    unit_guid:
+      aliases: [guid]
+  rules:
+    - name: guid
+      kind: uuid
+      columns: [unit_guid]
+`,
+			want: func(base Source, _ string) []Source {
+				base.Parser.Columns = map[string]Column{"unit_guid": {Aliases: []string{"guid"}}}
+				base.Parser.Rules = []Rule{{Name: "guid", Kind: RuleUUID, Columns: []string{"unit_guid"}}}
+
+				return []Source{base}
+			},
+		},
+		{
+			name: "sources inherit flags",
+			config: `
+app:
+  columns:
+    unit_guid:
+      aliases: [guid]
+  sources:
+    - name: plant-a
+    - name: plant-b
+      watch_dir: {dir}/plant-b/input
+      reports_dir: {dir}/plant-b/output
+      report_layout: compact
+      report_locale: ru
+      report_timezone: UTC
+      watch_mode: notify
+      scan_interval: 10s
+      reconcile_interval: 1m
+      include: ["*.csv"]
+      exclude: ["tmp*"]
+      ignore_hidden: false
+      recursive: true
+      on_change: reingest
+      settle:
+        observations: 3
+        interval: 2s
+        require_done_marker: true
+      delimiter: ";"
+      columns:
+        n:
+          aliases: [number]
+      collect_row_errors: true
+      row_policy: accept_valid
+      encoding: windows-1251
+      rules:
+        - name: level
+          kind: range
+          columns: [level]
+          min: 0
+`,
+			want: func(base Source, dir string) []Source {
+				base.Parser.Columns = map[string]Column{"unit_guid": {Aliases: []string{"guid"}}}
+
+				plantA := base
+				plantA.Name = "plant-a"
+
+				zero := 0
+				plantB := base
+				plantB.Name = "plant-b"
+				plantB.ReportsDirectory = filepath.Join(dir, "plant-b", "output")
+				plantB.ReportStyle = ReportStyle{Layout: "compact", Locale: ReportLocaleRU, TimeZone: time.UTC}
+				plantB.Scanner = Scanner{
+					WatchDirectory:    filepath.Join(dir, "plant-b", "input"),
+					WatchMode:         WatchModeNotify,
+					ScanInterval:      10 * time.Second,
+					ReconcileInterval: time.Minute,
+					Include:           []string{"*.csv"},
+					Exclude:           []string{"tmp*"},
+					IgnoreHidden:      false,
+					Recursive:         true,
+					OnChange:          ChangePolicyReingest,
+					Settle:            Settle{Observations: 3, Interval: 2 * time.Second, RequireDoneMarker: true},
+				}
+				// колонки источника заменяют общие, а не дополняют их
+				plantB.Parser = Parser{
+					Delimiter:        ';',
+					Columns:          map[string]Column{"n": {Aliases: []string{"number"}}},
+					CollectRowErrors: true,
+					RowPolicy:        RowPolicyAcceptValid,
+					Encoding:         EncodingWindows1251,
+					Rules:            []Rule{{Name: "level", Kind: RuleRange, Columns: []string{"level"}, Min: &zero}},
+				}
+
+				return []Source{plantA, plantB}
+			},
+		},
+		{
+			name: "auto delimiter",
+			config: `
+app:
+  sources:
+    - name: plant-a
+      delimiter: auto
+`,
+			want: func(base Source, _ string) []Source {
+				base.Name = "plant-a"
+				base.Parser.Delimiter = 0
+
+				return []Source{base}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := sourceDirs(t)
+			base := baseSource(dir)
+
+			sources, err := loadSources(writeConfig(t, dir, tt.config), base)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want(baseSource(dir), dir), sources)
+
+			// источники получают копию настроек из флагов и не меняют их
+			assert.Equal(t, baseSource(dir), base)
+		})
+	}
+}
+
+func TestLoadSources_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name:    "invalid yaml",
+			config:  "app: [\n",
+			wantErr: "failed to parse config file",
+		},
+		{
+			name: "duplicate names",
+			config: `
+app:
+  sources:
+    - name: plant-a
+    - name: plant-b
+    - name: plant-a
+      watch_dir: {dir}/plant-b/input
+`,
+			wantErr: `source "plant-a" is defined twice`,
+		},
+		{
+			name: "missing name",
+			config: `
+app:
+  sources:
+    - name: plant-a
+    - watch_dir: {dir}/plant-b/input
+`,
+			wantErr: "source #2: name is required",
+		},
+		{
+			name: "missing watch directory",
+			config: `
+app:
+  sources:
+    - name: plant-a
+      watch_dir: {dir}/missing
+`,
+			wantErr: `source #1: source "plant-a": failed to stat`,
+		},
+		{
+			name: "unknown watch mode",
+			config: `
+app:
+  sources:
+    - name: plant-a
+      watch_mode: inotify
+`,
+			wantErr: `source #1: source "plant-a": unknown watch mode "inotify"`,
+		},
+		{
+			name: "invalid pattern",
+			config: `
+app:
+  sources:
+    - name: plant-a
+      include: ["[a-"]
+`,
+			wantErr: `source #1: source "plant-a": invalid pattern "[a-"`,
+		},
+		{
+			name: "unknown row policy",
+			config: `
+app:
+  sources:
+    - name: plant-a
+      row_policy: skip
+`,
+			wantErr: `source #1: source "plant-a": unknown row policy "skip"`,
+		},
+		{
+			name: "unknown report locale",
+			config: `
+app:
+  sources:
+    - name: plant-a
+      report_locale: de
+`,
+			wantErr: `source #1: source "plant-a": unknown report locale "de"`,
+		},
+		{
+			name: "invalid time zone",
+			config: `
+app:
+  sources:
+    - name: plant-a
+      report_timezone: Mars/Olympus
+`,
+			wantErr: "source #1: failed to load report time zone",
+		},
+		{
+			name: "invalid delimiter",
+			config: `
+app:
+  sources:
+    - name: plant-a
+      delimiter: ";;"
+`,
+			wantErr: `source #1: delimiter must be a single character, got ";;"`,
+		},
+		{
+			name: "non-positive settle interval",
+			config: `
+app:
+  sources:
+    - name: plant-a
+      settle:
+        interval: 0s
+`,
+			wantErr: `source #1: source "plant-a": settle interval must be positive`,
+		},
+		{
+			name: "unknown source column",
+			config: `
+app:
+  sources:
+    - name: plant-a
+      columns:
+        serial: {}
+`,
+			wantErr: `source #1: source "plant-a": unknown column "serial"`,
+		},
+		{
+			name: "invalid source rule",
+			config: `
+app:
+  sources:
+    - name: plant-a
+      rules:
+        - name: level
+          kind: range
+          columns: [level]
+`,
+			wantErr: `source #1: source "plant-a": rule "level": min or max is required`,
+		},
+		{
+			name: "unknown global column",
+			config: `
+app:
+  columns:
+    serial: {}
+`,
+			wantErr: `unknown column "serial"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := sourceDirs(t)
+
+			_, err := loadSources(writeConfig(t, dir, tt.config), baseSource(dir))
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestLoadSources_InvalidFlags(t *testing.T) {
+	t.Parallel()
+
+	dir := sourceDirs(t)
+
+	// источник из флагов проверяется, даже если в конфиге нет источников
+	base := baseSource(dir)
+	base.Scanner.ScanInterval = 0
+
+	_, err := loadSources(writeConfig(t, dir, "app: {}\n"), base)
+	require.EqualError(t, err, `source "default": scan interval must be positive`)
+}
+
+func TestLoadSources_MissingFile(t *testing.T) {
+	t.Parallel()
+
+	dir := sourceDirs(t)
+
+	_, err := loadSources(filepath.Join(dir, "missing.yaml"), baseSource(dir))
+	require.ErrorContains(t, err, "failed to read config file")
+}
+
+// sourceDirs creates the watch and reports directories of the flags and of plant-b.
+func sourceDirs(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, name := range []string{"input", "output", filepath.Join("plant-b", "input"), filepath.Join("plant-b", "output")} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, name), 0o755))
+	}
+
+	return dir
+}
+
+// baseSource is the source the flags build, with its directories under dir.
+func baseSource(dir string) Source {
+	return Source{
+		Name:             DefaultSource,
+		ReportsDirectory: filepath.Join(dir, "output"),
+		ReportStyle:      ReportStyle{Layout: "default", Locale: ReportLocaleEN},
+		Scanner: Scanner{
+			WatchDirectory:    filepath.Join(dir, "input"),
+			WatchMode:         WatchModePoll,
+			ScanInterval:      time.Second,
+			ReconcileInterval: 5 * time.Minute,
+			Include:           []string{"*.tsv"},
+			IgnoreHidden:      true,
+			OnChange:          ChangePolicyIgnore,
+			Settle:            Settle{Observations: 2, Interval: time.Second},
+		},
+		Parser: Parser{
+			Delimiter: '\t',
+			RowPolicy: RowPolicyRejectFile,
+			Encoding:  EncodingAuto,
+		},
+	}
+}
+
+func writeConfig(t *testing.T, dir, config string) string {
+	t.Helper()
+
+	filename := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte(strings.ReplaceAll(config, "{dir}", dir)), 0o644))
+
+	return filename
+}
@@ -0,0 +1,123 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/device_reporter/internal/config"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+)
+
+type ReportsHandler struct {
+	reportsRepository ReportsRepository
+}
+
+type ReportsRepository interface {
+	Reports(ctx context.Context, source, fileName string, limit, offset uint64) ([]*domain.Report, int, error)
+	ReportVersions(ctx context.Context, source, unitGUID string, limit, offset uint64) ([]*domain.ReportVersion, int, error)
+}
+
+func NewReportsHandler(reportsRepository ReportsRepository) *ReportsHandler {
+	return &ReportsHandler{
+		reportsRepository: reportsRepository,
+	}
+}
+
+type GetReportsResponse struct {
+	Source     string           `json:"source"`
+	File       string           `json:"file"`
+	Reports    []*domain.Report `json:"reports"`
+	Pagination Pagination       `json:"pagination"`
+}
+
+// GetReports lists the reports of a file with their statuses.
+func (h *ReportsHandler) GetReports(w http.ResponseWriter, r *http.Request) {
+	fileName := r.URL.Query().Get("file")
+	if fileName == "" {
+		http.Error(w, "file is required", http.StatusBadRequest)
+		return
+	}
+
+	source := r.URL.Query().Get("source")
+	if source == "" {
+		source = config.DefaultSource
+	}
+
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	offset := (page - 1) * limit
+
+	reports, total, err := h.reportsRepository.Reports(r.Context(), source, fileName, limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(GetReportsResponse{
+		Source:     source,
+		File:       fileName,
+		Reports:    reports,
+		Pagination: newPagination(page, limit, total),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(data)
+}
+
+type GetReportVersionsResponse struct {
+	Source     string                  `json:"source"`
+	UnitGUID   string                  `json:"unit_guid"`
+	Versions   []*domain.ReportVersion `json:"versions"`
+	Pagination Pagination              `json:"pagination"`
+}
+
+// GetReportVersions lists the history of a unit's reports, the latest first.
+func (h *ReportsHandler) GetReportVersions(w http.ResponseWriter, r *http.Request) {
+	unitGUID := chi.URLParam(r, "unit_guid")
+	if err := uuid.Validate(unitGUID); err != nil {
+		http.Error(w, "invalid unit_guid", http.StatusBadRequest)
+		return
+	}
+
+	source := r.URL.Query().Get("source")
+	if source == "" {
+		source = config.DefaultSource
+	}
+
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	offset := (page - 1) * limit
+
+	versions, total, err := h.reportsRepository.ReportVersions(r.Context(), source, unitGUID, limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(GetReportVersionsResponse{
+		Source:     source,
+		UnitGUID:   unitGUID,
+		Versions:   versions,
+		Pagination: newPagination(page, limit, total),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(data)
+}
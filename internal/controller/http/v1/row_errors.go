@@ -0,0 +1,73 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/kurochkinivan/device_reporter/internal/config"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+)
+
+type RowErrorsHandler struct {
+	rowErrorsRepository RowErrorsRepository
+}
+
+type RowErrorsRepository interface {
+	RowErrors(ctx context.Context, source, fileName string, limit, offset uint64) ([]*domain.RowError, int, error)
+}
+
+func NewRowErrorsHandler(rowErrorsRepository RowErrorsRepository) *RowErrorsHandler {
+	return &RowErrorsHandler{
+		rowErrorsRepository: rowErrorsRepository,
+	}
+}
+
+type GetRowErrorsResponse struct {
+	Source     string             `json:"source"`
+	File       string             `json:"file"`
+	RowErrors  []*domain.RowError `json:"row_errors"`
+	Pagination Pagination         `json:"pagination"`
+}
+
+// GetRowErrors lists row errors of a file. The file name goes in the query,
+// because it may contain slashes, e.g. bundle.zip!/unit7.tsv.
+func (h *RowErrorsHandler) GetRowErrors(w http.ResponseWriter, r *http.Request) {
+	fileName := r.URL.Query().Get("file")
+	if fileName == "" {
+		http.Error(w, "file is required", http.StatusBadRequest)
+		return
+	}
+
+	source := r.URL.Query().Get("source")
+	if source == "" {
+		source = config.DefaultSource
+	}
+
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	offset := (page - 1) * limit
+
+	rowErrors, total, err := h.rowErrorsRepository.RowErrors(r.Context(), source, fileName, limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(GetRowErrorsResponse{
+		Source:     source,
+		File:       fileName,
+		RowErrors:  rowErrors,
+		Pagination: newPagination(page, limit, total),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(data)
+}
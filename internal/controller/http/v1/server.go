@@ -14,14 +14,24 @@ type Server struct {
 	httpServer *http.Server
 }
 
-func NewServer(cfg config.HTTP, devicesRepo DevicesRepository) *Server {
+func NewServer(
+	cfg config.HTTP,
+	devicesRepo DevicesRepository,
+	rowErrorsRepo RowErrorsRepository,
+	reportsRepo ReportsRepository,
+) *Server {
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
 	h := NewDevicesHandler(devicesRepo)
+	rh := NewRowErrorsHandler(rowErrorsRepo)
+	reh := NewReportsHandler(reportsRepo)
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Get("/devices/{unit_guid}", h.GetDevicesByUnitGUID)
+		r.Get("/row-errors", rh.GetRowErrors)
+		r.Get("/reports", reh.GetReports)
+		r.Get("/reports/{unit_guid}/versions", reh.GetReportVersions)
 	})
 
 	return &Server{
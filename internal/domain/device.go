@@ -18,20 +18,33 @@ type Device struct {
 	Type      string `csv:"type"       db:"type"       json:"type"`
 	Bit       string `csv:"bit"        db:"bit"        json:"bit"`
 	InvertBit string `csv:"invert_bit" db:"invert_bit" json:"invert_bit"`
+	Source    string `csv:"-"          db:"source"     json:"-"`
+	FileName  string `csv:"-"          db:"file_name"  json:"-"`
 }
 
 func (d *Device) Validate() error {
+	if violations := d.Violations(); len(violations) > 0 {
+		return fmt.Errorf("%s %s", violations[0].Column, violations[0].Reason)
+	}
+
+	return nil
+}
+
+// Violations returns every failed check of the device. Line is left for the caller to fill.
+func (d *Device) Violations() []*RowError {
+	var violations []*RowError
+
 	if d.UnitGUID == "" {
-		return fmt.Errorf("unit_guid is required")
+		violations = append(violations, &RowError{Column: "unit_guid", Reason: "is required"})
 	}
-	
+
 	if d.N == 0 {
-		return fmt.Errorf("n is required")
+		violations = append(violations, &RowError{Column: "n", Reason: "is required"})
 	}
 
 	if d.Class == "" {
-		return fmt.Errorf("class is required")
+		violations = append(violations, &RowError{Column: "class", Reason: "is required"})
 	}
 
-	return nil
+	return violations
 }
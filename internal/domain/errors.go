@@ -0,0 +1,5 @@
+package domain
+
+import "errors"
+
+var ErrFileNotFound = errors.New("file not found")
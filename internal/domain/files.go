@@ -3,8 +3,16 @@ package domain
 import "time"
 
 type File struct {
-	Name         string     `db:"name"`
-	Status       Status     `db:"status"`
-	ErrorMessage string     `db:"error_message"`
-	ProcessedAt  *time.Time `db:"processed_at"`
+	Source        string     `db:"source"`
+	Name          string     `db:"name"` // slash-separated path relative to the watch directory
+	Path          string     `db:"-"`    // location on disk, set by the scanner
+	Member        string     `db:"-"`    // path inside the bundle at Path, empty for plain files
+	Status        Status     `db:"status"`
+	ErrorMessage  string     `db:"error_message"`
+	ProcessedAt   *time.Time `db:"processed_at"`
+	Size          int64      `db:"size"`
+	ModifiedAt    *time.Time `db:"modified_at"`
+	Hash          string     `db:"sha256"`
+	DuplicateOf   string     `db:"duplicate_of"`
+	RowsProcessed int64      `db:"rows_processed"` // devices saved so far, updated while the file is written
 }
@@ -1,7 +1,13 @@
 package domain
 
 type ParseResult struct {
-	Filename string
-	Devices  []*Device // filled in case of a success
-	Error    error     // filled in case of an error
+	File *File
+	// Chunks streams the parsed devices in batches and is closed once the whole file is read.
+	// The fields below are final only after Chunks is closed.
+	Chunks <-chan []*Device
+	Error  error // filled in case of an error
+	// RowErrors lists every rejected row when the source collects row errors.
+	RowErrors []*RowError
+	// RejectedRows are the invalid rows of a file accepted with the accept_valid row policy.
+	RejectedRows []*RejectedRow
 }
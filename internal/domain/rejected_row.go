@@ -0,0 +1,8 @@
+package domain
+
+// RejectedRow is an input row left out of a partially accepted file.
+type RejectedRow struct {
+	Line   int    `db:"line"   json:"line"`
+	Raw    string `db:"raw"    json:"raw"` // fields as read, joined by tabs
+	Reason string `db:"reason" json:"reason"`
+}
@@ -0,0 +1,65 @@
+package domain
+
+import "time"
+
+// ReportStatus tracks the report of one unit: it is pending from the moment the file
+// is saved until the Reporter either generates it or fails to.
+type ReportStatus string
+
+const (
+	ReportStatusPending ReportStatus = "pending"
+	ReportStatusDone    ReportStatus = "done"
+	ReportStatusError   ReportStatus = "error"
+)
+
+// Report is the report of one unit_guid of a file.
+type Report struct {
+	Source       string        `db:"source"        json:"-"`
+	FileName     string        `db:"file_name"     json:"-"`
+	UnitGUID     string        `db:"unit_guid"     json:"unit_guid"`
+	Version      int           `db:"version"       json:"version"`
+	Files        []*ReportFile `db:"files"         json:"files"`
+	Status       ReportStatus  `db:"status"        json:"status"`
+	ErrorMessage string        `db:"error_message" json:"error_message,omitempty"`
+	GeneratedAt  *time.Time    `db:"generated_at"  json:"generated_at"`
+}
+
+// ReportFile is a report rendered in one of the configured formats.
+type ReportFile struct {
+	Format string `json:"format"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Hash   string `json:"sha256"`
+}
+
+// ReportVersion is a generated report kept in the history of its unit. Versions are
+// numbered from 1 per source and unit_guid, so the latest one has the greatest number.
+type ReportVersion struct {
+	Source      string        `db:"source"       json:"-"`
+	UnitGUID    string        `db:"unit_guid"    json:"-"`
+	Version     int           `db:"version"      json:"version"`
+	FileName    string        `db:"file_name"    json:"file_name"`
+	Files       []*ReportFile `db:"files"        json:"files"`
+	GeneratedAt time.Time     `db:"generated_at" json:"generated_at"`
+}
+
+// FleetUnit is a unit listed in a fleet report: the counts of its records and its latest report.
+type FleetUnit struct {
+	UnitGUID string `db:"unit_guid"`
+	InvID    string `db:"inv_id"`
+	Records  int    `db:"records"`
+	Alarms   int    `db:"alarms"`
+	Warnings int    `db:"warnings"`
+	// ReportVersion is nil and ReportFiles is empty while the unit has no report.
+	ReportVersion *int          `db:"report_version"`
+	ReportFiles   []*ReportFile `db:"report_files"`
+}
+
+// FleetReport lists the units of a processed file, or of an inventory ID across
+// every stored file of the source if InvID is set.
+type FleetReport struct {
+	Source   string
+	FileName string
+	InvID    string
+	Units    []*FleetUnit
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// ReportJob asks the Reporter to generate the pending reports of a file. Jobs are
+// written to the outbox in the transaction that saves the file and deleted once handled.
+type ReportJob struct {
+	ID        int64     `db:"id"`
+	Source    string    `db:"source"`
+	FileName  string    `db:"file_name"`
+	Attempts  int       `db:"attempts"`
+	LastError string    `db:"last_error"`
+	CreatedAt time.Time `db:"created_at"`
+}
@@ -0,0 +1,31 @@
+package domain
+
+import "fmt"
+
+// RowError describes why a single input row was rejected.
+type RowError struct {
+	Line   int    `db:"line"        json:"line"` // 1-based line of the file, the row of a sheet in XLSX
+	Column string `db:"column_name" json:"column"`
+	Value  string `db:"value"       json:"value"`
+	Reason string `db:"reason"      json:"reason"`
+	// Rule names the configured validation rule that failed; empty for built-in checks.
+	Rule string `db:"rule" json:"rule,omitempty"`
+}
+
+func (e *RowError) String() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message())
+}
+
+// Message describes the error without the line number.
+func (e *RowError) Message() string {
+	msg := e.Reason
+	if e.Column != "" {
+		msg = e.Column + " " + e.Reason
+	}
+
+	if e.Rule != "" {
+		msg += fmt.Sprintf(" (rule %s)", e.Rule)
+	}
+
+	return msg
+}
@@ -3,8 +3,10 @@ package domain
 type Status string
 
 const (
-	StatusPending    Status = "pending"
-	StatusProcessing Status = "processing"
-	StatusDone       Status = "done"
-	StatusError      Status = "error"
+	StatusPending        Status = "pending"
+	StatusProcessing     Status = "processing"
+	StatusDone           Status = "done"
+	StatusDoneWithErrors Status = "done_with_errors"
+	StatusError          Status = "error"
+	StatusDuplicate      Status = "duplicate"
 )
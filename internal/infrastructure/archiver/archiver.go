@@ -0,0 +1,165 @@
+package archiver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/kurochkinivan/device_reporter/internal/config"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+)
+
+const errorSidecarSuffix = ".error.txt"
+
+type Archiver struct {
+	cfg config.Archive
+}
+
+func New(cfg config.Archive) *Archiver {
+	return &Archiver{
+		cfg: cfg,
+	}
+}
+
+// Archive applies the post-action configured for the file's outcome.
+func (a *Archiver) Archive(file *domain.File) error {
+	// бандл содержит и другие файлы, которые могут быть ещё не обработаны;
+	// его целиком архивирует Scanner, когда обработаны все они
+	if file.Member != "" {
+		return nil
+	}
+
+	switch file.Status {
+	// дубликат уже обработан под другим именем, поэтому убирается так же, как обработанный файл
+	case domain.StatusDone, domain.StatusDoneWithErrors, domain.StatusDuplicate:
+		return a.apply(file, a.cfg.OnDone, a.cfg.DoneDirectory)
+	case domain.StatusError:
+		return a.apply(file, a.cfg.OnError, a.cfg.ErrorDirectory)
+	default:
+		return nil
+	}
+}
+
+func (a *Archiver) apply(file *domain.File, action config.PostAction, dir string) error {
+	switch action {
+	case config.PostActionMove:
+		return a.move(file, dir)
+
+	case config.PostActionDelete:
+		if err := os.Remove(file.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to delete file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// move puts the file under dir/<source>/<name>. A file archived earlier under the same
+// name is kept: the new one gets a numeric suffix instead.
+func (a *Archiver) move(file *domain.File, dir string) error {
+	target := filepath.Join(dir, file.Source, filepath.FromSlash(file.Name))
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	target, err := freeTarget(target)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(file.Path, target)
+	if errors.Is(err, syscall.EXDEV) {
+		// архив на другом разделе: rename невозможен, копируем и удаляем оригинал
+		err = moveAcrossDevices(file.Path, target)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to move file: %w", err)
+	}
+
+	if file.ErrorMessage != "" {
+		err := os.WriteFile(target+errorSidecarSuffix, []byte(file.ErrorMessage+"\n"), 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to write error sidecar: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// freeTarget returns target, or target with a numeric suffix before the extension,
+// such that neither the file nor its error sidecar exists yet.
+func freeTarget(target string) (string, error) {
+	ext := filepath.Ext(target)
+	base := strings.TrimSuffix(target, ext)
+
+	for i := 0; ; i++ {
+		candidate := target
+		if i > 0 {
+			candidate = fmt.Sprintf("%s.%d%s", base, i, ext)
+		}
+
+		// сайдкар без файла тоже занимает имя: иначе старая ошибка окажется рядом с новой копией
+		taken := false
+		for _, path := range []string{candidate, candidate + errorSidecarSuffix} {
+			_, err := os.Lstat(path)
+			if err == nil {
+				taken = true
+				break
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				return "", fmt.Errorf("failed to stat archived file: %w", err)
+			}
+		}
+
+		if !taken {
+			return candidate, nil
+		}
+	}
+}
+
+func moveAcrossDevices(src, dst string) error {
+	if err := copyFile(src, dst); err != nil {
+		// неполную копию не оставляем, оригинал остаётся на месте
+		if rmErr := os.Remove(dst); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
+			err = errors.Join(err, rmErr)
+		}
+		return err
+	}
+
+	return os.Remove(src)
+}
+
+func copyFile(src, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, in.Close()) }()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, out.Close()) }()
+
+	n, err := io.Copy(out, in)
+	if err != nil {
+		return err
+	}
+
+	if n != info.Size() {
+		return fmt.Errorf("copied %d of %d bytes", n, info.Size())
+	}
+
+	return out.Sync()
+}
@@ -0,0 +1,247 @@
+package archiver_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kurochkinivan/device_reporter/internal/config"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+	"github.com/kurochkinivan/device_reporter/internal/infrastructure/archiver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiver_Archive(t *testing.T) {
+	t.Parallel()
+
+	input := filepath.Join("input", "unit7.tsv")
+
+	tests := []struct {
+		name    string
+		archive config.Archive
+		file    domain.File
+		// existing are files relative to the temp dir that are there before the file is archived.
+		existing map[string]string
+		// want lists every file relative to the temp dir after the file is archived.
+		want map[string]string
+	}{
+		{
+			name:    "keep",
+			archive: config.Archive{OnDone: config.PostActionKeep},
+			file:    domain.File{Name: "unit7.tsv", Status: domain.StatusDone},
+			want:    map[string]string{input: "data"},
+		},
+		{
+			name:    "move done",
+			archive: config.Archive{OnDone: config.PostActionMove},
+			file:    domain.File{Name: "unit7.tsv", Status: domain.StatusDoneWithErrors},
+			want:    map[string]string{filepath.Join("done", "plant-a", "unit7.tsv"): "data"},
+		},
+		{
+			name:    "delete done",
+			archive: config.Archive{OnDone: config.PostActionDelete},
+			file:    domain.File{Name: "unit7.tsv", Status: domain.StatusDone},
+			want:    map[string]string{},
+		},
+		{
+			// Дубликат убирается так же, как обработанный файл
+			name:    "duplicate",
+			archive: config.Archive{OnDone: config.PostActionMove, OnError: config.PostActionKeep},
+			file:    domain.File{Name: "unit7.tsv", Status: domain.StatusDuplicate},
+			want:    map[string]string{filepath.Join("done", "plant-a", "unit7.tsv"): "data"},
+		},
+		{
+			name:    "in progress",
+			archive: config.Archive{OnDone: config.PostActionDelete, OnError: config.PostActionDelete},
+			file:    domain.File{Name: "unit7.tsv", Status: domain.StatusProcessing},
+			want:    map[string]string{input: "data"},
+		},
+		{
+			name:    "move error with sidecar",
+			archive: config.Archive{OnDone: config.PostActionDelete, OnError: config.PostActionMove},
+			file:    domain.File{Name: "unit7.tsv", Status: domain.StatusError, ErrorMessage: "bad header"},
+			want: map[string]string{
+				filepath.Join("error", "plant-a", "unit7.tsv"):           "data",
+				filepath.Join("error", "plant-a", "unit7.tsv.error.txt"): "bad header\n",
+			},
+		},
+		{
+			name:    "delete error",
+			archive: config.Archive{OnError: config.PostActionDelete},
+			file:    domain.File{Name: "unit7.tsv", Status: domain.StatusError, ErrorMessage: "bad header"},
+			want:    map[string]string{},
+		},
+		{
+			// Ранее заархивированная копия с тем же именем не перезаписывается
+			name:     "name collision",
+			archive:  config.Archive{OnDone: config.PostActionMove},
+			file:     domain.File{Name: "unit7.tsv", Status: domain.StatusDone},
+			existing: map[string]string{filepath.Join("done", "plant-a", "unit7.tsv"): "old"},
+			want: map[string]string{
+				filepath.Join("done", "plant-a", "unit7.tsv"):   "old",
+				filepath.Join("done", "plant-a", "unit7.1.tsv"): "data",
+			},
+		},
+		{
+			name:    "several collisions",
+			archive: config.Archive{OnDone: config.PostActionMove},
+			file:    domain.File{Name: "unit7.tsv", Status: domain.StatusDone},
+			existing: map[string]string{
+				filepath.Join("done", "plant-a", "unit7.tsv"):   "old",
+				filepath.Join("done", "plant-a", "unit7.1.tsv"): "older",
+			},
+			want: map[string]string{
+				filepath.Join("done", "plant-a", "unit7.tsv"):   "old",
+				filepath.Join("done", "plant-a", "unit7.1.tsv"): "older",
+				filepath.Join("done", "plant-a", "unit7.2.tsv"): "data",
+			},
+		},
+		{
+			// Сайдкар прежней ошибки не должен оказаться рядом с новой копией
+			name:     "stale sidecar",
+			archive:  config.Archive{OnDone: config.PostActionMove},
+			file:     domain.File{Name: "unit7.tsv", Status: domain.StatusDone},
+			existing: map[string]string{filepath.Join("done", "plant-a", "unit7.tsv.error.txt"): "old error\n"},
+			want: map[string]string{
+				filepath.Join("done", "plant-a", "unit7.tsv.error.txt"): "old error\n",
+				filepath.Join("done", "plant-a", "unit7.1.tsv"):         "data",
+			},
+		},
+		{
+			name:    "nested name",
+			archive: config.Archive{OnDone: config.PostActionMove},
+			file:    domain.File{Name: "2026/10/unit7.tsv", Status: domain.StatusDone},
+			want:    map[string]string{filepath.Join("done", "plant-a", "2026", "10", "unit7.tsv"): "data"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+
+			file := tt.file
+			file.Source = "plant-a"
+			file.Path = filepath.Join(dir, "input", filepath.FromSlash(file.Name))
+			writeFile(t, file.Path, "data")
+
+			for name, content := range tt.existing {
+				writeFile(t, filepath.Join(dir, name), content)
+			}
+
+			archive := tt.archive
+			archive.DoneDirectory = filepath.Join(dir, "done")
+			archive.ErrorDirectory = filepath.Join(dir, "error")
+
+			require.NoError(t, archiver.New(archive).Archive(&file))
+
+			assert.Equal(t, tt.want, readTree(t, dir))
+		})
+	}
+}
+
+func TestArchiver_Archive_Bundle(t *testing.T) {
+	t.Parallel()
+
+	input := filepath.Join("input", "bundle.zip")
+
+	tests := []struct {
+		name string
+		file domain.File
+		// wantPath is where the bundle ends up relative to the temp dir, empty if it is deleted.
+		wantPath    string
+		wantSidecar string
+	}{
+		{
+			// Файл внутри бандла не трогает бандл: остальные файлы могут быть ещё не обработаны
+			name:     "member",
+			file:     domain.File{Name: "bundle.zip!/unit7.tsv", Member: "unit7.tsv", Status: domain.StatusDone},
+			wantPath: input,
+		},
+		{
+			name: "done bundle",
+			file: domain.File{Name: "bundle.zip", Status: domain.StatusDone},
+		},
+		{
+			name:        "failed bundle",
+			file:        domain.File{Name: "bundle.zip", Status: domain.StatusError, ErrorMessage: "unit8.tsv: bad header"},
+			wantPath:    filepath.Join("error", "plant-a", "bundle.zip"),
+			wantSidecar: "unit8.tsv: bad header\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+
+			bundle := filepath.Join(dir, input)
+			require.NoError(t, os.MkdirAll(filepath.Dir(bundle), 0o755))
+			require.NoError(t, os.WriteFile(bundle, []byte("PK\x03\x04"), 0o644))
+
+			a := archiver.New(config.Archive{
+				OnDone:         config.PostActionDelete,
+				OnError:        config.PostActionMove,
+				ErrorDirectory: filepath.Join(dir, "error"),
+			})
+
+			file := tt.file
+			file.Source = "plant-a"
+			file.Path = bundle
+			require.NoError(t, a.Archive(&file))
+
+			if tt.wantPath != input {
+				assert.NoFileExists(t, bundle)
+			}
+
+			if tt.wantPath != "" {
+				assert.FileExists(t, filepath.Join(dir, tt.wantPath))
+			}
+
+			if tt.wantSidecar != "" {
+				sidecar, err := os.ReadFile(filepath.Join(dir, tt.wantPath+".error.txt"))
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantSidecar, string(sidecar))
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(name), 0o755))
+	require.NoError(t, os.WriteFile(name, []byte(content), 0o644))
+}
+
+// readTree returns the content of every file under dir by its path relative to dir.
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[rel] = string(content)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	return files
+}
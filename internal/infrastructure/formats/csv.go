@@ -0,0 +1,98 @@
+package formats
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+
+	"github.com/jszwec/csvutil"
+	"github.com/kurochkinivan/device_reporter/internal/config"
+)
+
+// candidateDelimiters are tried in this order when the delimiter is sniffed;
+// on a tie the earlier one wins, so plain TSV stays the default.
+var candidateDelimiters = []byte{'\t', ';', ',', '|'}
+
+type CSV struct{}
+
+func NewCSV() *CSV {
+	return &CSV{}
+}
+
+func (c *CSV) Name() string {
+	return "csv"
+}
+
+func (c *CSV) Extensions() []string {
+	return []string{".tsv", ".csv", ".txt"}
+}
+
+// Sniff accepts anything: CSV is the fallback format and should be registered last.
+func (c *CSV) Sniff([]byte) bool {
+	return true
+}
+
+// Binary is false: the text is converted to UTF-8 before it is read.
+func (c *CSV) Binary() bool {
+	return false
+}
+
+func (c *CSV) NewReader(r io.Reader, settings config.Parser) (csvutil.Reader, error) {
+	br := bufio.NewReader(r)
+
+	delimiter := settings.Delimiter
+	if delimiter == 0 {
+		head, _ := br.Peek(br.Size())
+		delimiter = sniffDelimiter(head)
+	}
+
+	reader := csv.NewReader(br)
+	reader.Comma = delimiter
+
+	return &CSVReader{reader: reader}, nil
+}
+
+// CSVReader reports the line a record starts at. It differs from the record number
+// when quoted fields span several lines.
+type CSVReader struct {
+	reader *csv.Reader
+	line   int
+}
+
+func (r *CSVReader) Read() ([]string, error) {
+	record, err := r.reader.Read()
+
+	// FieldPos недоступен для записи, которую не удалось разобрать
+	var parseErr *csv.ParseError
+	switch {
+	case errors.As(err, &parseErr):
+		r.line = parseErr.StartLine
+	case len(record) > 0:
+		r.line, _ = r.reader.FieldPos(0)
+	}
+
+	return record, err
+}
+
+// Line returns the line of the record read last.
+func (r *CSVReader) Line() int {
+	return r.line
+}
+
+// sniffDelimiter picks the candidate that occurs most often in the header row.
+func sniffDelimiter(head []byte) rune {
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+
+	best, bestCount := candidateDelimiters[0], 0
+	for _, d := range candidateDelimiters {
+		if count := bytes.Count(head, []byte{d}); count > bestCount {
+			best, bestCount = d, count
+		}
+	}
+
+	return rune(best)
+}
@@ -0,0 +1,192 @@
+package formats
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jszwec/csvutil"
+	"github.com/kurochkinivan/device_reporter/internal/config"
+)
+
+type JSONL struct{}
+
+func NewJSONL() *JSONL {
+	return &JSONL{}
+}
+
+func (j *JSONL) Name() string {
+	return "jsonl"
+}
+
+func (j *JSONL) Extensions() []string {
+	return []string{".jsonl", ".ndjson"}
+}
+
+func (j *JSONL) Sniff(head []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(head, " \t\r\n\uFEFF"), []byte("{"))
+}
+
+// Binary is false: the text is converted to UTF-8 before it is read.
+func (j *JSONL) Binary() bool {
+	return false
+}
+
+func (j *JSONL) NewReader(r io.Reader, _ config.Parser) (csvutil.Reader, error) {
+	return &JSONLReader{r: bufio.NewReader(r)}, nil
+}
+
+// JSONLReader turns JSON objects, one per line, into records. The keys of the first
+// object, sorted, become the header; later objects are laid out by that header, and
+// an object with keys missing from it is a row error. The first object is both
+// the header and the first record. Blank lines are skipped.
+//
+// A line that is not a valid object is a row error: its text is returned as the
+// only field of the record, so that it can be kept with the rejected rows.
+type JSONLReader struct {
+	r       *bufio.Reader
+	header  []string
+	pending map[string]any
+	// read counts the lines read so far, line is the line of the object read last.
+	read int
+	line int
+	// raw is the text of the line read last.
+	raw string
+}
+
+func (r *JSONLReader) Read() ([]string, error) {
+	if r.header == nil {
+		object, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+
+		r.header = slices.Sorted(maps.Keys(object))
+		r.pending = object
+
+		return r.header, nil
+	}
+
+	object := r.pending
+	r.pending = nil
+
+	if object == nil {
+		var err error
+		if object, err = r.next(); err != nil {
+			// ошибка разбора строки не мешает читать следующие
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return []string{r.raw}, err
+			}
+			return nil, err
+		}
+	}
+
+	record := make([]string, len(r.header))
+	for i, key := range r.header {
+		value, err := stringify(object[key])
+		if err != nil {
+			return []string{r.raw}, r.parseError(fmt.Errorf("key %q: %w", key, err))
+		}
+		record[i] = value
+	}
+
+	// значения ключей, которых нет в заголовке, некуда положить — строка ошибочна, а не теряет их молча;
+	// ParseError парсер считает ошибкой строки, как и у CSV
+	if unknown := r.unknownKeys(object); len(unknown) > 0 {
+		return record, r.parseError(fmt.Errorf("unknown keys %s, the header is taken from the first object", strings.Join(unknown, ", ")))
+	}
+
+	return record, nil
+}
+
+// parseError ties err to the line read last, so that the parser treats it as a row error.
+func (r *JSONLReader) parseError(err error) error {
+	return &csv.ParseError{StartLine: r.line, Line: r.line, Err: err}
+}
+
+func (r *JSONLReader) unknownKeys(object map[string]any) []string {
+	var unknown []string
+	for key := range object {
+		if !slices.Contains(r.header, key) {
+			unknown = append(unknown, key)
+		}
+	}
+	slices.Sort(unknown)
+
+	return unknown
+}
+
+// Line returns the line of the object read last.
+func (r *JSONLReader) Line() int {
+	return r.line
+}
+
+func (r *JSONLReader) next() (map[string]any, error) {
+	for {
+		data, err := r.r.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+
+		if len(bytes.TrimSpace(data)) == 0 {
+			if err != nil {
+				return nil, io.EOF
+			}
+
+			r.read++
+			continue
+		}
+
+		r.read++
+		r.line = r.read
+		r.raw = string(bytes.TrimSpace(data))
+
+		return r.decode(data)
+	}
+}
+
+func (r *JSONLReader) decode(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, r.parseError(fmt.Errorf("invalid JSON: %w", err))
+	}
+
+	object, ok := value.(map[string]any)
+	if !ok {
+		return nil, r.parseError(errors.New("expected an object"))
+	}
+
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, r.parseError(errors.New("unexpected data after the object"))
+	}
+
+	return object, nil
+}
+
+func stringify(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+}
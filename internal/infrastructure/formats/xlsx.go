@@ -0,0 +1,93 @@
+package formats
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jszwec/csvutil"
+	"github.com/kurochkinivan/device_reporter/internal/config"
+	"github.com/xuri/excelize/v2"
+)
+
+type XLSX struct{}
+
+func NewXLSX() *XLSX {
+	return &XLSX{}
+}
+
+func (x *XLSX) Name() string {
+	return "xlsx"
+}
+
+func (x *XLSX) Extensions() []string {
+	return []string{".xlsx"}
+}
+
+// Sniff matches the zip signature an OOXML workbook starts with.
+func (x *XLSX) Sniff(head []byte) bool {
+	return len(head) >= 4 && string(head[:4]) == "PK\x03\x04"
+}
+
+// Binary is true: a workbook is a zip archive, transcoding would corrupt it.
+func (x *XLSX) Binary() bool {
+	return true
+}
+
+// NewReader reads rows of the first sheet.
+func (x *XLSX) NewReader(r io.Reader, _ config.Parser) (csvutil.Reader, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workbook: %w", err)
+	}
+
+	rows, err := f.Rows(f.GetSheetName(0))
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to read sheet: %w", err), f.Close())
+	}
+
+	return &RowsReader{file: f, rows: rows}, nil
+}
+
+// RowsReader streams the rows of a sheet, so the sheet is never held in memory whole.
+// Rows are padded to the width of the header because spreadsheets drop trailing empty cells.
+type RowsReader struct {
+	file  *excelize.File
+	rows  *excelize.Rows
+	width int
+	line  int
+}
+
+func (r *RowsReader) Read() ([]string, error) {
+	// Next проходит и пустые строки листа, поэтому номер строки совпадает с номером в книге
+	if !r.rows.Next() {
+		if err := r.rows.Error(); err != nil {
+			return nil, fmt.Errorf("failed to read sheet: %w", err)
+		}
+		return nil, io.EOF
+	}
+
+	row, err := r.rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read row %d: %w", r.line+1, err)
+	}
+	r.line++
+
+	if r.line == 1 {
+		r.width = len(row)
+	} else if len(row) < r.width {
+		row = append(row, make([]string, r.width-len(row))...)
+	}
+
+	return row, nil
+}
+
+// Line returns the row number of the record read last.
+func (r *RowsReader) Line() int {
+	return r.line
+}
+
+// Close releases the workbook and the temporary files of the sheet.
+func (r *RowsReader) Close() error {
+	return errors.Join(r.rows.Close(), r.file.Close())
+}
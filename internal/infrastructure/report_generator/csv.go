@@ -0,0 +1,86 @@
+package report_generator
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/kurochkinivan/device_reporter/internal/config"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+)
+
+// CSV renders the records of a report as a table, one row per record.
+type CSV struct{}
+
+func NewCSV() *CSV {
+	return &CSV{}
+}
+
+func (c *CSV) Format() string {
+	return config.ReportFormatCSV
+}
+
+func (c *CSV) GenerateReport(outputPath string, _ config.ReportStyle, _, sourceFile string, devices []*domain.Device) (err error) {
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	defer func() { err = errors.Join(err, f.Close()) }()
+
+	w := csv.NewWriter(f)
+
+	// исходный файл записи нужен, чтобы различать разделы накопительного отчёта
+	if err := w.Write(append([]string{"file_name"}, deviceColumns...)); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	for _, device := range devices {
+		if err := w.Write(append([]string{recordFile(device, sourceFile)}, deviceValues(device)...)); err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
+
+func (c *CSV) GenerateFleetReport(outputPath string, _ config.ReportStyle, fleet *domain.FleetReport) (err error) {
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	defer func() { err = errors.Join(err, f.Close()) }()
+
+	w := csv.NewWriter(f)
+
+	if err := w.Write([]string{"inv_id", "unit_guid", "records", "alarms", "warnings", "report_version", "report"}); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	for _, unit := range fleet.Units {
+		version := ""
+		if unit.ReportVersion != nil {
+			version = strconv.Itoa(*unit.ReportVersion)
+		}
+
+		record := []string{
+			unit.InvID,
+			unit.UnitGUID,
+			strconv.Itoa(unit.Records),
+			strconv.Itoa(unit.Alarms),
+			strconv.Itoa(unit.Warnings),
+			version,
+			unitLink(outputPath, config.ReportFormatCSV, unit),
+		}
+		if err := w.Write(record); err != nil {
+			return fmt.Errorf("failed to write unit: %w", err)
+		}
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
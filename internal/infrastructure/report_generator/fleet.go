@@ -0,0 +1,119 @@
+package report_generator
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/kurochkinivan/device_reporter/internal/config"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+)
+
+// fleetContent is what every format renders in a fleet report: the units
+// grouped by inventory ID with the totals of the report and of every group.
+type fleetContent struct {
+	FileName    string
+	InvID       string
+	GeneratedAt time.Time
+	Total       fleetTotal
+	Groups      []fleetGroup
+
+	locale *locale
+}
+
+type fleetTotal struct {
+	Units    int `json:"units"`
+	Records  int `json:"records"`
+	Alarms   int `json:"alarms"`
+	Warnings int `json:"warnings"`
+}
+
+func (t *fleetTotal) add(unit *domain.FleetUnit) {
+	t.Units++
+	t.Records += unit.Records
+	t.Alarms += unit.Alarms
+	t.Warnings += unit.Warnings
+}
+
+type fleetGroup struct {
+	InvID string
+	Total fleetTotal
+	Units []*domain.FleetUnit
+}
+
+// newFleetContent groups consecutive units by inventory ID; the units come ordered by it.
+func newFleetContent(style config.ReportStyle, fleet *domain.FleetReport) fleetContent {
+	c := fleetContent{
+		FileName:    fleet.FileName,
+		InvID:       fleet.InvID,
+		GeneratedAt: time.Now().In(timeZone(style)),
+		locale:      localeOf(style.Locale),
+	}
+
+	for _, unit := range fleet.Units {
+		c.Total.add(unit)
+
+		if n := len(c.Groups); n == 0 || c.Groups[n-1].InvID != unit.InvID {
+			c.Groups = append(c.Groups, fleetGroup{InvID: unit.InvID})
+		}
+
+		group := &c.Groups[len(c.Groups)-1]
+		group.Total.add(unit)
+		group.Units = append(group.Units, unit)
+	}
+
+	return c
+}
+
+// scope names what the report covers: the processed file or the inventory ID.
+func (c fleetContent) scope() (label, value string) {
+	if c.InvID != "" {
+		return c.locale.text("Inventory ID:"), c.InvID
+	}
+
+	return c.locale.text("Source File:"), c.FileName
+}
+
+// text translates a layout text and substitutes the meta fields that apply to the whole
+// report; the fields of a single unit are left empty.
+func (c fleetContent) text(s string) string {
+	return expand(c.locale.text(s), map[string]string{
+		"unit_guid":     "",
+		"inv_id":        c.InvID,
+		"source_file":   c.FileName,
+		"source_files":  "",
+		"total_records": c.locale.number(c.Total.Records),
+		"generated_at":  c.locale.time(c.GeneratedAt),
+	})
+}
+
+// unitLink returns the latest report of a unit relative to the fleet report, preferably
+// in the same format. It is empty while the unit has no report.
+func unitLink(outputPath, format string, unit *domain.FleetUnit) string {
+	if len(unit.ReportFiles) == 0 {
+		return ""
+	}
+
+	file := unit.ReportFiles[0]
+	for _, f := range unit.ReportFiles {
+		if f.Format == format {
+			file = f
+			break
+		}
+	}
+
+	link, err := filepath.Rel(filepath.Dir(outputPath), file.Path)
+	if err != nil {
+		return ""
+	}
+
+	return filepath.ToSlash(link)
+}
+
+// reportVersion is the version of the latest report of a unit, empty without one.
+func (c fleetContent) reportVersion(unit *domain.FleetUnit) string {
+	if unit.ReportVersion == nil {
+		return ""
+	}
+
+	return "v" + c.locale.number(*unit.ReportVersion)
+}
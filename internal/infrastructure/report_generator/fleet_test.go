@@ -0,0 +1,266 @@
+package report_generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kurochkinivan/device_reporter/internal/config"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	fleetGUIDA = "01749246-95f6-57db-b7c3-2ae0e8be671f"
+	fleetGUIDB = "0a1b2c3d-95f6-57db-b7c3-2ae0e8be671f"
+	fleetGUIDC = "0f0e0d0c-95f6-57db-b7c3-2ae0e8be671f"
+)
+
+// testFleet lists a unit without an inventory ID and a report, a unit with reports in
+// every format and a unit with a PDF report only, under reports directory dir.
+func testFleet(dir string) *domain.FleetReport {
+	latest, first := 2, 1
+
+	files := make([]*domain.ReportFile, 0, 5)
+	for _, format := range []string{"pdf", "html", "xlsx", "csv", "json"} {
+		files = append(files, &domain.ReportFile{Format: format, Path: filepath.Join(dir, fleetGUIDB, "v2_data."+format)})
+	}
+
+	return &domain.FleetReport{
+		Source:   "plant-a",
+		FileName: "data.tsv",
+		Units: []*domain.FleetUnit{
+			{UnitGUID: fleetGUIDA, Records: 3},
+			{UnitGUID: fleetGUIDB, InvID: "INV-7", Records: 10, Alarms: 2, Warnings: 1, ReportVersion: &latest, ReportFiles: files},
+			{
+				UnitGUID: fleetGUIDC, InvID: "INV-7", Records: 5, Warnings: 4, ReportVersion: &first,
+				ReportFiles: []*domain.ReportFile{{Format: "pdf", Path: filepath.Join(dir, fleetGUIDC, "v1_data.pdf")}},
+			},
+		},
+	}
+}
+
+func TestNewFleetContent(t *testing.T) {
+	t.Parallel()
+
+	fleet := testFleet(t.TempDir())
+	c := newFleetContent(config.ReportStyle{}, fleet)
+
+	assert.Equal(t, fleetTotal{Units: 3, Records: 18, Alarms: 2, Warnings: 5}, c.Total)
+	assert.Equal(t, []fleetGroup{
+		{InvID: "", Total: fleetTotal{Units: 1, Records: 3}, Units: fleet.Units[:1]},
+		{InvID: "INV-7", Total: fleetTotal{Units: 2, Records: 15, Alarms: 2, Warnings: 5}, Units: fleet.Units[1:]},
+	}, c.Groups)
+
+	assert.Empty(t, c.reportVersion(fleet.Units[0]))
+	assert.Equal(t, "v2", c.reportVersion(fleet.Units[1]))
+}
+
+func TestUnitLink(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join("reports", "plant-a")
+	fleet := testFleet(dir)
+
+	tests := []struct {
+		name   string
+		output string
+		format string
+		unit   *domain.FleetUnit
+		want   string
+	}{
+		{
+			name:   "no report",
+			output: filepath.Join(dir, "fleet", "files", "data.csv"),
+			format: "csv",
+			unit:   fleet.Units[0],
+			want:   "",
+		},
+		{
+			name:   "same format",
+			output: filepath.Join(dir, "fleet", "files", "data.csv"),
+			format: "csv",
+			unit:   fleet.Units[1],
+			want:   "../../" + fleetGUIDB + "/v2_data.csv",
+		},
+		{
+			// Отчёта в формате сводного нет — ссылка на первый из построенных
+			name:   "other format",
+			output: filepath.Join(dir, "fleet", "inventory", "INV-7.html"),
+			format: "html",
+			unit:   fleet.Units[2],
+			want:   "../../" + fleetGUIDC + "/v1_data.pdf",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, unitLink(tt.output, tt.format, tt.unit))
+		})
+	}
+}
+
+func TestCSV_GenerateFleetReport(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	output := fleetOutput(t, dir, "csv")
+
+	require.NoError(t, NewCSV().GenerateFleetReport(output, config.ReportStyle{}, testFleet(dir)))
+
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+
+	want := "inv_id,unit_guid,records,alarms,warnings,report_version,report\n" +
+		"," + fleetGUIDA + ",3,0,0,,\n" +
+		"INV-7," + fleetGUIDB + ",10,2,1,2,../../" + fleetGUIDB + "/v2_data.csv\n" +
+		"INV-7," + fleetGUIDC + ",5,0,4,1,../../" + fleetGUIDC + "/v1_data.pdf\n"
+	assert.Equal(t, want, string(content))
+}
+
+func TestJSON_GenerateFleetReport(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	output := fleetOutput(t, dir, "json")
+
+	require.NoError(t, NewJSON().GenerateFleetReport(output, config.ReportStyle{}, testFleet(dir)))
+
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+
+	var report map[string]any
+	require.NoError(t, json.Unmarshal(content, &report))
+	assert.NotEmpty(t, report["generated_at"])
+	delete(report, "generated_at")
+
+	// версия устройства без отчёта — null, ссылки нет
+	want := map[string]any{
+		"file_name": "data.tsv",
+		"total":     map[string]any{"units": 3.0, "records": 18.0, "alarms": 2.0, "warnings": 5.0},
+		"groups": []any{
+			map[string]any{
+				"inv_id": "",
+				"total":  map[string]any{"units": 1.0, "records": 3.0, "alarms": 0.0, "warnings": 0.0},
+				"units": []any{
+					map[string]any{"unit_guid": fleetGUIDA, "records": 3.0, "alarms": 0.0, "warnings": 0.0, "report_version": nil},
+				},
+			},
+			map[string]any{
+				"inv_id": "INV-7",
+				"total":  map[string]any{"units": 2.0, "records": 15.0, "alarms": 2.0, "warnings": 5.0},
+				"units": []any{
+					map[string]any{
+						"unit_guid": fleetGUIDB, "records": 10.0, "alarms": 2.0, "warnings": 1.0,
+						"report_version": 2.0, "report": "../../" + fleetGUIDB + "/v2_data.json",
+					},
+					map[string]any{
+						"unit_guid": fleetGUIDC, "records": 5.0, "alarms": 0.0, "warnings": 4.0,
+						"report_version": 1.0, "report": "../../" + fleetGUIDC + "/v1_data.pdf",
+					},
+				},
+			},
+		},
+	}
+	assert.Equal(t, want, report)
+}
+
+func TestHTML_GenerateFleetReport(t *testing.T) {
+	t.Parallel()
+
+	layouts, err := LoadLayouts("")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	output := fleetOutput(t, dir, "html")
+
+	style := config.ReportStyle{Locale: config.ReportLocaleRU}
+	require.NoError(t, NewHTML(layouts).GenerateFleetReport(output, style, testFleet(dir)))
+
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+	page := string(content)
+
+	assert.Contains(t, page, `<html lang="ru">`)
+	assert.Contains(t, page, "<h1>Сводный отчёт по устройствам</h1>")
+	assert.Contains(t, page, "<tr><td>Исходный файл:</td><td>data.tsv</td></tr>")
+	assert.Contains(t, page, "<tr><td>Устройств:</td><td>3</td></tr>")
+
+	// устройства без инвентарного номера собраны в группу «—»
+	assert.Contains(t, page, `<tr class="group"><td>Инвентарный номер: —</td>`)
+	assert.Contains(t, page, `<tr class="group"><td>Инвентарный номер: INV-7</td>`)
+
+	// у устройства без отчёта нет ни ссылки, ни версии
+	assert.Contains(t, page, "<tr><td>"+fleetGUIDA+"</td>")
+	assert.Contains(t, page, `<td style="background: #FFFFFF">0</td><td></td></tr>`)
+
+	assert.Contains(t, page, `<a href="../../`+fleetGUIDB+`/v2_data.html">`+fleetGUIDB+`</a>`)
+	assert.Contains(t, page, `<a href="../../`+fleetGUIDC+`/v1_data.pdf">`+fleetGUIDC+`</a>`)
+	assert.Contains(t, page, "<td>v2</td></tr>")
+}
+
+func TestXLSX_GenerateFleetReport(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	output := fleetOutput(t, dir, "xlsx")
+
+	require.NoError(t, NewXLSX().GenerateFleetReport(output, config.ReportStyle{}, testFleet(dir)))
+
+	f, err := excelize.OpenFile(output)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, f.Close()) })
+
+	rows, err := f.GetRows(xlsxSheet)
+	require.NoError(t, err)
+	require.Len(t, rows, 11)
+
+	assert.Equal(t, []string{"Source File:", "data.tsv"}, rows[0])
+	assert.Equal(t, []string{"Units:", "3"}, rows[1])
+	assert.Equal(t, []string{"inv_id", "unit_guid", "records", "alarms", "warnings", "report_version", "report"}, rows[7])
+	assert.Equal(t, []string{"", fleetGUIDA, "3", "0", "0"}, rows[8])
+	assert.Equal(t, []string{"INV-7", fleetGUIDB, "10", "2", "1", "v2", "../../" + fleetGUIDB + "/v2_data.xlsx"}, rows[9])
+	assert.Equal(t, []string{"INV-7", fleetGUIDC, "5", "0", "4", "v1", "../../" + fleetGUIDC + "/v1_data.pdf"}, rows[10])
+
+	linked, link, err := f.GetCellHyperLink(xlsxSheet, "G10")
+	require.NoError(t, err)
+	assert.True(t, linked)
+	assert.Equal(t, "../../"+fleetGUIDB+"/v2_data.xlsx", link)
+
+	linked, _, err = f.GetCellHyperLink(xlsxSheet, "G9")
+	require.NoError(t, err)
+	assert.False(t, linked)
+}
+
+func TestPDF_GenerateFleetReport(t *testing.T) {
+	t.Parallel()
+
+	layouts, err := LoadLayouts("")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	output := fleetOutput(t, dir, "pdf")
+
+	require.NoError(t, NewPDF(layouts).GenerateFleetReport(output, config.ReportStyle{}, testFleet(dir)))
+
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(content, []byte("%PDF-")))
+}
+
+// fleetOutput is the path of the fleet report of data.tsv within reports directory dir,
+// as the Reporter lays it out.
+func fleetOutput(t *testing.T, dir, format string) string {
+	t.Helper()
+
+	output := filepath.Join(dir, "fleet", "files", "data."+format)
+	require.NoError(t, os.MkdirAll(filepath.Dir(output), 0o755))
+
+	return output
+}
@@ -0,0 +1,376 @@
+package report_generator
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+
+	"github.com/kurochkinivan/device_reporter/internal/config"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+)
+
+// HTML renders a report as a standalone page to be opened in a browser. The table
+// shows the fields of the record card of the source layout.
+type HTML struct {
+	layouts   Layouts
+	tmpl      *template.Template
+	fleetTmpl *template.Template
+}
+
+func NewHTML(layouts Layouts) *HTML {
+	return &HTML{
+		layouts:   layouts,
+		tmpl:      template.Must(template.New("report").Parse(htmlTemplate)),
+		fleetTmpl: template.Must(template.New("fleet").Parse(htmlFleetTemplate)),
+	}
+}
+
+func (h *HTML) Format() string {
+	return config.ReportFormatHTML
+}
+
+// htmlReport is the content of a report prepared for the template by its layout.
+type htmlReport struct {
+	content
+	Layout       *Layout
+	Title        string
+	Header       string
+	Footer       string
+	RecordsTitle string
+	Meta         []htmlMeta
+	Logo         template.URL
+	Columns      []string
+}
+
+type htmlMeta struct {
+	Label string
+	Value string
+}
+
+// Background is the row color of a record class.
+func (r htmlReport) Background(class string) string {
+	return r.Layout.Colors.class(class).Background
+}
+
+// Values are the values of the columns of a record, with the class translated.
+func (r htmlReport) Values(d *domain.Device) []string {
+	values := make([]string, 0, len(r.Columns))
+	for _, column := range r.Columns {
+		value := deviceField(d, column)
+		if column == "class" {
+			value = r.locale.class(value)
+		}
+		values = append(values, value)
+	}
+
+	return values
+}
+
+// SectionTitle heads the records of a source file.
+func (r htmlReport) SectionTitle(section fileSection) string {
+	return r.sectionTitle(r.Layout.SectionTitle, section)
+}
+
+// T translates a report string.
+func (r htmlReport) T(s string) string {
+	return r.locale.text(s)
+}
+
+// Class translates a record class.
+func (r htmlReport) Class(class string) string {
+	return r.locale.class(class)
+}
+
+// Number formats a count for the locale of the report.
+func (r htmlReport) Number(n int) string {
+	return r.locale.number(n)
+}
+
+// Share is the percentage of the records of the report a count makes up.
+func (r htmlReport) Share(count int) int {
+	return r.share(count)
+}
+
+// Accent is the badge color of a record class.
+func (r htmlReport) Accent(class string) string {
+	return r.Layout.Colors.class(class).Accent
+}
+
+// Lang is the language of the page.
+func (r htmlReport) Lang() string {
+	return r.locale.tag.String()
+}
+
+func (h *HTML) GenerateReport(outputPath string, style config.ReportStyle, unitGUID, sourceFile string, devices []*domain.Device) (err error) {
+	layout, err := h.layouts.get(style.Layout)
+	if err != nil {
+		return err
+	}
+
+	c := newContent(style, unitGUID, sourceFile, devices)
+	meta := c.meta()
+
+	report := htmlReport{
+		content:      c,
+		Layout:       layout,
+		Title:        c.text(layout.Title),
+		Header:       c.text(layout.Header),
+		Footer:       c.text(layout.Footer),
+		RecordsTitle: c.text(layout.RecordsTitle),
+		Columns:      layout.Card.fields(),
+	}
+
+	for _, field := range layout.Meta {
+		// число файлов имеет смысл только в накопительном отчёте
+		if field.Field == "source_files" && len(c.Sections) < 2 {
+			continue
+		}
+		report.Meta = append(report.Meta, htmlMeta{Label: c.locale.text(field.Label), Value: meta[field.Field]})
+	}
+
+	report.Logo = logoURL(layout)
+
+	return h.render(h.tmpl, outputPath, report)
+}
+
+// htmlFleet is the content of a fleet report prepared for the template.
+type htmlFleet struct {
+	fleetContent
+	Layout     *Layout
+	Title      string
+	Header     string
+	Footer     string
+	ScopeLabel string
+	ScopeValue string
+	Logo       template.URL
+
+	outputPath string
+}
+
+// T translates a report string.
+func (f htmlFleet) T(s string) string {
+	return f.locale.text(s)
+}
+
+// Number formats a count for the locale of the report.
+func (f htmlFleet) Number(n int) string {
+	return f.locale.number(n)
+}
+
+// Link is the latest report of a unit relative to the page.
+func (f htmlFleet) Link(unit *domain.FleetUnit) string {
+	return unitLink(f.outputPath, config.ReportFormatHTML, unit)
+}
+
+// Lang is the language of the page.
+func (f htmlFleet) Lang() string {
+	return f.locale.tag.String()
+}
+
+// Version is the version of the latest report of a unit.
+func (f htmlFleet) Version(unit *domain.FleetUnit) string {
+	return f.reportVersion(unit)
+}
+
+// Background is the cell color of a class with records, white without them.
+func (f htmlFleet) Background(class string, count int) string {
+	if count == 0 {
+		return "FFFFFF"
+	}
+
+	return f.Layout.Colors.class(class).Background
+}
+
+func (h *HTML) GenerateFleetReport(outputPath string, style config.ReportStyle, fleet *domain.FleetReport) error {
+	layout, err := h.layouts.get(style.Layout)
+	if err != nil {
+		return err
+	}
+
+	c := newFleetContent(style, fleet)
+	scopeLabel, scopeValue := c.scope()
+
+	return h.render(h.fleetTmpl, outputPath, htmlFleet{
+		fleetContent: c,
+		Layout:       layout,
+		Title:        c.locale.text("Fleet Report"),
+		Header:       c.text("Generated: {generated_at}"),
+		Footer:       c.text(layout.Footer),
+		ScopeLabel:   scopeLabel,
+		ScopeValue:   scopeValue,
+		Logo:         logoURL(layout),
+		outputPath:   outputPath,
+	})
+}
+
+func (h *HTML) render(tmpl *template.Template, outputPath string, data any) (err error) {
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	defer func() { err = errors.Join(err, f.Close()) }()
+
+	if err := tmpl.Execute(f, data); err != nil {
+		return fmt.Errorf("failed to render html: %w", err)
+	}
+
+	return nil
+}
+
+// logoURL embeds the logo of the layout, so the page opens without the layouts directory.
+func logoURL(layout *Layout) template.URL {
+	if layout.logo == nil {
+		return ""
+	}
+
+	return template.URL("data:image/" + string(layout.logoExtension) + ";base64," + base64.StdEncoding.EncodeToString(layout.logo))
+}
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} {{.UnitGUID}}</title>
+<style>
+body { font-family: "DejaVu Sans", Arial, sans-serif; color: #{{.Layout.Colors.Text}}; margin: 24px; }
+.logo { display: block; margin: 0 auto; max-height: {{.Layout.LogoHeight}}mm; }
+h1 { text-align: center; font-size: 22px; }
+.header { text-align: center; color: #{{.Layout.Colors.Muted}}; font-size: 12px; }
+.meta td { padding: 2px 12px 2px 0; }
+.meta td:first-child { font-weight: bold; }
+h2 { font-size: 16px; margin-top: 24px; }
+h3 { font-size: 14px; background: #{{.Layout.Colors.Section}}; padding: 6px; }
+table.records { border-collapse: collapse; width: 100%; font-size: 12px; }
+table.records th, table.records td { border: 1px solid #ccc; padding: 4px; text-align: left; }
+table.records th { background: #f4f4f4; }
+.summary { display: flex; gap: 48px; align-items: flex-start; }
+.summary table td { padding: 2px 12px 2px 0; font-size: 12px; }
+.summary td.count { text-align: right; }
+.class { font-weight: bold; padding: 2px 6px; text-transform: uppercase; }
+.chart { width: 320px; }
+.bar { height: 12px; }
+table.alarms td { padding: 4px; font-size: 12px; }
+footer { margin-top: 24px; text-align: center; color: #{{.Layout.Colors.Muted}}; font-size: 11px; }
+</style>
+</head>
+<body>
+{{- if .Logo}}
+<img class="logo" src="{{.Logo}}" alt="">
+{{- end}}
+{{- if .Title}}
+<h1>{{.Title}}</h1>
+{{- end}}
+{{- if .Header}}
+<p class="header">{{.Header}}</p>
+{{- end}}
+<table class="meta">
+{{- range .Meta}}
+<tr><td>{{.Label}}</td><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+{{- if .Layout.Summary}}
+<h2>{{.T "Summary"}}</h2>
+<div class="summary">
+<table>
+<tr><th colspan="4">{{.T "Records by class"}}</th></tr>
+{{- range .Summary.Classes}}
+<tr><td class="class" style="background: #{{$.Background .Value}}">{{$.Class .Value}}</td><td class="count">{{$.Number .Count}}</td><td class="count">{{$.Share .Count}}%</td>
+<td class="chart"><div class="bar" style="width: {{$.Share .Count}}%; background: #{{$.Accent .Value}}"></div></td></tr>
+{{- end}}
+</table>
+<table>
+<tr><th colspan="2">{{.T "Records by area"}}</th></tr>
+{{- range .Summary.Areas}}
+<tr><td>{{if .Value}}{{.Value}}{{else}}—{{end}}</td><td class="count">{{$.Number .Count}}</td></tr>
+{{- end}}
+</table>
+<table>
+<tr><th colspan="2">{{.T "Records by level"}}</th></tr>
+{{- range .Summary.Levels}}
+<tr><td>{{.Value}}</td><td class="count">{{$.Number .Count}}</td></tr>
+{{- end}}
+</table>
+</div>
+<h3>{{.T "Alarm records"}}: {{.Number (len .Summary.Alarms)}}</h3>
+{{- if .Summary.Alarms}}
+<table class="alarms">
+{{- range .Summary.Alarms}}
+<tr style="background: #{{$.Background .Class}}"><td>{{.N}}</td><td><b>{{.MsgID}}</b></td><td>{{.Text}}</td><td>{{.Area}} / {{.Addr}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p class="header">{{.T "No alarm records"}}</p>
+{{- end}}
+{{- end}}
+{{- if .RecordsTitle}}
+<h2>{{.RecordsTitle}}</h2>
+{{- end}}
+{{- range .Sections}}
+{{- if .FileName}}
+<h3>{{$.SectionTitle .}}</h3>
+{{- end}}
+<table class="records">
+<tr>{{range $.Columns}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Devices}}
+<tr style="background: #{{$.Background .Class}}">{{range $.Values .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Footer}}
+<footer>{{.Footer}}</footer>
+{{- end}}
+</body>
+</html>
+`
+
+const htmlFleetTemplate = `<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} {{.ScopeValue}}</title>
+<style>
+body { font-family: "DejaVu Sans", Arial, sans-serif; color: #{{.Layout.Colors.Text}}; margin: 24px; }
+.logo { display: block; margin: 0 auto; max-height: {{.Layout.LogoHeight}}mm; }
+h1 { text-align: center; font-size: 22px; }
+.header { text-align: center; color: #{{.Layout.Colors.Muted}}; font-size: 12px; }
+.meta td { padding: 2px 12px 2px 0; }
+.meta td:first-child { font-weight: bold; }
+table.units { border-collapse: collapse; width: 100%; font-size: 12px; margin-top: 24px; }
+table.units th, table.units td { border: 1px solid #ccc; padding: 4px; text-align: left; }
+table.units th { background: #{{.Layout.Colors.Section}}; }
+table.units tr.group td { font-weight: bold; background: #f4f4f4; }
+footer { margin-top: 24px; text-align: center; color: #{{.Layout.Colors.Muted}}; font-size: 11px; }
+</style>
+</head>
+<body>
+{{- if .Logo}}
+<img class="logo" src="{{.Logo}}" alt="">
+{{- end}}
+<h1>{{.Title}}</h1>
+<p class="header">{{.Header}}</p>
+<table class="meta">
+<tr><td>{{.ScopeLabel}}</td><td>{{.ScopeValue}}</td></tr>
+<tr><td>{{.T "Units:"}}</td><td>{{.Number .Total.Units}}</td></tr>
+<tr><td>{{.T "Total Records:"}}</td><td>{{.Number .Total.Records}}</td></tr>
+<tr><td>{{.T "Alarms:"}}</td><td>{{.Number .Total.Alarms}}</td></tr>
+<tr><td>{{.T "Warnings:"}}</td><td>{{.Number .Total.Warnings}}</td></tr>
+</table>
+<table class="units">
+<tr><th>{{.T "Unit GUID"}}</th><th>{{.T "Records"}}</th><th>{{.T "Alarms"}}</th><th>{{.T "Warnings"}}</th><th>{{.T "Report"}}</th></tr>
+{{- range .Groups}}
+<tr class="group"><td>{{$.T "Inventory ID:"}} {{if .InvID}}{{.InvID}}{{else}}—{{end}}</td><td>{{$.Number .Total.Records}}</td><td>{{$.Number .Total.Alarms}}</td><td>{{$.Number .Total.Warnings}}</td><td></td></tr>
+{{- range .Units}}
+<tr><td>{{with $.Link .}}<a href="{{.}}">{{end}}{{.UnitGUID}}{{if $.Link .}}</a>{{end}}</td><td>{{$.Number .Records}}</td>
+<td style="background: #{{$.Background "alarm" .Alarms}}">{{$.Number .Alarms}}</td>
+<td style="background: #{{$.Background "warning" .Warnings}}">{{$.Number .Warnings}}</td><td>{{$.Version .}}</td></tr>
+{{- end}}
+{{- end}}
+</table>
+{{- if .Footer}}
+<footer>{{.Footer}}</footer>
+{{- end}}
+</body>
+</html>
+`
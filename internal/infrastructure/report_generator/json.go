@@ -0,0 +1,133 @@
+package report_generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kurochkinivan/device_reporter/internal/config"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+)
+
+// JSON renders a report as a machine-readable document with the same content as the PDF.
+type JSON struct{}
+
+func NewJSON() *JSON {
+	return &JSON{}
+}
+
+func (j *JSON) Format() string {
+	return config.ReportFormatJSON
+}
+
+type jsonReport struct {
+	UnitGUID     string        `json:"unit_guid"`
+	InvID        string        `json:"inv_id"`
+	SourceFile   string        `json:"source_file"`
+	GeneratedAt  time.Time     `json:"generated_at"`
+	TotalRecords int           `json:"total_records"`
+	Summary      jsonSummary   `json:"summary"`
+	Sections     []jsonSection `json:"sections"`
+}
+
+// jsonSummary holds the record counts of the report summary; the alarm records
+// are found among the sections by their class.
+type jsonSummary struct {
+	Classes []tally `json:"classes"`
+	Areas   []tally `json:"areas"`
+	Levels  []tally `json:"levels"`
+}
+
+type jsonSection struct {
+	// FileName is empty in a per-file report.
+	FileName string           `json:"file_name,omitempty"`
+	Devices  []*domain.Device `json:"devices"`
+}
+
+func (j *JSON) GenerateReport(outputPath string, style config.ReportStyle, unitGUID, sourceFile string, devices []*domain.Device) error {
+	c := newContent(style, unitGUID, sourceFile, devices)
+
+	report := jsonReport{
+		UnitGUID:     c.UnitGUID,
+		InvID:        c.InvID,
+		SourceFile:   c.SourceFile,
+		GeneratedAt:  c.GeneratedAt,
+		TotalRecords: c.Total,
+		Summary: jsonSummary{
+			Classes: c.Summary.Classes,
+			Areas:   c.Summary.Areas,
+			Levels:  c.Summary.Levels,
+		},
+		Sections: make([]jsonSection, 0, len(c.Sections)),
+	}
+	for _, section := range c.Sections {
+		report.Sections = append(report.Sections, jsonSection(section))
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	return os.WriteFile(outputPath, data, 0o644)
+}
+
+type jsonFleetReport struct {
+	// FileName or InvID names what the report covers, the other one is empty.
+	FileName    string           `json:"file_name,omitempty"`
+	InvID       string           `json:"inv_id,omitempty"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Total       fleetTotal       `json:"total"`
+	Groups      []jsonFleetGroup `json:"groups"`
+}
+
+type jsonFleetGroup struct {
+	InvID string          `json:"inv_id"`
+	Total fleetTotal      `json:"total"`
+	Units []jsonFleetUnit `json:"units"`
+}
+
+type jsonFleetUnit struct {
+	UnitGUID      string `json:"unit_guid"`
+	Records       int    `json:"records"`
+	Alarms        int    `json:"alarms"`
+	Warnings      int    `json:"warnings"`
+	ReportVersion *int   `json:"report_version"`
+	// Report is the latest report of the unit relative to the fleet report.
+	Report string `json:"report,omitempty"`
+}
+
+func (j *JSON) GenerateFleetReport(outputPath string, style config.ReportStyle, fleet *domain.FleetReport) error {
+	c := newFleetContent(style, fleet)
+
+	report := jsonFleetReport{
+		FileName:    c.FileName,
+		InvID:       c.InvID,
+		GeneratedAt: c.GeneratedAt,
+		Total:       c.Total,
+		Groups:      make([]jsonFleetGroup, 0, len(c.Groups)),
+	}
+	for _, group := range c.Groups {
+		units := make([]jsonFleetUnit, 0, len(group.Units))
+		for _, unit := range group.Units {
+			units = append(units, jsonFleetUnit{
+				UnitGUID:      unit.UnitGUID,
+				Records:       unit.Records,
+				Alarms:        unit.Alarms,
+				Warnings:      unit.Warnings,
+				ReportVersion: unit.ReportVersion,
+				Report:        unitLink(outputPath, config.ReportFormatJSON, unit),
+			})
+		}
+
+		report.Groups = append(report.Groups, jsonFleetGroup{InvID: group.InvID, Total: group.Total, Units: units})
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	return os.WriteFile(outputPath, data, 0o644)
+}
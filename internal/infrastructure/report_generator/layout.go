@@ -0,0 +1,323 @@
+package report_generator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	_ "embed"
+
+	"github.com/johnfercher/maroto/v2/pkg/consts/extension"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLayout names the built-in layout. It is used by sources that select no layout.
+const DefaultLayout = "default"
+
+//go:embed layouts/default.yaml
+var defaultLayout []byte
+
+// Layout describes how the PDF and HTML reports look. Layouts are YAML files
+// named <layout>.yaml, and every field a file leaves out is taken from the built-in layout.
+type Layout struct {
+	Title string `yaml:"title"`
+	// Header and Footer are printed under the title and at the end of the report.
+	// They may contain the meta fields in braces, e.g. {unit_guid}.
+	Header string `yaml:"header"`
+	Footer string `yaml:"footer"`
+	// Logo is a PNG or JPEG image printed above the title, relative to the layouts directory.
+	Logo       string      `yaml:"logo"`
+	LogoHeight float64     `yaml:"logo_height"`
+	Meta       []MetaField `yaml:"meta"`
+	// Summary prints the record counts by class, area and level, a chart of the classes
+	// and the alarm records before the records themselves.
+	Summary bool `yaml:"summary"`
+	// RecordsTitle heads the records; an empty title is not printed.
+	RecordsTitle string `yaml:"records_title"`
+	// SectionTitle heads the records of a source file in a cumulative report,
+	// {file} and {records} are substituted.
+	SectionTitle string `yaml:"section_title"`
+	Card         Card   `yaml:"card"`
+	Colors       Colors `yaml:"colors"`
+
+	logo          []byte
+	logoExtension extension.Type
+}
+
+// MetaField is a line of the meta-information block. Field is one of metaFields.
+type MetaField struct {
+	Label string `yaml:"label"`
+	Field string `yaml:"field"`
+}
+
+// Card describes the card of a record. Fields are named by the record columns.
+type Card struct {
+	Number string    `yaml:"number"`
+	Title  string    `yaml:"title"`
+	Badge  string    `yaml:"badge"`
+	Rows   []CardRow `yaml:"rows"`
+	// Widths are given in the 12 columns of the page grid; the title and
+	// the row values take the rest of the line.
+	NumberWidth int `yaml:"number_width"`
+	BadgeWidth  int `yaml:"badge_width"`
+	LabelWidth  int `yaml:"label_width"`
+}
+
+// CardRow shows the values of its fields separated by slashes.
+type CardRow struct {
+	Label  string   `yaml:"label"`
+	Fields []string `yaml:"fields"`
+}
+
+// Colors are hex RGB values, e.g. 1A1A2E.
+type Colors struct {
+	Text      string `yaml:"text"`
+	Muted     string `yaml:"muted"`
+	Section   string `yaml:"section"`
+	BadgeText string `yaml:"badge_text"`
+	// Default colors the cards of classes missing from Classes
+	// and the colors a class leaves out.
+	Default ClassColors            `yaml:"default"`
+	Classes map[string]ClassColors `yaml:"classes"`
+}
+
+type ClassColors struct {
+	Background string `yaml:"background"`
+	Accent     string `yaml:"accent"`
+}
+
+// Layouts are keyed by the layout name.
+type Layouts map[string]*Layout
+
+// metaFields are the values of the meta-information block and the header and footer placeholders.
+var metaFields = []string{"unit_guid", "inv_id", "source_file", "source_files", "total_records", "generated_at"}
+
+var hexColor = regexp.MustCompile(`^[0-9A-Fa-f]{6}$`)
+
+// LoadLayouts reads the layouts of dir on top of the built-in one. An empty dir
+// yields the built-in layout only.
+func LoadLayouts(dir string) (Layouts, error) {
+	builtin, err := parseLayout(nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("built-in layout: %w", err)
+	}
+
+	layouts := Layouts{DefaultLayout: builtin}
+	if dir == "" {
+		return layouts, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read layouts directory: %w", err)
+	}
+
+	loaded := make(map[string]struct{}, len(entries))
+
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ext)
+		// default.yaml из каталога заменяет встроенный макет
+		if _, ok := loaded[name]; ok {
+			return nil, fmt.Errorf("layout %q is defined twice", name)
+		}
+		loaded[name] = struct{}{}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layout %q: %w", name, err)
+		}
+
+		layout, err := parseLayout(data, dir)
+		if err != nil {
+			return nil, fmt.Errorf("layout %q: %w", name, err)
+		}
+
+		layouts[name] = layout
+	}
+
+	return layouts, nil
+}
+
+// get returns the named layout; an empty name selects the default one.
+func (l Layouts) get(name string) (*Layout, error) {
+	if name == "" {
+		name = DefaultLayout
+	}
+
+	layout, ok := l[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown report layout %q", name)
+	}
+
+	return layout, nil
+}
+
+// parseLayout decodes data over the built-in layout, so data may set only the fields it changes.
+func parseLayout(data []byte, dir string) (*Layout, error) {
+	var layout Layout
+	if err := yaml.Unmarshal(defaultLayout, &layout); err != nil {
+		return nil, fmt.Errorf("failed to parse layout: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, &layout); err != nil {
+		return nil, fmt.Errorf("failed to parse layout: %w", err)
+	}
+
+	if layout.Logo != "" {
+		if err := layout.loadLogo(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := layout.validate(); err != nil {
+		return nil, err
+	}
+
+	return &layout, nil
+}
+
+func (l *Layout) loadLogo(dir string) error {
+	switch strings.ToLower(filepath.Ext(l.Logo)) {
+	case ".png":
+		l.logoExtension = extension.Png
+	case ".jpg", ".jpeg":
+		l.logoExtension = extension.Jpg
+	default:
+		return fmt.Errorf("logo %q must be a png or jpeg image", l.Logo)
+	}
+
+	if !filepath.IsLocal(filepath.FromSlash(l.Logo)) {
+		return fmt.Errorf("logo %q must be a path within the layouts directory", l.Logo)
+	}
+
+	logo, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(l.Logo)))
+	if err != nil {
+		return fmt.Errorf("failed to read logo: %w", err)
+	}
+	l.logo = logo
+
+	return nil
+}
+
+func (l *Layout) validate() error {
+	if l.Logo != "" && l.LogoHeight <= 0 {
+		return errors.New("logo height must be positive")
+	}
+
+	for _, meta := range l.Meta {
+		if !slices.Contains(metaFields, meta.Field) {
+			return fmt.Errorf("unknown meta field %q, expected one of %v", meta.Field, metaFields)
+		}
+	}
+
+	if err := l.Card.validate(); err != nil {
+		return fmt.Errorf("card: %w", err)
+	}
+
+	return l.Colors.validate()
+}
+
+func (c Card) validate() error {
+	for _, field := range c.fields() {
+		if !slices.Contains(deviceColumns, field) {
+			return fmt.Errorf("unknown field %q, expected one of %v", field, deviceColumns)
+		}
+	}
+
+	if c.LabelWidth < 1 || c.LabelWidth > 11 {
+		return fmt.Errorf("label width must be between 1 and 11, got %d", c.LabelWidth)
+	}
+
+	if c.NumberWidth < 0 || c.BadgeWidth < 0 || c.NumberWidth+c.BadgeWidth > 11 {
+		return fmt.Errorf("number and badge widths must leave room for the title, got %d and %d", c.NumberWidth, c.BadgeWidth)
+	}
+
+	return nil
+}
+
+// fields lists the fields the card shows, in the order they appear.
+func (c Card) fields() []string {
+	var fields []string
+
+	for _, field := range []string{c.Number, c.Title, c.Badge} {
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	for _, row := range c.Rows {
+		fields = append(fields, row.Fields...)
+	}
+
+	return fields
+}
+
+func (c Colors) validate() error {
+	colors := map[string]string{
+		"text":               c.Text,
+		"muted":              c.Muted,
+		"section":            c.Section,
+		"badge_text":         c.BadgeText,
+		"default.background": c.Default.Background,
+		"default.accent":     c.Default.Accent,
+	}
+
+	for class, colorsOfClass := range c.Classes {
+		// не заданный цвет класса берётся из default
+		if colorsOfClass.Background != "" {
+			colors[class+".background"] = colorsOfClass.Background
+		}
+		if colorsOfClass.Accent != "" {
+			colors[class+".accent"] = colorsOfClass.Accent
+		}
+	}
+
+	for name, color := range colors {
+		if !hexColor.MatchString(color) {
+			return fmt.Errorf("color %s must be a hex RGB value, got %q", name, color)
+		}
+	}
+
+	return nil
+}
+
+// class returns the card colors of a record class.
+func (c Colors) class(class string) ClassColors {
+	colors := c.Classes[class]
+
+	if colors.Background == "" {
+		colors.Background = c.Default.Background
+	}
+	if colors.Accent == "" {
+		colors.Accent = c.Default.Accent
+	}
+
+	return colors
+}
+
+// expand substitutes the meta fields in braces.
+func expand(text string, values map[string]string) string {
+	pairs := make([]string, 0, 2*len(values))
+	for field, value := range values {
+		pairs = append(pairs, "{"+field+"}", value)
+	}
+
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// parseHexColor splits a validated hex RGB value into its components.
+func parseHexColor(color string) (red, green, blue int) {
+	rgb, _ := strconv.ParseUint(color, 16, 32)
+
+	return int(rgb >> 16 & 0xFF), int(rgb >> 8 & 0xFF), int(rgb & 0xFF)
+}
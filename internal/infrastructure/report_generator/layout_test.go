@@ -0,0 +1,209 @@
+package report_generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/johnfercher/maroto/v2/pkg/consts/extension"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logoImage is enough of an image for the layouts: the logo is read, not decoded.
+var logoImage = []byte("\x89PNG\r\n\x1a\n")
+
+func TestLoadLayouts(t *testing.T) {
+	t.Parallel()
+
+	builtin, err := parseLayout(nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Device Report", builtin.Title)
+	assert.Empty(t, builtin.logo)
+
+	tests := []struct {
+		name  string
+		files map[string][]byte
+		// want lists the expected layouts by name.
+		want func() Layouts
+	}{
+		{
+			name:  "no layouts",
+			files: nil,
+			want: func() Layouts {
+				return Layouts{DefaultLayout: builtin}
+			},
+		},
+		{
+			// Поля, которых нет в файле, берутся из встроенного макета
+			name: "override",
+			files: map[string][]byte{
+				"dark.yaml": []byte("title: Dark\nsummary: false\ncolors:\n  text: \"000000\"\n"),
+			},
+			want: func() Layouts {
+				dark := *builtin
+				dark.Title = "Dark"
+				dark.Summary = false
+				dark.Colors.Text = "000000"
+
+				return Layouts{DefaultLayout: builtin, "dark": &dark}
+			},
+		},
+		{
+			name: "default replaced",
+			files: map[string][]byte{
+				"default.yml": []byte("title: Plant report\n"),
+			},
+			want: func() Layouts {
+				replaced := *builtin
+				replaced.Title = "Plant report"
+
+				return Layouts{DefaultLayout: &replaced}
+			},
+		},
+		{
+			name: "other files ignored",
+			files: map[string][]byte{
+				"notes.txt":       []byte("not a layout"),
+				"logos/logo.yaml": []byte("title: nested"),
+			},
+			want: func() Layouts {
+				return Layouts{DefaultLayout: builtin}
+			},
+		},
+		{
+			name: "logo",
+			files: map[string][]byte{
+				"branded.yaml":    []byte("logo: logos/plant.PNG\nlogo_height: 12\n"),
+				"logos/plant.PNG": logoImage,
+			},
+			want: func() Layouts {
+				branded := *builtin
+				branded.Logo = "logos/plant.PNG"
+				branded.LogoHeight = 12
+				branded.logo = logoImage
+				branded.logoExtension = extension.Png
+
+				return Layouts{DefaultLayout: builtin, "branded": &branded}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := writeLayouts(t, tt.files)
+
+			layouts, err := LoadLayouts(dir)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want(), layouts)
+		})
+	}
+}
+
+func TestLoadLayouts_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		files   map[string][]byte
+		wantErr string
+	}{
+		{
+			name:    "defined twice",
+			files:   map[string][]byte{"plant.yaml": nil, "plant.yml": nil},
+			wantErr: `layout "plant" is defined twice`,
+		},
+		{
+			name:    "invalid yaml",
+			files:   map[string][]byte{"plant.yaml": []byte("title: [unclosed\n")},
+			wantErr: `layout "plant": failed to parse layout`,
+		},
+		{
+			name:    "unknown meta field",
+			files:   map[string][]byte{"plant.yaml": []byte("meta:\n  - label: Owner\n    field: owner\n")},
+			wantErr: `layout "plant": unknown meta field "owner"`,
+		},
+		{
+			name:    "unknown card field",
+			files:   map[string][]byte{"plant.yaml": []byte("card:\n  badge: severity\n")},
+			wantErr: `layout "plant": card: unknown field "severity"`,
+		},
+		{
+			name:    "label width",
+			files:   map[string][]byte{"plant.yaml": []byte("card:\n  label_width: 12\n")},
+			wantErr: `layout "plant": card: label width must be between 1 and 11, got 12`,
+		},
+		{
+			name:    "no room for the title",
+			files:   map[string][]byte{"plant.yaml": []byte("card:\n  number_width: 5\n  badge_width: 7\n")},
+			wantErr: `layout "plant": card: number and badge widths must leave room for the title, got 5 and 7`,
+		},
+		{
+			name:    "invalid color",
+			files:   map[string][]byte{"plant.yaml": []byte("colors:\n  classes:\n    alarm:\n      accent: red\n")},
+			wantErr: `layout "plant": color alarm.accent must be a hex RGB value, got "red"`,
+		},
+		{
+			name:    "logo format",
+			files:   map[string][]byte{"plant.yaml": []byte("logo: logo.gif\n"), "logo.gif": logoImage},
+			wantErr: `layout "plant": logo "logo.gif" must be a png or jpeg image`,
+		},
+		{
+			// Логотип читается только из каталога макетов
+			name:    "logo outside the directory",
+			files:   map[string][]byte{"plant.yaml": []byte("logo: ../logo.png\n")},
+			wantErr: `layout "plant": logo "../logo.png" must be a path within the layouts directory`,
+		},
+		{
+			name:    "absolute logo path",
+			files:   map[string][]byte{"plant.yaml": []byte("logo: /etc/logo.png\n")},
+			wantErr: `layout "plant": logo "/etc/logo.png" must be a path within the layouts directory`,
+		},
+		{
+			name:    "missing logo",
+			files:   map[string][]byte{"plant.yaml": []byte("logo: logo.png\n")},
+			wantErr: `layout "plant": failed to read logo`,
+		},
+		{
+			name:    "logo height",
+			files:   map[string][]byte{"plant.yaml": []byte("logo: logo.png\nlogo_height: 0\n"), "logo.png": logoImage},
+			wantErr: `layout "plant": logo height must be positive`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := LoadLayouts(writeLayouts(t, tt.files))
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestLoadLayouts_MissingDirectory(t *testing.T) {
+	t.Parallel()
+
+	_, err := LoadLayouts(filepath.Join(t.TempDir(), "layouts"))
+	require.ErrorContains(t, err, "failed to read layouts directory")
+}
+
+// writeLayouts writes files to a new layouts directory; no files means no directory.
+func writeLayouts(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+
+	if files == nil {
+		return ""
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, content, 0o644))
+	}
+
+	return dir
+}
@@ -0,0 +1,105 @@
+package report_generator
+
+import (
+	"time"
+
+	"github.com/kurochkinivan/device_reporter/internal/config"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// locale translates the report strings and formats numbers and dates. Strings are
+// looked up by their English text, so layout texts missing from the catalog are kept as is.
+type locale struct {
+	tag      language.Tag
+	printer  *message.Printer
+	messages map[string]string
+	// classes translates the record classes shown on card badges.
+	classes  map[string]string
+	dateTime string
+}
+
+var locales = map[string]*locale{
+	config.ReportLocaleEN: {
+		tag:      language.English,
+		printer:  message.NewPrinter(language.English),
+		dateTime: "2006-01-02 15:04:05 MST",
+	},
+	config.ReportLocaleRU: {
+		tag:     language.Russian,
+		printer: message.NewPrinter(language.Russian),
+		messages: map[string]string{
+			"Device Report":             "Отчёт по устройству",
+			"Generated: {generated_at}": "Сформирован: {generated_at}",
+			"This report was automatically generated by device-reporter service.": "Отчёт сформирован автоматически сервисом device-reporter.",
+			"Unit GUID:":     "GUID устройства:",
+			"Inventory ID:":  "Инвентарный номер:",
+			"Source File:":   "Исходный файл:",
+			"Source Files:":  "Исходных файлов:",
+			"Total Records:": "Всего записей:",
+			"Generated At:":  "Сформирован:",
+			"Event Records":  "Записи событий",
+			"Source File: {file} ({records} records)": "Файл: {file} (записей: {records})",
+			"TEXT / CONTEXT":   "ТЕКСТ / КОНТЕКСТ",
+			"AREA / ADDR":      "ОБЛАСТЬ / АДРЕС",
+			"LEVEL / BLOCK":    "УРОВЕНЬ / БЛОК",
+			"TYPE / BIT / INV": "ТИП / БИТ / ИНВ",
+			"Summary":          "Сводка",
+			"Records by class": "Записи по классам",
+			"Records by area":  "Записи по областям",
+			"Records by level": "Записи по уровням",
+			"Alarm records":    "Аварийные записи",
+			"No alarm records": "Аварийных записей нет",
+			"Fleet Report":     "Сводный отчёт по устройствам",
+			"Units:":           "Устройств:",
+			"Alarms:":          "Аварий:",
+			"Warnings:":        "Предупреждений:",
+			"Unit GUID":        "GUID устройства",
+			"Records":          "Записей",
+			"Alarms":           "Аварии",
+			"Warnings":         "Предупреждения",
+			"Report":           "Отчёт",
+		},
+		classes: map[string]string{
+			"alarm":   "авария",
+			"warning": "предупреждение",
+			"working": "работа",
+			"waiting": "ожидание",
+		},
+		dateTime: "02.01.2006 15:04:05 MST",
+	},
+}
+
+// localeOf returns the named locale, English for an unknown one.
+func localeOf(name string) *locale {
+	if l, ok := locales[name]; ok {
+		return l
+	}
+
+	return locales[config.ReportLocaleEN]
+}
+
+func (l *locale) text(s string) string {
+	if translated, ok := l.messages[s]; ok {
+		return translated
+	}
+
+	return s
+}
+
+func (l *locale) class(class string) string {
+	if translated, ok := l.classes[class]; ok {
+		return translated
+	}
+
+	return class
+}
+
+// number groups the digits as is customary for the language, e.g. 12 345 in Russian.
+func (l *locale) number(n int) string {
+	return l.printer.Sprint(n)
+}
+
+func (l *locale) time(t time.Time) string {
+	return t.Format(l.dateTime)
+}
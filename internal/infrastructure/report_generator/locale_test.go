@@ -0,0 +1,135 @@
+package report_generator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kurochkinivan/device_reporter/internal/config"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocale(t *testing.T) {
+	t.Parallel()
+
+	generatedAt := time.Date(2026, time.October, 16, 9, 30, 5, 0, time.UTC)
+	moscow := time.FixedZone("MSK", 3*60*60)
+
+	tests := []struct {
+		name   string
+		locale string
+		zone   *time.Location
+		// want holds the text, class, number and time as the locale renders them.
+		wantText   string
+		wantClass  string
+		wantNumber string
+		wantTime   string
+	}{
+		{
+			name:       "en",
+			locale:     config.ReportLocaleEN,
+			wantText:   "Device Report",
+			wantClass:  "alarm",
+			wantNumber: "12,345",
+			wantTime:   "2026-10-16 09:30:05 UTC",
+		},
+		{
+			name:       "ru",
+			locale:     config.ReportLocaleRU,
+			zone:       moscow,
+			wantText:   "Отчёт по устройству",
+			wantClass:  "авария",
+			wantNumber: "12\u00a0345", // разряды разделяются неразрывным пробелом
+			wantTime:   "16.10.2026 12:30:05 MSK",
+		},
+		{
+			// Неизвестный язык отчёта — английский
+			name:       "unknown",
+			locale:     "de",
+			zone:       moscow,
+			wantText:   "Device Report",
+			wantClass:  "alarm",
+			wantNumber: "12,345",
+			wantTime:   "2026-10-16 12:30:05 MSK",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			style := config.ReportStyle{Locale: tt.locale, TimeZone: tt.zone}
+			l := localeOf(style.Locale)
+
+			assert.Equal(t, tt.wantText, l.text("Device Report"))
+			assert.Equal(t, tt.wantClass, l.class("alarm"))
+			assert.Equal(t, tt.wantNumber, l.number(12345))
+			assert.Equal(t, tt.wantTime, l.time(generatedAt.In(timeZone(style))))
+
+			// текст и класс, которых нет в каталоге, выводятся как есть
+			assert.Equal(t, "Custom title", l.text("Custom title"))
+			assert.Equal(t, "maintenance", l.class("maintenance"))
+		})
+	}
+}
+
+func TestLocale_RussianCatalog(t *testing.T) {
+	t.Parallel()
+
+	layout, err := parseLayout(nil, "")
+	require.NoError(t, err)
+
+	// every text of the built-in layout is translated
+	texts := []string{layout.Title, layout.Header, layout.Footer, layout.RecordsTitle, layout.SectionTitle}
+	for _, meta := range layout.Meta {
+		texts = append(texts, meta.Label)
+	}
+	for _, row := range layout.Card.Rows {
+		texts = append(texts, row.Label)
+	}
+
+	ru := localeOf(config.ReportLocaleRU)
+	for _, text := range texts {
+		assert.Contains(t, ru.messages, text)
+	}
+
+	for _, class := range classOrder {
+		assert.Contains(t, ru.classes, class)
+	}
+}
+
+func TestContent_Meta(t *testing.T) {
+	t.Parallel()
+
+	c := content{
+		UnitGUID:    "01749246-95f6-57db-b7c3-2ae0e8be671f",
+		InvID:       "INV-7",
+		SourceFile:  "data.tsv",
+		GeneratedAt: time.Date(2026, time.October, 16, 9, 30, 5, 0, time.UTC).In(time.FixedZone("MSK", 3*60*60)),
+		Total:       1500,
+		Sections:    make([]fileSection, 2),
+		locale:      localeOf(config.ReportLocaleRU),
+	}
+
+	assert.Equal(t, map[string]string{
+		"unit_guid":     "01749246-95f6-57db-b7c3-2ae0e8be671f",
+		"inv_id":        "INV-7",
+		"source_file":   "data.tsv",
+		"source_files":  "2",
+		"total_records": "1\u00a0500",
+		"generated_at":  "16.10.2026 12:30:05 MSK",
+	}, c.meta())
+
+	assert.Equal(t, "Сформирован: 16.10.2026 12:30:05 MSK", c.text("Generated: {generated_at}"))
+	assert.Equal(t, "Файл: data.tsv (записей: 1\u00a0500)", c.sectionTitle("Source File: {file} ({records} records)", fileSection{FileName: "data.tsv", Devices: make([]*domain.Device, 1500)}))
+}
+
+func TestTimeZone(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, time.UTC, timeZone(config.ReportStyle{}))
+
+	moscow := time.FixedZone("MSK", 3*60*60)
+	assert.Equal(t, moscow, timeZone(config.ReportStyle{TimeZone: moscow}))
+}
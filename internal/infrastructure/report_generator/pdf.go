@@ -0,0 +1,524 @@
+package report_generator
+
+import (
+	"bytes"
+	"fmt"
+	goimage "image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strconv"
+	"strings"
+
+	_ "embed"
+
+	"github.com/johnfercher/maroto/v2"
+	"github.com/johnfercher/maroto/v2/pkg/components/col"
+	"github.com/johnfercher/maroto/v2/pkg/components/image"
+	"github.com/johnfercher/maroto/v2/pkg/components/page"
+	"github.com/johnfercher/maroto/v2/pkg/components/row"
+	"github.com/johnfercher/maroto/v2/pkg/components/text"
+	"github.com/johnfercher/maroto/v2/pkg/config"
+	"github.com/johnfercher/maroto/v2/pkg/consts/align"
+	"github.com/johnfercher/maroto/v2/pkg/consts/extension"
+	"github.com/johnfercher/maroto/v2/pkg/consts/fontstyle"
+	"github.com/johnfercher/maroto/v2/pkg/consts/orientation"
+	"github.com/johnfercher/maroto/v2/pkg/core"
+	"github.com/johnfercher/maroto/v2/pkg/core/entity"
+	"github.com/johnfercher/maroto/v2/pkg/props"
+	appconfig "github.com/kurochkinivan/device_reporter/internal/config"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+)
+
+//go:embed fonts/DejaVuSans.ttf
+var dejavuNormal []byte
+
+//go:embed fonts/DejaVu_Serif_Condensed_Bold.ttf
+var dejavuBold []byte
+
+// PDF renders a report as a document with a card per record, laid out by the layout of the source.
+type PDF struct {
+	layouts Layouts
+}
+
+func NewPDF(layouts Layouts) *PDF {
+	return &PDF{
+		layouts: layouts,
+	}
+}
+
+func (r *PDF) Format() string {
+	return appconfig.ReportFormatPDF
+}
+
+func (r *PDF) GenerateReport(outputPath string, style appconfig.ReportStyle, unitGUID, sourceFile string, devices []*domain.Device) error {
+	layout, err := r.layouts.get(style.Layout)
+	if err != nil {
+		return err
+	}
+
+	m := r.newDocument()
+
+	c := newContent(style, unitGUID, sourceFile, devices)
+
+	m.AddRows(r.buildLogo(layout)...)
+
+	if layout.Title != "" {
+		m.AddRows(
+			row.New(12).Add(
+				col.New(12).Add(
+					text.New(c.text(layout.Title), props.Text{
+						Family: "DejaVuSans",
+						Size:   16,
+						Style:  fontstyle.Bold,
+						Align:  align.Center,
+						Color:  pdfColor(layout.Colors.Text),
+					}),
+				),
+			),
+		)
+	}
+
+	if layout.Header != "" {
+		m.AddRows(
+			row.New(6).Add(
+				col.New(12).Add(
+					text.New(
+						c.text(layout.Header),
+						props.Text{Family: "DejaVuSans", Size: 9, Align: align.Center, Color: pdfColor(layout.Colors.Muted)},
+					),
+				),
+			),
+		)
+	}
+
+	m.AddRow(4)
+
+	// meta-info
+	m.AddRows(r.buildMetaSection(layout, c)...)
+
+	// spacer
+	m.AddRow(6)
+
+	// table header
+	var heading []core.Row
+	if layout.RecordsTitle != "" {
+		heading = append(heading,
+			row.New(7).Add(
+				col.New(12).Add(
+					text.New(c.text(layout.RecordsTitle), props.Text{
+						Family: "DejaVuSans",
+						Size:   12,
+						Style:  fontstyle.Bold,
+						Color:  pdfColor(layout.Colors.Text),
+					}),
+				),
+			),
+		)
+	}
+
+	if layout.Summary {
+		m.AddRows(r.buildSummary(layout, c)...)
+
+		// карточки начинаются с новой страницы, сводка остаётся первой
+		m.AddPages(page.New().Add(heading...))
+	} else {
+		m.AddRows(heading...)
+	}
+
+	// data cards, grouped by source file in cumulative reports
+	for _, section := range c.Sections {
+		if section.FileName != "" {
+			m.AddRows(r.buildSectionHeader(layout, c, section)...)
+		}
+
+		for _, device := range section.Devices {
+			m.AddRows(r.buildDeviceCard(layout, c, device)...)
+		}
+	}
+
+	// footer
+	if layout.Footer != "" {
+		m.AddRow(6)
+		m.AddRows(
+			row.New(5).Add(
+				col.New(12).Add(
+					text.New(
+						c.text(layout.Footer),
+						props.Text{Family: "DejaVuSans", Size: 8, Align: align.Center, Color: pdfColor(layout.Colors.Muted)},
+					),
+				),
+			),
+		)
+	}
+
+	doc, err := m.Generate()
+	if err != nil {
+		return fmt.Errorf("failed to generate pdf: %w", err)
+	}
+
+	return doc.Save(outputPath)
+}
+
+func (r *PDF) GenerateFleetReport(outputPath string, style appconfig.ReportStyle, fleet *domain.FleetReport) error {
+	layout, err := r.layouts.get(style.Layout)
+	if err != nil {
+		return err
+	}
+
+	m := r.newDocument()
+
+	c := newFleetContent(style, fleet)
+	dark := pdfColor(layout.Colors.Text)
+
+	m.AddRows(r.buildLogo(layout)...)
+	m.AddRows(
+		row.New(12).Add(
+			col.New(12).Add(
+				text.New(c.locale.text("Fleet Report"), props.Text{
+					Family: "DejaVuSans", Size: 16, Style: fontstyle.Bold, Align: align.Center, Color: dark,
+				}),
+			),
+		),
+		row.New(6).Add(
+			col.New(12).Add(
+				text.New(
+					c.text("Generated: {generated_at}"),
+					props.Text{Family: "DejaVuSans", Size: 9, Align: align.Center, Color: pdfColor(layout.Colors.Muted)},
+				),
+			),
+		),
+		row.New(4),
+	)
+
+	labelProps := props.Text{Family: "DejaVuSans", Size: 9, Style: fontstyle.Bold, Color: dark}
+	valueProps := props.Text{Family: "DejaVuSans", Size: 9, Color: dark}
+
+	scopeLabel, scopeValue := c.scope()
+	for _, line := range [][2]string{
+		{scopeLabel, scopeValue},
+		{c.locale.text("Units:"), c.locale.number(c.Total.Units)},
+		{c.locale.text("Total Records:"), c.locale.number(c.Total.Records)},
+		{c.locale.text("Alarms:"), c.locale.number(c.Total.Alarms)},
+		{c.locale.text("Warnings:"), c.locale.number(c.Total.Warnings)},
+	} {
+		m.AddRows(row.New(7).Add(
+			col.New(3).Add(text.New(line[0], labelProps)),
+			col.New(9).Add(text.New(line[1], valueProps)),
+		))
+	}
+
+	m.AddRow(6)
+
+	section := &props.Cell{BackgroundColor: pdfColor(layout.Colors.Section)}
+	headerProps := props.Text{Family: "DejaVuSans", Size: 9, Style: fontstyle.Bold, Color: dark}
+	m.AddRows(row.New(8).Add(
+		col.New(5).WithStyle(section).Add(text.New(c.locale.text("Unit GUID"), headerProps)),
+		col.New(2).WithStyle(section).Add(text.New(c.locale.text("Records"), headerProps)),
+		col.New(2).WithStyle(section).Add(text.New(c.locale.text("Alarms"), headerProps)),
+		col.New(2).WithStyle(section).Add(text.New(c.locale.text("Warnings"), headerProps)),
+		col.New(1).WithStyle(section).Add(text.New(c.locale.text("Report"), headerProps)),
+	))
+
+	alarm := &props.Cell{BackgroundColor: pdfColor(layout.Colors.class("alarm").Background)}
+	warning := &props.Cell{BackgroundColor: pdfColor(layout.Colors.class("warning").Background)}
+
+	for _, group := range c.Groups {
+		invID := group.InvID
+		if invID == "" {
+			invID = "—"
+		}
+
+		m.AddRows(row.New(7).Add(
+			col.New(5).Add(text.New(fmt.Sprintf("%s %s", c.locale.text("Inventory ID:"), invID), headerProps)),
+			col.New(2).Add(text.New(c.locale.number(group.Total.Records), headerProps)),
+			col.New(2).Add(text.New(c.locale.number(group.Total.Alarms), headerProps)),
+			col.New(2).Add(text.New(c.locale.number(group.Total.Warnings), headerProps)),
+			col.New(1),
+		))
+
+		for _, unit := range group.Units {
+			guidProps := valueProps
+			if link := unitLink(outputPath, appconfig.ReportFormatPDF, unit); link != "" {
+				guidProps.Hyperlink = &link
+			}
+
+			alarms := col.New(2).Add(text.New(c.locale.number(unit.Alarms), valueProps))
+			if unit.Alarms > 0 {
+				alarms.WithStyle(alarm)
+			}
+
+			warnings := col.New(2).Add(text.New(c.locale.number(unit.Warnings), valueProps))
+			if unit.Warnings > 0 {
+				warnings.WithStyle(warning)
+			}
+
+			m.AddRows(row.New(6).Add(
+				col.New(5).Add(text.New(unit.UnitGUID, guidProps)),
+				col.New(2).Add(text.New(c.locale.number(unit.Records), valueProps)),
+				alarms,
+				warnings,
+				col.New(1).Add(text.New(c.reportVersion(unit), valueProps)),
+			))
+		}
+	}
+
+	if layout.Footer != "" {
+		m.AddRow(6)
+		m.AddRows(
+			row.New(5).Add(
+				col.New(12).Add(
+					text.New(
+						c.text(layout.Footer),
+						props.Text{Family: "DejaVuSans", Size: 8, Align: align.Center, Color: pdfColor(layout.Colors.Muted)},
+					),
+				),
+			),
+		)
+	}
+
+	doc, err := m.Generate()
+	if err != nil {
+		return fmt.Errorf("failed to generate pdf: %w", err)
+	}
+
+	return doc.Save(outputPath)
+}
+
+func (r *PDF) newDocument() core.Maroto {
+	cfg := config.NewBuilder().
+		WithOrientation(orientation.Horizontal).
+		WithPageNumber().
+		WithLeftMargin(10).
+		WithRightMargin(10).
+		WithTopMargin(10).
+		WithCompression(true).
+		WithCustomFonts([]*entity.CustomFont{
+			{
+				Family: "DejaVuSans",
+				Style:  fontstyle.Normal,
+				Bytes:  dejavuNormal,
+			},
+			{
+				Family: "DejaVuSans",
+				Style:  fontstyle.Bold,
+				Bytes:  dejavuBold,
+			},
+		}).
+		Build()
+
+	return maroto.New(cfg)
+}
+
+func (r *PDF) buildLogo(layout *Layout) []core.Row {
+	if layout.logo == nil {
+		return nil
+	}
+
+	return []core.Row{
+		image.NewFromBytesRow(layout.LogoHeight, layout.logo, layout.logoExtension, props.Rect{Center: true, Percent: 100}),
+		row.New(2),
+	}
+}
+
+func (r *PDF) buildMetaSection(layout *Layout, c content) []core.Row {
+	meta := c.meta()
+
+	labelProps := props.Text{Family: "DejaVuSans", Size: 9, Style: fontstyle.Bold, Color: pdfColor(layout.Colors.Text)}
+	valueProps := props.Text{Family: "DejaVuSans", Size: 9, Color: pdfColor(layout.Colors.Text)}
+
+	rows := make([]core.Row, 0, len(layout.Meta))
+	for _, field := range layout.Meta {
+		// число файлов имеет смысл только в накопительном отчёте
+		if field.Field == "source_files" && len(c.Sections) < 2 {
+			continue
+		}
+
+		rows = append(rows, row.New(7).Add(
+			col.New(3).Add(text.New(c.locale.text(field.Label), labelProps)),
+			col.New(9).Add(text.New(meta[field.Field], valueProps)),
+		))
+	}
+
+	return rows
+}
+
+func (r *PDF) buildSummary(layout *Layout, c content) []core.Row {
+	dark := pdfColor(layout.Colors.Text)
+	titleProps := props.Text{Family: "DejaVuSans", Size: 12, Style: fontstyle.Bold, Color: dark}
+	headerProps := props.Text{Family: "DejaVuSans", Size: 9, Style: fontstyle.Bold, Color: pdfColor(layout.Colors.Muted)}
+	valueProps := props.Text{Family: "DejaVuSans", Size: 9, Color: dark}
+	countProps := props.Text{Family: "DejaVuSans", Size: 9, Align: align.Right, Color: dark}
+	section := &props.Cell{BackgroundColor: pdfColor(layout.Colors.Section)}
+
+	rows := []core.Row{
+		row.New(7).Add(col.New(12).Add(text.New(c.locale.text("Summary"), titleProps))),
+		row.New(6).Add(col.New(12).Add(text.New(c.locale.text("Records by class"), headerProps))),
+	}
+
+	// диаграмма: полоса каждого класса пропорциональна его доле в отчёте
+	for _, class := range c.Summary.Classes {
+		colors := layout.Colors.class(class.Value)
+		share := c.share(class.Count)
+
+		bar := col.New(8)
+		if share > 0 {
+			bar = image.NewFromBytesCol(8, barImage(colors.Accent, share), extension.Png, props.Rect{Percent: 80, Top: 1})
+		}
+
+		rows = append(rows, row.New(7).Add(
+			col.New(2).WithStyle(&props.Cell{BackgroundColor: pdfColor(colors.Background)}).Add(
+				text.New(strings.ToUpper(c.locale.class(class.Value)), props.Text{Family: "DejaVuSans", Size: 8, Style: fontstyle.Bold, Color: dark}),
+			),
+			col.New(1).Add(text.New(c.locale.number(class.Count), countProps)),
+			col.New(1).Add(text.New(fmt.Sprintf("%d%%", share), countProps)),
+			bar,
+		))
+	}
+
+	rows = append(rows,
+		row.New(4),
+		row.New(6).Add(
+			col.New(4).WithStyle(section).Add(text.New(c.locale.text("Records by area"), headerProps)),
+			col.New(2).WithStyle(section),
+			col.New(1),
+			col.New(3).WithStyle(section).Add(text.New(c.locale.text("Records by level"), headerProps)),
+			col.New(2).WithStyle(section),
+		),
+	)
+
+	// области и уровни выводятся рядом, по строке на значение
+	for i := range max(len(c.Summary.Areas), len(c.Summary.Levels)) {
+		cols := []core.Col{col.New(4), col.New(2), col.New(1), col.New(3), col.New(2)}
+
+		if i < len(c.Summary.Areas) {
+			area := c.Summary.Areas[i]
+			value := area.Value
+			if value == "" {
+				value = "—"
+			}
+			cols[0].Add(text.New(value, valueProps))
+			cols[1].Add(text.New(c.locale.number(area.Count), countProps))
+		}
+
+		if i < len(c.Summary.Levels) {
+			level := c.Summary.Levels[i]
+			cols[3].Add(text.New(level.Value, valueProps))
+			cols[4].Add(text.New(c.locale.number(level.Count), countProps))
+		}
+
+		rows = append(rows, row.New(6).Add(cols...))
+	}
+
+	rows = append(rows,
+		row.New(4),
+		row.New(7).Add(col.New(12).Add(text.New(
+			fmt.Sprintf("%s: %s", c.locale.text("Alarm records"), c.locale.number(len(c.Summary.Alarms))), titleProps,
+		))),
+	)
+
+	if len(c.Summary.Alarms) == 0 {
+		return append(rows, row.New(6).Add(col.New(12).Add(
+			text.New(c.locale.text("No alarm records"), props.Text{Family: "DejaVuSans", Size: 9, Color: pdfColor(layout.Colors.Muted)}),
+		)))
+	}
+
+	alarm := &props.Cell{BackgroundColor: pdfColor(layout.Colors.class("alarm").Background)}
+	for _, d := range c.Summary.Alarms {
+		rows = append(rows, row.New(7).Add(
+			col.New(1).WithStyle(alarm).Add(text.New(strconv.Itoa(d.N), props.Text{Family: "DejaVuSans", Size: 8, Style: fontstyle.Bold, Color: dark})),
+			col.New(3).WithStyle(alarm).Add(text.New(d.MsgID, props.Text{Family: "DejaVuSans", Size: 8, Style: fontstyle.Bold, Color: dark})),
+			col.New(5).WithStyle(alarm).Add(text.New(d.Text, props.Text{Family: "DejaVuSans", Size: 8, Color: dark})),
+			col.New(3).WithStyle(alarm).Add(text.New(fmt.Sprintf("%s / %s", d.Area, d.Addr), props.Text{Family: "DejaVuSans", Size: 8, Color: dark})),
+		))
+	}
+
+	return rows
+}
+
+func (r *PDF) buildSectionHeader(layout *Layout, c content, section fileSection) []core.Row {
+	title := c.sectionTitle(layout.SectionTitle, section)
+
+	return []core.Row{
+		row.New(4),
+		row.New(8).Add(
+			col.New(12).WithStyle(&props.Cell{BackgroundColor: pdfColor(layout.Colors.Section)}).Add(
+				text.New(title, props.Text{Family: "DejaVuSans", Size: 10, Style: fontstyle.Bold, Color: pdfColor(layout.Colors.Text)}),
+			),
+		),
+		row.New(3),
+	}
+}
+
+func (r *PDF) buildDeviceCard(layout *Layout, c content, d *domain.Device) []core.Row {
+	card := layout.Card
+	colors := layout.Colors.class(d.Class)
+	bg := pdfColor(colors.Background)
+	dark := pdfColor(layout.Colors.Text)
+
+	var cols []core.Col
+	if card.NumberWidth > 0 {
+		cols = append(cols, col.New(card.NumberWidth).WithStyle(&props.Cell{BackgroundColor: bg}).Add(
+			text.New(deviceField(d, card.Number), props.Text{Family: "DejaVuSans", Size: 11, Style: fontstyle.Bold, Color: dark}),
+		))
+	}
+
+	cols = append(cols, col.New(12-card.NumberWidth-card.BadgeWidth).WithStyle(&props.Cell{BackgroundColor: bg}).Add(
+		text.New(deviceField(d, card.Title), props.Text{Family: "DejaVuSans", Size: 10, Style: fontstyle.Bold, Color: dark}),
+	))
+
+	if card.BadgeWidth > 0 {
+		cols = append(cols, col.New(card.BadgeWidth).WithStyle(&props.Cell{BackgroundColor: pdfColor(colors.Accent)}).Add(
+			text.New(strings.ToUpper(c.badge(card, d)), props.Text{
+				Family: "DejaVuSans", Size: 8, Style: fontstyle.Bold, Align: align.Center,
+				Color: pdfColor(layout.Colors.BadgeText),
+			}),
+		))
+	}
+
+	rows := []core.Row{row.New(10).Add(cols...)}
+
+	for _, cardRow := range card.Rows {
+		values := make([]string, 0, len(cardRow.Fields))
+		for _, field := range cardRow.Fields {
+			values = append(values, deviceField(d, field))
+		}
+
+		rows = append(rows, r.buildFieldRow(layout, c.locale.text(cardRow.Label), strings.Join(values, " / "), bg))
+	}
+
+	return append(rows, row.New(4))
+}
+
+func (r *PDF) buildFieldRow(layout *Layout, label, value string, bg *props.Color) core.Row {
+	return row.New(7).Add(
+		col.New(layout.Card.LabelWidth).WithStyle(&props.Cell{BackgroundColor: bg}).Add(
+			text.New(label, props.Text{Family: "DejaVuSans", Size: 7, Style: fontstyle.Bold, Color: pdfColor(layout.Colors.Muted)}),
+		),
+		col.New(12-layout.Card.LabelWidth).WithStyle(&props.Cell{BackgroundColor: bg}).Add(
+			text.New(value, props.Text{Family: "DejaVuSans", Size: 8, Color: pdfColor(layout.Colors.Text)}),
+		),
+	)
+}
+
+// barImage draws a horizontal bar filling percent of its width, to be scaled to a cell.
+func barImage(hex string, percent int) []byte {
+	const width, height = 500, 20
+
+	red, green, blue := parseHexColor(hex)
+	fill := color.RGBA{R: uint8(red), G: uint8(green), B: uint8(blue), A: 255}
+
+	img := goimage.NewRGBA(goimage.Rect(0, 0, width, height))
+	draw.Draw(img, goimage.Rect(0, 0, width*percent/100, height), &goimage.Uniform{C: fill}, goimage.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img) // запись в буфер не отказывает
+
+	return buf.Bytes()
+}
+
+func pdfColor(hex string) *props.Color {
+	red, green, blue := parseHexColor(hex)
+
+	return &props.Color{Red: red, Green: green, Blue: blue}
+}
@@ -1,226 +1,235 @@
 package report_generator
 
 import (
-	"fmt"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
-	_ "embed"
-
-	"github.com/johnfercher/maroto/v2"
-	"github.com/johnfercher/maroto/v2/pkg/components/col"
-	"github.com/johnfercher/maroto/v2/pkg/components/row"
-	"github.com/johnfercher/maroto/v2/pkg/components/text"
-	"github.com/johnfercher/maroto/v2/pkg/config"
-	"github.com/johnfercher/maroto/v2/pkg/consts/align"
-	"github.com/johnfercher/maroto/v2/pkg/consts/fontstyle"
-	"github.com/johnfercher/maroto/v2/pkg/consts/orientation"
-	"github.com/johnfercher/maroto/v2/pkg/core"
-	"github.com/johnfercher/maroto/v2/pkg/core/entity"
-	"github.com/johnfercher/maroto/v2/pkg/props"
+	"github.com/kurochkinivan/device_reporter/internal/config"
 	"github.com/kurochkinivan/device_reporter/internal/domain"
 )
 
-//go:embed fonts/DejaVuSans.ttf
-var dejavuNormal []byte
-
-//go:embed fonts/DejaVu_Serif_Condensed_Bold.ttf
-var dejavuBold []byte
-
-type ReportGenerator struct{}
-
-func New() *ReportGenerator {
-	return &ReportGenerator{}
-}
-
-func (r *ReportGenerator) GenerateReport(outputPath, unitGUID, sourceFile string, devices []*domain.Device) error {
-	cfg := config.NewBuilder().
-		WithOrientation(orientation.Horizontal).
-		WithPageNumber().
-		WithLeftMargin(10).
-		WithRightMargin(10).
-		WithTopMargin(10).
-		WithCompression(true).
-		WithCustomFonts([]*entity.CustomFont{
-			{
-				Family: "DejaVuSans",
-				Style:  fontstyle.Normal,
-				Bytes:  dejavuNormal,
-			},
-			{
-				Family: "DejaVuSans",
-				Style:  fontstyle.Bold,
-				Bytes:  dejavuBold,
-			},
-		}).
-		Build()
-
-	m := maroto.New(cfg)
-
-	m.AddRows(
-		row.New(12).Add(
-			col.New(12).Add(
-				text.New("Device Report", props.Text{
-					Family: "DejaVuSans",
-					Size:   16,
-					Style:  fontstyle.Bold,
-					Align:  align.Center,
-					Color:  r.darkColor(),
-				}),
-			),
-		),
-		row.New(6).Add(
-			col.New(12).Add(
-				text.New(
-					fmt.Sprintf("Generated: %s", time.Now().Format("2006-01-02 15:04:05")),
-					props.Text{Family: "DejaVuSans", Size: 9, Align: align.Center, Color: r.grayColor()},
-				),
-			),
-		),
-		row.New(4),
-	)
-
-	// meta-info
-	m.AddRows(r.buildMetaSection(unitGUID, devices[0].InvID, sourceFile, len(devices))...)
-
-	// spacer
-	m.AddRow(6)
-
-	// table header
-	m.AddRows(
-		row.New(7).Add(
-			col.New(12).Add(
-				text.New("Event Records", props.Text{
-					Family: "DejaVuSans",
-					Size:   12,
-					Style:  fontstyle.Bold,
-					Color:  r.darkColor(),
-				}),
-			),
-		),
-	)
-
-	// data cards
-	for _, device := range devices {
-		m.AddRows(r.buildDeviceCard(device)...)
+// content is what every format renders: the meta-information of a unit
+// and its records grouped by source file.
+type content struct {
+	UnitGUID    string
+	InvID       string
+	SourceFile  string
+	GeneratedAt time.Time
+	Total       int
+	Sections    []fileSection
+	Summary     summary
+
+	locale *locale
+}
+
+func newContent(style config.ReportStyle, unitGUID, sourceFile string, devices []*domain.Device) content {
+	c := content{
+		UnitGUID:    unitGUID,
+		SourceFile:  sourceFile,
+		GeneratedAt: time.Now().In(timeZone(style)),
+		Total:       len(devices),
+		Sections:    splitByFile(devices),
+		Summary:     summarize(devices),
+		locale:      localeOf(style.Locale),
 	}
 
-	// footer
-	m.AddRow(6)
-	m.AddRows(
-		row.New(5).Add(
-			col.New(12).Add(
-				text.New(
-					"This report was automatically generated by device-reporter service.",
-					props.Text{Family: "DejaVuSans", Size: 8, Align: align.Center, Color: r.grayColor()},
-				),
-			),
-		),
-	)
-
-	doc, err := m.Generate()
-	if err != nil {
-		return fmt.Errorf("failed to generate pdf: %w", err)
+	if len(devices) > 0 {
+		c.InvID = devices[0].InvID
 	}
 
-	return doc.Save(outputPath)
+	return c
+}
+
+// meta returns the values of metaFields formatted for the locale of the report.
+func (c content) meta() map[string]string {
+	return map[string]string{
+		"unit_guid":     c.UnitGUID,
+		"inv_id":        c.InvID,
+		"source_file":   c.SourceFile,
+		"source_files":  c.locale.number(len(c.Sections)),
+		"total_records": c.locale.number(c.Total),
+		"generated_at":  c.locale.time(c.GeneratedAt),
+	}
 }
 
-func (r *ReportGenerator) buildMetaSection(unitGUID, invID, sourceFile string, total int) []core.Row {
-	labelProps := props.Text{Family: "DejaVuSans", Size: 9, Style: fontstyle.Bold, Color: r.darkColor()}
-	valueProps := props.Text{Family: "DejaVuSans", Size: 9, Color: r.darkColor()}
+// text translates a layout text and substitutes the meta fields in braces.
+func (c content) text(s string) string {
+	return expand(c.locale.text(s), c.meta())
+}
 
-	makeRow := func(label, value string) core.Row {
-		return row.New(7).Add(
-			col.New(3).Add(text.New(label, labelProps)),
-			col.New(9).Add(text.New(value, valueProps)),
-		)
+// sectionTitle translates the title of a source file section and substitutes {file} and {records}.
+func (c content) sectionTitle(title string, section fileSection) string {
+	return strings.NewReplacer(
+		"{file}", section.FileName,
+		"{records}", c.locale.number(len(section.Devices)),
+	).Replace(c.locale.text(title))
+}
+
+// badge is the card badge of a record; classes are translated.
+func (c content) badge(card Card, d *domain.Device) string {
+	value := deviceField(d, card.Badge)
+	if card.Badge == "class" {
+		return c.locale.class(value)
 	}
 
-	return []core.Row{
-		makeRow("Unit GUID:", unitGUID),
-		makeRow("Inventory ID", invID),
-		makeRow("Source File:", sourceFile),
-		makeRow("Total Records:", strconv.Itoa(total)),
-		makeRow("Processed At:", time.Now().UTC().Format("2006-01-02 15:04:05 UTC")),
+	return value
+}
+
+// timeZone is the zone the report times are shown in.
+func timeZone(style config.ReportStyle) *time.Location {
+	if style.TimeZone == nil {
+		return time.UTC
 	}
+
+	return style.TimeZone
 }
 
-func (r *ReportGenerator) buildDeviceCard(d *domain.Device) []core.Row {
-	bg := r.classColor(d.Class)
-	accent := r.classAccentColor(d.Class)
-
-	rows := []core.Row{
-		row.New(10).Add(
-			col.New(1).WithStyle(&props.Cell{BackgroundColor: bg}).Add(
-				text.New(strconv.Itoa(d.N), props.Text{Family: "DejaVuSans", Size: 11, Style: fontstyle.Bold, Color: r.darkColor()}),
-			),
-			col.New(9).WithStyle(&props.Cell{BackgroundColor: bg}).Add(
-				text.New(d.MsgID, props.Text{Family: "DejaVuSans", Size: 10, Style: fontstyle.Bold, Color: r.darkColor()}),
-			),
-			col.New(2).WithStyle(&props.Cell{BackgroundColor: accent}).Add(
-				text.New(strings.ToUpper(d.Class), props.Text{
-					Family: "DejaVuSans", Size: 8, Style: fontstyle.Bold, Align: align.Center,
-					Color: &props.Color{Red: 255, Green: 255, Blue: 255},
-				}),
-			),
-		),
-
-		r.buildFieldRow("TEXT / CONTEXT", fmt.Sprintf("%s / %s", d.Text, d.Context), bg),
-		r.buildFieldRow("AREA / ADDR", fmt.Sprintf("%s / %s", d.Area, d.Addr), bg),
-		r.buildFieldRow("LEVEL / BLOCK", fmt.Sprintf("%d / %s", d.Level, d.Block), bg),
-		r.buildFieldRow("TYPE / BIT / INV", fmt.Sprintf("%s / %s / %s", d.Type, d.Bit, d.InvertBit), bg),
-
-		row.New(4),
+// fileSection holds the records of one source file.
+type fileSection struct {
+	FileName string
+	Devices  []*domain.Device
+}
+
+// splitByFile groups consecutive records by their source file. Records of a per-file
+// report carry no file name and make up a single section without a header.
+func splitByFile(devices []*domain.Device) []fileSection {
+	var sections []fileSection
+
+	for _, device := range devices {
+		if n := len(sections); n > 0 && sections[n-1].FileName == device.FileName {
+			sections[n-1].Devices = append(sections[n-1].Devices, device)
+			continue
+		}
+
+		sections = append(sections, fileSection{FileName: device.FileName, Devices: []*domain.Device{device}})
 	}
 
-	return rows
+	return sections
 }
 
-func (r *ReportGenerator) buildFieldRow(label, value string, bg *props.Color) core.Row {
-	return row.New(7).Add(
-		col.New(3).WithStyle(&props.Cell{BackgroundColor: bg}).Add(
-			text.New(label, props.Text{Family: "DejaVuSans", Size: 7, Style: fontstyle.Bold, Color: r.grayColor()}),
-		),
-		col.New(9).WithStyle(&props.Cell{BackgroundColor: bg}).Add(
-			text.New(value, props.Text{Family: "DejaVuSans", Size: 8, Color: r.darkColor()}),
-		),
-	)
+// classOrder lists the known record classes from the most urgent one.
+var classOrder = []string{"alarm", "warning", "working", "waiting"}
+
+// tally is the number of records with a value of a column.
+type tally struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
 }
 
-func (r *ReportGenerator) classAccentColor(class string) *props.Color {
-	switch class {
-	case "alarm":
-		return &props.Color{Red: 192, Green: 57, Blue: 43}
-	case "warning":
-		return &props.Color{Red: 230, Green: 126, Blue: 34}
-	case "working":
-		return &props.Color{Red: 39, Green: 174, Blue: 96}
-	default:
-		return &props.Color{Red: 41, Green: 128, Blue: 185}
+// summary is the overview printed before the records.
+type summary struct {
+	// Classes always holds the known classes, even without records.
+	Classes []tally
+	// Areas are ordered from the most frequent one, Levels by level.
+	Areas  []tally
+	Levels []tally
+	Alarms []*domain.Device
+}
+
+func summarize(devices []*domain.Device) summary {
+	classes := make(map[string]int, len(classOrder))
+	areas := make(map[string]int)
+	levels := make(map[int]int)
+
+	var s summary
+	for _, device := range devices {
+		classes[device.Class]++
+		areas[device.Area]++
+		levels[device.Level]++
+
+		if device.Class == "alarm" {
+			s.Alarms = append(s.Alarms, device)
+		}
+	}
+
+	for _, class := range classOrder {
+		s.Classes = append(s.Classes, tally{Value: class, Count: classes[class]})
+		delete(classes, class)
 	}
+	s.Classes = append(s.Classes, byCount(classes)...)
+
+	s.Areas = byCount(areas)
+
+	for _, level := range slices.Sorted(maps.Keys(levels)) {
+		s.Levels = append(s.Levels, tally{Value: strconv.Itoa(level), Count: levels[level]})
+	}
+
+	return s
+}
+
+// byCount orders the tallies from the largest count, equal counts by value.
+func byCount(counts map[string]int) []tally {
+	tallies := make([]tally, 0, len(counts))
+	for value, count := range counts {
+		tallies = append(tallies, tally{Value: value, Count: count})
+	}
+
+	slices.SortFunc(tallies, func(a, b tally) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Value, b.Value)
+	})
+
+	return tallies
+}
+
+// share is the percentage of the records of the report a count makes up.
+func (c content) share(count int) int {
+	if c.Total == 0 {
+		return 0
+	}
+
+	return count * 100 / c.Total
+}
+
+// deviceColumns are the record columns of the tabular formats, in the order of deviceValues.
+var deviceColumns = []string{
+	"n", "mqtt", "inv_id", "unit_guid", "msg_id", "text", "context", "class",
+	"level", "area", "addr", "block", "type", "bit", "invert_bit",
 }
 
-func (r *ReportGenerator) classColor(class string) *props.Color {
+func deviceValues(d *domain.Device) []string {
+	return []string{
+		strconv.Itoa(d.N), d.MQTT, d.InvID, d.UnitGUID, d.MsgID, d.Text, d.Context, d.Class,
+		strconv.Itoa(d.Level), d.Area, d.Addr, d.Block, d.Type, d.Bit, d.InvertBit,
+	}
+}
+
+// deviceField returns the value of a record column.
+func deviceField(d *domain.Device, column string) string {
+	i := slices.Index(deviceColumns, column)
+	if i < 0 {
+		return ""
+	}
+
+	return deviceValues(d)[i]
+}
+
+// recordFile is the source file of a record; records of a per-file report come from sourceFile.
+func recordFile(d *domain.Device, sourceFile string) string {
+	if d.FileName != "" {
+		return d.FileName
+	}
+
+	return sourceFile
+}
+
+// classFill is the background of a record by its class, the same as in the built-in layout.
+func classFill(class string) string {
 	switch class {
 	case "alarm":
-		return &props.Color{Red: 248, Green: 215, Blue: 218}
+		return "F8D7DA"
 	case "warning":
-		return &props.Color{Red: 255, Green: 238, Blue: 186}
+		return "FFEEBA"
 	case "working":
-		return &props.Color{Red: 212, Green: 237, Blue: 218}
+		return "D4EDDA"
 	case "waiting":
-		return &props.Color{Red: 222, Green: 235, Blue: 247}
+		return "DEEBF7"
 	default:
-		return &props.Color{Red: 255, Green: 255, Blue: 255}
+		return "FFFFFF"
 	}
 }
-
-func (r *ReportGenerator) darkColor() *props.Color {
-	return &props.Color{Red: 26, Green: 26, Blue: 46}
-}
-
-func (r *ReportGenerator) grayColor() *props.Color {
-	return &props.Color{Red: 119, Green: 119, Blue: 119}
-}
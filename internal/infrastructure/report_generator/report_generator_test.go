@@ -0,0 +1,86 @@
+package report_generator
+
+import (
+	"testing"
+
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	first := &domain.Device{N: 1, Class: "alarm", Area: "LOCAL", Level: 100}
+	second := &domain.Device{N: 2, Class: "alarm", Area: "REMOTE", Level: 50}
+
+	tests := []struct {
+		name    string
+		devices []*domain.Device
+		want    summary
+	}{
+		{
+			// Известные классы выводятся всегда, даже без записей
+			name:    "no records",
+			devices: nil,
+			want: summary{
+				Classes: []tally{{"alarm", 0}, {"warning", 0}, {"working", 0}, {"waiting", 0}},
+				Areas:   []tally{},
+			},
+		},
+		{
+			name: "no alarms",
+			devices: []*domain.Device{
+				{N: 1, Class: "waiting", Area: "LOCAL", Level: 100},
+				{N: 2, Class: "working", Area: "LOCAL", Level: 100},
+				{N: 3, Class: "waiting", Area: "REMOTE", Level: 10},
+			},
+			want: summary{
+				Classes: []tally{{"alarm", 0}, {"warning", 0}, {"working", 1}, {"waiting", 2}},
+				Areas:   []tally{{"LOCAL", 2}, {"REMOTE", 1}},
+				Levels:  []tally{{"10", 1}, {"100", 2}},
+			},
+		},
+		{
+			// Записи одинаковой частоты упорядочены по значению, неизвестные классы — после известных
+			name: "ties",
+			devices: []*domain.Device{
+				{N: 1, Class: "maintenance", Area: "ZONE-B", Level: 1},
+				{N: 2, Class: "calibration", Area: "ZONE-A", Level: 1},
+				{N: 3, Class: "test", Area: "ZONE-C", Level: 2},
+				{N: 4, Class: "test", Area: "ZONE-C", Level: 2},
+				{N: 5, Class: "", Area: "", Level: 3},
+			},
+			want: summary{
+				Classes: []tally{
+					{"alarm", 0}, {"warning", 0}, {"working", 0}, {"waiting", 0},
+					{"test", 2}, {"", 1}, {"calibration", 1}, {"maintenance", 1},
+				},
+				Areas:  []tally{{"ZONE-C", 2}, {"", 1}, {"ZONE-A", 1}, {"ZONE-B", 1}},
+				Levels: []tally{{"1", 2}, {"2", 2}, {"3", 1}},
+			},
+		},
+		{
+			// Аварийные записи идут в порядке записей отчёта
+			name: "several alarms",
+			devices: []*domain.Device{
+				first,
+				{N: 3, Class: "warning", Area: "REMOTE", Level: 50},
+				second,
+			},
+			want: summary{
+				Classes: []tally{{"alarm", 2}, {"warning", 1}, {"working", 0}, {"waiting", 0}},
+				Areas:   []tally{{"REMOTE", 2}, {"LOCAL", 1}},
+				Levels:  []tally{{"50", 2}, {"100", 1}},
+				Alarms:  []*domain.Device{first, second},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, summarize(tt.devices))
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package report_generator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kurochkinivan/device_reporter/internal/config"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+	"github.com/xuri/excelize/v2"
+)
+
+const xlsxSheet = "Report"
+
+// XLSX renders a report as a workbook: the meta-information on top
+// and a row per record, filled by its class.
+type XLSX struct{}
+
+func NewXLSX() *XLSX {
+	return &XLSX{}
+}
+
+func (x *XLSX) Format() string {
+	return config.ReportFormatXLSX
+}
+
+func (x *XLSX) GenerateReport(outputPath string, style config.ReportStyle, unitGUID, sourceFile string, devices []*domain.Device) (err error) {
+	c := newContent(style, unitGUID, sourceFile, devices)
+	meta := c.meta()
+
+	f := excelize.NewFile()
+	defer func() { err = errors.Join(err, f.Close()) }()
+
+	if err := f.SetSheetName(f.GetSheetName(0), xlsxSheet); err != nil {
+		return fmt.Errorf("failed to name sheet: %w", err)
+	}
+
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return fmt.Errorf("failed to create style: %w", err)
+	}
+
+	rows := [][]any{
+		{c.locale.text("Unit GUID:"), c.UnitGUID},
+		{c.locale.text("Inventory ID:"), c.InvID},
+		{c.locale.text("Source File:"), c.SourceFile},
+	}
+	if len(c.Sections) > 1 {
+		rows = append(rows, []any{c.locale.text("Source Files:"), len(c.Sections)})
+	}
+	rows = append(rows,
+		[]any{c.locale.text("Total Records:"), c.Total},
+		[]any{c.locale.text("Generated At:"), meta["generated_at"]},
+	)
+
+	line := 1
+	for _, values := range rows {
+		if err := x.setRow(f, line, values, bold); err != nil {
+			return err
+		}
+		line++
+	}
+	line++
+
+	// шапка таблицы: исходный файл и колонки записи
+	header := []any{"file_name"}
+	for _, column := range deviceColumns {
+		header = append(header, column)
+	}
+	if err := x.setRow(f, line, header, bold); err != nil {
+		return err
+	}
+	if err := f.SetPanes(xlsxSheet, &excelize.Panes{Freeze: true, YSplit: line, TopLeftCell: fmt.Sprintf("A%d", line+1), ActivePane: "bottomLeft"}); err != nil {
+		return fmt.Errorf("failed to freeze header: %w", err)
+	}
+	line++
+
+	fills := make(map[string]int)
+	for _, device := range devices {
+		fill, ok := fills[device.Class]
+		if !ok {
+			fill, err = f.NewStyle(&excelize.Style{
+				Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{classFill(device.Class)}},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create style: %w", err)
+			}
+			fills[device.Class] = fill
+		}
+
+		values := []any{recordFile(device, sourceFile)}
+		for _, value := range deviceValues(device) {
+			values = append(values, value)
+		}
+
+		if err := x.setRow(f, line, values, fill); err != nil {
+			return err
+		}
+		line++
+	}
+
+	return f.SaveAs(outputPath)
+}
+
+// setRow writes values from the first column of the line and applies the style to them.
+func (x *XLSX) setRow(f *excelize.File, line int, values []any, style int) error {
+	first, err := excelize.CoordinatesToCellName(1, line)
+	if err != nil {
+		return err
+	}
+
+	last, err := excelize.CoordinatesToCellName(len(values), line)
+	if err != nil {
+		return err
+	}
+
+	if err := f.SetSheetRow(xlsxSheet, first, &values); err != nil {
+		return fmt.Errorf("failed to write row %d: %w", line, err)
+	}
+
+	if err := f.SetCellStyle(xlsxSheet, first, last, style); err != nil {
+		return fmt.Errorf("failed to style row %d: %w", line, err)
+	}
+
+	return nil
+}
+
+func (x *XLSX) GenerateFleetReport(outputPath string, style config.ReportStyle, fleet *domain.FleetReport) (err error) {
+	c := newFleetContent(style, fleet)
+	scopeLabel, scopeValue := c.scope()
+
+	f := excelize.NewFile()
+	defer func() { err = errors.Join(err, f.Close()) }()
+
+	if err := f.SetSheetName(f.GetSheetName(0), xlsxSheet); err != nil {
+		return fmt.Errorf("failed to name sheet: %w", err)
+	}
+
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return fmt.Errorf("failed to create style: %w", err)
+	}
+
+	rows := [][]any{
+		{scopeLabel, scopeValue},
+		{c.locale.text("Units:"), c.Total.Units},
+		{c.locale.text("Total Records:"), c.Total.Records},
+		{c.locale.text("Alarms:"), c.Total.Alarms},
+		{c.locale.text("Warnings:"), c.Total.Warnings},
+		{c.locale.text("Generated At:"), c.locale.time(c.GeneratedAt)},
+	}
+
+	line := 1
+	for _, values := range rows {
+		if err := x.setRow(f, line, values, bold); err != nil {
+			return err
+		}
+		line++
+	}
+	line++
+
+	header := []any{"inv_id", "unit_guid", "records", "alarms", "warnings", "report_version", "report"}
+	if err := x.setRow(f, line, header, bold); err != nil {
+		return err
+	}
+	if err := f.SetPanes(xlsxSheet, &excelize.Panes{Freeze: true, YSplit: line, TopLeftCell: fmt.Sprintf("A%d", line+1), ActivePane: "bottomLeft"}); err != nil {
+		return fmt.Errorf("failed to freeze header: %w", err)
+	}
+	line++
+
+	fills := make(map[string]int)
+	for _, class := range []string{"alarm", "warning"} {
+		fills[class], err = f.NewStyle(&excelize.Style{
+			Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{classFill(class)}},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create style: %w", err)
+		}
+	}
+
+	for _, group := range c.Groups {
+		for _, unit := range group.Units {
+			link := unitLink(outputPath, config.ReportFormatXLSX, unit)
+
+			values := []any{unit.InvID, unit.UnitGUID, unit.Records, unit.Alarms, unit.Warnings, c.reportVersion(unit), link}
+			if err := f.SetSheetRow(xlsxSheet, fmt.Sprintf("A%d", line), &values); err != nil {
+				return fmt.Errorf("failed to write row %d: %w", line, err)
+			}
+
+			// ячейки аварий и предупреждений выделяются, только если они есть
+			counts := []struct {
+				column, class string
+				count         int
+			}{
+				{"D", "alarm", unit.Alarms},
+				{"E", "warning", unit.Warnings},
+			}
+			for _, count := range counts {
+				if count.count == 0 {
+					continue
+				}
+
+				cell := fmt.Sprintf("%s%d", count.column, line)
+				if err := f.SetCellStyle(xlsxSheet, cell, cell, fills[count.class]); err != nil {
+					return fmt.Errorf("failed to style row %d: %w", line, err)
+				}
+			}
+
+			if link != "" {
+				if err := f.SetCellHyperLink(xlsxSheet, fmt.Sprintf("G%d", line), link, "External"); err != nil {
+					return fmt.Errorf("failed to link row %d: %w", line, err)
+				}
+			}
+			line++
+		}
+	}
+
+	return f.SaveAs(outputPath)
+}
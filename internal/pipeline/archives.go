@@ -0,0 +1,207 @@
+package pipeline
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+)
+
+// memberSeparator joins a bundle name and a member path into a logical file name,
+// e.g. bundle.zip!/unit7.tsv.
+const memberSeparator = "!/"
+
+type bundleKind int
+
+const (
+	bundleNone bundleKind = iota
+	bundleZip
+	bundleTarGz
+)
+
+func bundleKindOf(name string) bundleKind {
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return bundleZip
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return bundleTarGz
+	default:
+		return bundleNone
+	}
+}
+
+// gzipped reports whether the file is a single gzip-compressed file such as data.tsv.gz.
+func gzipped(name string) bool {
+	return strings.HasSuffix(name, ".gz") && bundleKindOf(name) == bundleNone
+}
+
+// walkBundle calls fn for every regular file inside a zip or tar.gz bundle.
+// The reader is only valid until fn returns.
+func walkBundle(filename string, fn func(member string, info fs.FileInfo, r io.Reader) error) error {
+	switch bundleKindOf(filename) {
+	case bundleZip:
+		return walkZip(filename, fn)
+	case bundleTarGz:
+		return walkTarGz(filename, fn)
+	default:
+		return fmt.Errorf("%q is not a bundle", filename)
+	}
+}
+
+func walkZip(filename string, fn func(member string, info fs.FileInfo, r io.Reader) error) (err error) {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer func() { err = errors.Join(err, zr.Close()) }()
+
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open zip member %q: %w", f.Name, err)
+		}
+
+		err = fn(path.Clean(f.Name), f.FileInfo(), rc)
+		if err := errors.Join(err, rc.Close()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func walkTarGz(filename string, fn func(member string, info fs.FileInfo, r io.Reader) error) (err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() { err = errors.Join(err, f.Close()) }()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to open gzip: %w", err)
+	}
+	defer func() { err = errors.Join(err, gz.Close()) }()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar: %w", err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if err := fn(path.Clean(hdr.Name), hdr.FileInfo(), tr); err != nil {
+			return err
+		}
+	}
+}
+
+// openFile opens the content of a logical file for reading, decompressing it on the fly.
+func openFile(file *domain.File) (io.ReadCloser, error) {
+	if file.Member != "" {
+		return openMember(file.Path, file.Member)
+	}
+
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !gzipped(file.Path) {
+		return f, nil
+	}
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to open gzip: %w", err), f.Close())
+	}
+
+	return &readCloser{Reader: gz, closers: []io.Closer{gz, f}}, nil
+}
+
+func openMember(filename, member string) (io.ReadCloser, error) {
+	switch bundleKindOf(filename) {
+	case bundleZip:
+		zr, err := zip.OpenReader(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zip: %w", err)
+		}
+
+		for _, f := range zr.File {
+			if path.Clean(f.Name) != member {
+				continue
+			}
+
+			rc, err := f.Open()
+			if err != nil {
+				return nil, errors.Join(fmt.Errorf("failed to open zip member: %w", err), zr.Close())
+			}
+
+			return &readCloser{Reader: rc, closers: []io.Closer{rc, zr}}, nil
+		}
+
+		return nil, errors.Join(fmt.Errorf("member %q not found", member), zr.Close())
+
+	case bundleTarGz:
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to open gzip: %w", err), f.Close())
+		}
+
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = fmt.Errorf("member %q not found", member)
+				}
+				return nil, errors.Join(err, gz.Close(), f.Close())
+			}
+
+			if hdr.Typeflag == tar.TypeReg && path.Clean(hdr.Name) == member {
+				return &readCloser{Reader: tr, closers: []io.Closer{gz, f}}, nil
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("%q is not a bundle", filename)
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *readCloser) Close() error {
+	var errs []error
+	for _, c := range r.closers {
+		errs = append(errs, c.Close())
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,114 @@
+package pipeline
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jszwec/csvutil"
+	"github.com/kurochkinivan/device_reporter/internal/config"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+)
+
+// columnMapper renames header cells to the csv tags of domain.Device, so csvutil
+// can decode files with vendor-specific headers, and appends defaults of missing columns.
+type columnMapper struct {
+	reader   csvutil.Reader
+	columns  map[string]config.Column
+	mapped   bool
+	defaults []string // дописываются в конец каждой записи
+	last     []string // последняя прочитанная запись без значений по умолчанию
+}
+
+func newColumnMapper(reader csvutil.Reader, columns map[string]config.Column) *columnMapper {
+	return &columnMapper{
+		reader:  reader,
+		columns: columns,
+	}
+}
+
+func (m *columnMapper) Read() ([]string, error) {
+	record, err := m.reader.Read()
+	m.last = record
+	if err != nil {
+		return nil, err
+	}
+
+	if !m.mapped {
+		m.mapped = true
+		return m.mapHeader(record)
+	}
+
+	if len(m.defaults) == 0 {
+		return record, nil
+	}
+
+	return append(slices.Clip(record), m.defaults...), nil
+}
+
+// Last returns the last record as it was read, before defaults were appended.
+func (m *columnMapper) Last() []string {
+	return m.last
+}
+
+func (m *columnMapper) mapHeader(header []string) ([]string, error) {
+	known, err := csvutil.Header(domain.Device{}, "csv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device columns: %w", err)
+	}
+
+	lookup := make(map[string]string, len(known))
+	for _, name := range known {
+		lookup[normalizeColumn(name)] = name
+	}
+	for name, column := range m.columns {
+		for _, alias := range column.Aliases {
+			lookup[normalizeColumn(alias)] = name
+		}
+	}
+
+	mapped := make([]string, len(header))
+	found := make(map[string]string, len(header)) // каноническое имя -> исходный заголовок
+
+	for i, cell := range header {
+		name, ok := lookup[normalizeColumn(cell)]
+		if !ok {
+			mapped[i] = cell
+			continue
+		}
+
+		if prev, ok := found[name]; ok {
+			return nil, fmt.Errorf("columns %q and %q both map to %q", prev, cell, name)
+		}
+
+		found[name] = cell
+		mapped[i] = name
+	}
+
+	var missing []string
+	for _, name := range slices.Sorted(maps.Keys(m.columns)) {
+		if _, ok := found[name]; ok {
+			continue
+		}
+
+		column := m.columns[name]
+		switch {
+		case column.Required:
+			missing = append(missing, name)
+		case column.Default != "":
+			mapped = append(mapped, name)
+			m.defaults = append(m.defaults, column.Default)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required columns: %s", strings.Join(missing, ", "))
+	}
+
+	return mapped, nil
+}
+
+func normalizeColumn(name string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))
+}
@@ -0,0 +1,123 @@
+package pipeline
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kurochkinivan/device_reporter/internal/config"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// detectEncoding resolves EncodingAuto by the byte order mark or, without one, by the content:
+// valid UTF-8 stays UTF-8, zero bytes in every other position mean UTF-16, anything else
+// is taken for Windows-1251.
+func detectEncoding(configured config.Encoding, head []byte) config.Encoding {
+	if configured != config.EncodingAuto && configured != "" {
+		return configured
+	}
+
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		return config.EncodingUTF8
+	case bytes.HasPrefix(head, bomUTF16LE):
+		return config.EncodingUTF16LE
+	case bytes.HasPrefix(head, bomUTF16BE):
+		return config.EncodingUTF16BE
+	}
+
+	var evenZeros, oddZeros int
+	for i, b := range head {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenZeros++
+		} else {
+			oddZeros++
+		}
+	}
+
+	// в UTF-16 старший байт ASCII-символов нулевой
+	switch half := len(head) / 4; {
+	case half > 0 && oddZeros > half:
+		return config.EncodingUTF16LE
+	case half > 0 && evenZeros > half:
+		return config.EncodingUTF16BE
+	}
+
+	if utf8.Valid(trimPartialRune(head)) {
+		return config.EncodingUTF8
+	}
+
+	return config.EncodingWindows1251
+}
+
+// decodeText converts text in the given encoding to UTF-8 and strips the byte order mark.
+// Invalid sequences of non-UTF-8 encodings become U+FFFD; invalid UTF-8 is passed through
+// and caught per row by encodingErrors.
+func decodeText(r io.Reader, enc config.Encoding) io.Reader {
+	var decoder *encoding.Decoder
+
+	switch enc {
+	case config.EncodingWindows1251:
+		decoder = charmap.Windows1251.NewDecoder()
+	case config.EncodingUTF16LE:
+		decoder = unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder()
+	case config.EncodingUTF16BE:
+		decoder = unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewDecoder()
+	default:
+		return transform.NewReader(r, unicode.BOMOverride(transform.Nop))
+	}
+
+	return transform.NewReader(r, decoder)
+}
+
+// encodingErrors reports the fields of a record that are not valid text.
+func encodingErrors(header, record []string) []*domain.RowError {
+	var rowErrors []*domain.RowError
+
+	for i, value := range record {
+		if utf8.ValidString(value) && !strings.ContainsRune(value, utf8.RuneError) {
+			continue
+		}
+
+		var column string
+		if i < len(header) {
+			column = header[i]
+		}
+
+		rowErrors = append(rowErrors, &domain.RowError{
+			Column: column,
+			Value:  strings.ToValidUTF8(value, string(utf8.RuneError)),
+			Reason: "contains an invalid byte sequence",
+		})
+	}
+
+	return rowErrors
+}
+
+// trimPartialRune drops a rune cut in half at the end of the sniffed bytes.
+func trimPartialRune(head []byte) []byte {
+	for i := len(head) - 1; i >= 0 && i >= len(head)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(head[i]) {
+			if !utf8.FullRune(head[i:]) {
+				return head[:i]
+			}
+			break
+		}
+	}
+
+	return head
+}
@@ -0,0 +1,66 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+)
+
+// filesLookup resolves known files for the scanner: from a snapshot of the files table
+// during a full scan, or straight from the database for single watcher events.
+type filesLookup interface {
+	File(ctx context.Context, name string) (*domain.File, error)
+	FileByHash(ctx context.Context, hash string) (*domain.File, error)
+	add(file *domain.File)
+}
+
+type filesSnapshot struct {
+	byName map[string]*domain.File
+	byHash map[string]*domain.File
+}
+
+func (s *filesSnapshot) File(_ context.Context, name string) (*domain.File, error) {
+	file, ok := s.byName[name]
+	if !ok {
+		return nil, domain.ErrFileNotFound
+	}
+
+	return file, nil
+}
+
+func (s *filesSnapshot) FileByHash(_ context.Context, hash string) (*domain.File, error) {
+	file, ok := s.byHash[hash]
+	if !ok {
+		return nil, domain.ErrFileNotFound
+	}
+
+	return file, nil
+}
+
+func (s *filesSnapshot) add(file *domain.File) {
+	s.byName[file.Name] = file
+
+	if file.Hash == "" || file.Status == domain.StatusDuplicate {
+		return
+	}
+
+	if _, ok := s.byHash[file.Hash]; !ok {
+		s.byHash[file.Hash] = file
+	}
+}
+
+// filesDB looks files up one by one; rows written by the scanner are already in the database.
+type filesDB struct {
+	provider FilesProvider
+	source   string
+}
+
+func (db filesDB) File(ctx context.Context, name string) (*domain.File, error) {
+	return db.provider.File(ctx, db.source, name)
+}
+
+func (db filesDB) FileByHash(ctx context.Context, hash string) (*domain.File, error) {
+	return db.provider.FileByHash(ctx, db.source, hash)
+}
+
+func (filesDB) add(*domain.File) {}
@@ -2,26 +2,114 @@ package pipeline
 
 import (
 	"context"
+	"io"
+	"time"
 
+	"github.com/jszwec/csvutil"
+	"github.com/kurochkinivan/device_reporter/internal/config"
 	"github.com/kurochkinivan/device_reporter/internal/domain"
 )
 
 type FilesProvider interface {
-	Files(ctx context.Context) ([]*domain.File, error)
+	Files(ctx context.Context, source string) ([]*domain.File, error)
+	File(ctx context.Context, source, name string) (*domain.File, error)
+	FileByHash(ctx context.Context, source, hash string) (*domain.File, error)
 }
 
 type FileUpdater interface {
 	UpdateOrCreateFile(ctx context.Context, file *domain.File) error
+	UpdateProgress(ctx context.Context, source, name string, rowsProcessed int64) error
+}
+
+type DevicesProvider interface {
+	DevicesByFile(ctx context.Context, source, fileName, unitGUID string) ([]*domain.Device, error)
+	// DevicesByUnit returns the records of a unit stored in the source since the given time,
+	// file by file. A zero since returns all records.
+	DevicesByUnit(ctx context.Context, source, unitGUID string, since time.Time) ([]*domain.Device, error)
+}
+
+// FleetProvider lists the units of fleet reports with the counts of their records.
+type FleetProvider interface {
+	UnitsByFile(ctx context.Context, source, fileName string) ([]*domain.FleetUnit, error)
+	UnitsByInvID(ctx context.Context, source, invID string) ([]*domain.FleetUnit, error)
 }
 
 type DevicesSaver interface {
 	SaveDevices(ctx context.Context, devices ...*domain.Device) error
+	DeleteDevicesByFile(ctx context.Context, source, fileName string) error
+}
+
+type RowErrorsSaver interface {
+	// ReplaceRowErrors stores the row errors of a file in place of the previous ones.
+	ReplaceRowErrors(ctx context.Context, source, fileName string, rowErrors []*domain.RowError) error
+}
+
+type RejectedRowsSaver interface {
+	// ReplaceRejectedRows stores the rejected rows of a file in place of the previous ones.
+	ReplaceRejectedRows(ctx context.Context, source, fileName string, rows []*domain.RejectedRow) error
 }
 
 type Transactor interface {
 	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
+type ReportsScheduler interface {
+	// ScheduleReports replaces the reports of a file with pending ones, one per unit_guid of its devices,
+	// and enqueues a report job for them in the outbox.
+	ScheduleReports(ctx context.Context, source, fileName string) error
+}
+
+type ReportsTracker interface {
+	// PendingReports returns the reports of a file that are not generated yet or failed.
+	PendingReports(ctx context.Context, source, fileName string) ([]*domain.Report, error)
+	UpdateReport(ctx context.Context, report *domain.Report) error
+	// NextReportVersion returns the number the next report of the unit gets.
+	NextReportVersion(ctx context.Context, source, unitGUID string) (int, error)
+	// AddReportVersion adds a generated report to the history of its unit.
+	AddReportVersion(ctx context.Context, version *domain.ReportVersion) error
+	// MoveReportFile records that a report file of the source was moved from one path to another.
+	MoveReportFile(ctx context.Context, source, from, to string) error
+}
+
+// ReportJobs is the outbox of report jobs.
+type ReportJobs interface {
+	// DueReportJobs returns up to limit jobs that are due and have attempts left, oldest first.
+	DueReportJobs(ctx context.Context, limit uint64, maxAttempts int) ([]*domain.ReportJob, error)
+	AckReportJob(ctx context.Context, id int64) error
+	// RetryReportJob counts a failed attempt and postpones the job until retryAt.
+	RetryReportJob(ctx context.Context, id int64, retryAt time.Time, reason string) error
+	// ResetReportJobs makes every job due again with its attempts restored.
+	ResetReportJobs(ctx context.Context) error
+}
+
 type ReportGenerator interface {
-	GenerateReport(outputPath, unitGUID, sourceFile string, devices []*domain.Device) error
+	// Format names the format the generator renders, which is also the extension of its files.
+	Format() string
+	// GenerateReport renders the report in the style of its source; formats without layouts
+	// ignore the layout of the style.
+	GenerateReport(outputPath string, style config.ReportStyle, unitGUID, sourceFile string, devices []*domain.Device) error
+	// GenerateFleetReport renders the list of the units of a file or of an inventory ID.
+	GenerateFleetReport(outputPath string, style config.ReportStyle, fleet *domain.FleetReport) error
+}
+
+type FileArchiver interface {
+	Archive(file *domain.File) error
+}
+
+type Format interface {
+	Name() string
+	// Extensions lists file extensions (with the dot) the format is picked by.
+	Extensions() []string
+	// Sniff reports whether the first bytes of a file look like this format.
+	Sniff(head []byte) bool
+	// Binary reports whether the format reads raw bytes, so its input must not be transcoded.
+	Binary() bool
+	// NewReader returns the rows of the file as string records, the header row first.
+	// The reader must be a lineReader. A reader that is an io.Closer is closed once the file is read.
+	NewReader(r io.Reader, settings config.Parser) (csvutil.Reader, error)
+}
+
+// lineReader reports where in the file the record read last starts, for row errors.
+type lineReader interface {
+	Line() int
 }
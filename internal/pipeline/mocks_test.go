@@ -6,7 +6,9 @@ package pipeline_test
 
 import (
 	"context"
+	"time"
 
+	"github.com/kurochkinivan/device_reporter/internal/config"
 	"github.com/kurochkinivan/device_reporter/internal/domain"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -38,9 +40,157 @@ func (_m *MockFilesProvider) EXPECT() *MockFilesProvider_Expecter {
 	return &MockFilesProvider_Expecter{mock: &_m.Mock}
 }
 
+// File provides a mock function for the type MockFilesProvider
+func (_mock *MockFilesProvider) File(ctx context.Context, source string, name string) (*domain.File, error) {
+	ret := _mock.Called(ctx, source, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for File")
+	}
+
+	var r0 *domain.File
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) (*domain.File, error)); ok {
+		return returnFunc(ctx, source, name)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) *domain.File); ok {
+		r0 = returnFunc(ctx, source, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.File)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, source, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockFilesProvider_File_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'File'
+type MockFilesProvider_File_Call struct {
+	*mock.Call
+}
+
+// File is a helper method to define mock.On call
+//   - ctx context.Context
+//   - source string
+//   - name string
+func (_e *MockFilesProvider_Expecter) File(ctx interface{}, source interface{}, name interface{}) *MockFilesProvider_File_Call {
+	return &MockFilesProvider_File_Call{Call: _e.mock.On("File", ctx, source, name)}
+}
+
+func (_c *MockFilesProvider_File_Call) Run(run func(ctx context.Context, source string, name string)) *MockFilesProvider_File_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockFilesProvider_File_Call) Return(file *domain.File, err error) *MockFilesProvider_File_Call {
+	_c.Call.Return(file, err)
+	return _c
+}
+
+func (_c *MockFilesProvider_File_Call) RunAndReturn(run func(ctx context.Context, source string, name string) (*domain.File, error)) *MockFilesProvider_File_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FileByHash provides a mock function for the type MockFilesProvider
+func (_mock *MockFilesProvider) FileByHash(ctx context.Context, source string, hash string) (*domain.File, error) {
+	ret := _mock.Called(ctx, source, hash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FileByHash")
+	}
+
+	var r0 *domain.File
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) (*domain.File, error)); ok {
+		return returnFunc(ctx, source, hash)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) *domain.File); ok {
+		r0 = returnFunc(ctx, source, hash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.File)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, source, hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockFilesProvider_FileByHash_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FileByHash'
+type MockFilesProvider_FileByHash_Call struct {
+	*mock.Call
+}
+
+// FileByHash is a helper method to define mock.On call
+//   - ctx context.Context
+//   - source string
+//   - hash string
+func (_e *MockFilesProvider_Expecter) FileByHash(ctx interface{}, source interface{}, hash interface{}) *MockFilesProvider_FileByHash_Call {
+	return &MockFilesProvider_FileByHash_Call{Call: _e.mock.On("FileByHash", ctx, source, hash)}
+}
+
+func (_c *MockFilesProvider_FileByHash_Call) Run(run func(ctx context.Context, source string, hash string)) *MockFilesProvider_FileByHash_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockFilesProvider_FileByHash_Call) Return(file *domain.File, err error) *MockFilesProvider_FileByHash_Call {
+	_c.Call.Return(file, err)
+	return _c
+}
+
+func (_c *MockFilesProvider_FileByHash_Call) RunAndReturn(run func(ctx context.Context, source string, hash string) (*domain.File, error)) *MockFilesProvider_FileByHash_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Files provides a mock function for the type MockFilesProvider
-func (_mock *MockFilesProvider) Files(ctx context.Context) ([]*domain.File, error) {
-	ret := _mock.Called(ctx)
+func (_mock *MockFilesProvider) Files(ctx context.Context, source string) ([]*domain.File, error) {
+	ret := _mock.Called(ctx, source)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Files")
@@ -48,18 +198,18 @@ func (_mock *MockFilesProvider) Files(ctx context.Context) ([]*domain.File, erro
 
 	var r0 []*domain.File
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context) ([]*domain.File, error)); ok {
-		return returnFunc(ctx)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) ([]*domain.File, error)); ok {
+		return returnFunc(ctx, source)
 	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context) []*domain.File); ok {
-		r0 = returnFunc(ctx)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) []*domain.File); ok {
+		r0 = returnFunc(ctx, source)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*domain.File)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = returnFunc(ctx)
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, source)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -73,18 +223,24 @@ type MockFilesProvider_Files_Call struct {
 
 // Files is a helper method to define mock.On call
 //   - ctx context.Context
-func (_e *MockFilesProvider_Expecter) Files(ctx interface{}) *MockFilesProvider_Files_Call {
-	return &MockFilesProvider_Files_Call{Call: _e.mock.On("Files", ctx)}
+//   - source string
+func (_e *MockFilesProvider_Expecter) Files(ctx interface{}, source interface{}) *MockFilesProvider_Files_Call {
+	return &MockFilesProvider_Files_Call{Call: _e.mock.On("Files", ctx, source)}
 }
 
-func (_c *MockFilesProvider_Files_Call) Run(run func(ctx context.Context)) *MockFilesProvider_Files_Call {
+func (_c *MockFilesProvider_Files_Call) Run(run func(ctx context.Context, source string)) *MockFilesProvider_Files_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
 		run(
 			arg0,
+			arg1,
 		)
 	})
 	return _c
@@ -95,7 +251,7 @@ func (_c *MockFilesProvider_Files_Call) Return(files []*domain.File, err error)
 	return _c
 }
 
-func (_c *MockFilesProvider_Files_Call) RunAndReturn(run func(ctx context.Context) ([]*domain.File, error)) *MockFilesProvider_Files_Call {
+func (_c *MockFilesProvider_Files_Call) RunAndReturn(run func(ctx context.Context, source string) ([]*domain.File, error)) *MockFilesProvider_Files_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -184,106 +340,82 @@ func (_c *MockFileUpdater_UpdateOrCreateFile_Call) RunAndReturn(run func(ctx con
 	return _c
 }
 
-// NewMockDevicesSaver creates a new instance of MockDevicesSaver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
-// The first argument is typically a *testing.T value.
-func NewMockDevicesSaver(t interface {
-	mock.TestingT
-	Cleanup(func())
-}) *MockDevicesSaver {
-	mock := &MockDevicesSaver{}
-	mock.Mock.Test(t)
-
-	t.Cleanup(func() { mock.AssertExpectations(t) })
-
-	return mock
-}
-
-// MockDevicesSaver is an autogenerated mock type for the DevicesSaver type
-type MockDevicesSaver struct {
-	mock.Mock
-}
-
-type MockDevicesSaver_Expecter struct {
-	mock *mock.Mock
-}
-
-func (_m *MockDevicesSaver) EXPECT() *MockDevicesSaver_Expecter {
-	return &MockDevicesSaver_Expecter{mock: &_m.Mock}
-}
-
-// SaveDevices provides a mock function for the type MockDevicesSaver
-func (_mock *MockDevicesSaver) SaveDevices(ctx context.Context, devices ...*domain.Device) error {
-	var tmpRet mock.Arguments
-	if len(devices) > 0 {
-		tmpRet = _mock.Called(ctx, devices)
-	} else {
-		tmpRet = _mock.Called(ctx)
-	}
-	ret := tmpRet
+// UpdateProgress provides a mock function for the type MockFileUpdater
+func (_mock *MockFileUpdater) UpdateProgress(ctx context.Context, source string, name string, rowsProcessed int64) error {
+	ret := _mock.Called(ctx, source, name, rowsProcessed)
 
 	if len(ret) == 0 {
-		panic("no return value specified for SaveDevices")
+		panic("no return value specified for UpdateProgress")
 	}
 
 	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, ...*domain.Device) error); ok {
-		r0 = returnFunc(ctx, devices...)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, int64) error); ok {
+		r0 = returnFunc(ctx, source, name, rowsProcessed)
 	} else {
 		r0 = ret.Error(0)
 	}
 	return r0
 }
 
-// MockDevicesSaver_SaveDevices_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveDevices'
-type MockDevicesSaver_SaveDevices_Call struct {
+// MockFileUpdater_UpdateProgress_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateProgress'
+type MockFileUpdater_UpdateProgress_Call struct {
 	*mock.Call
 }
 
-// SaveDevices is a helper method to define mock.On call
+// UpdateProgress is a helper method to define mock.On call
 //   - ctx context.Context
-//   - devices ...*domain.Device
-func (_e *MockDevicesSaver_Expecter) SaveDevices(ctx interface{}, devices ...interface{}) *MockDevicesSaver_SaveDevices_Call {
-	return &MockDevicesSaver_SaveDevices_Call{Call: _e.mock.On("SaveDevices",
-		append([]interface{}{ctx}, devices...)...)}
+//   - source string
+//   - name string
+//   - rowsProcessed int64
+func (_e *MockFileUpdater_Expecter) UpdateProgress(ctx interface{}, source interface{}, name interface{}, rowsProcessed interface{}) *MockFileUpdater_UpdateProgress_Call {
+	return &MockFileUpdater_UpdateProgress_Call{Call: _e.mock.On("UpdateProgress", ctx, source, name, rowsProcessed)}
 }
 
-func (_c *MockDevicesSaver_SaveDevices_Call) Run(run func(ctx context.Context, devices ...*domain.Device)) *MockDevicesSaver_SaveDevices_Call {
+func (_c *MockFileUpdater_UpdateProgress_Call) Run(run func(ctx context.Context, source string, name string, rowsProcessed int64)) *MockFileUpdater_UpdateProgress_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
-		var arg1 []*domain.Device
-		var variadicArgs []*domain.Device
-		if len(args) > 1 {
-			variadicArgs = args[1].([]*domain.Device)
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 int64
+		if args[3] != nil {
+			arg3 = args[3].(int64)
 		}
-		arg1 = variadicArgs
 		run(
 			arg0,
-			arg1...,
+			arg1,
+			arg2,
+			arg3,
 		)
 	})
 	return _c
 }
 
-func (_c *MockDevicesSaver_SaveDevices_Call) Return(err error) *MockDevicesSaver_SaveDevices_Call {
+func (_c *MockFileUpdater_UpdateProgress_Call) Return(err error) *MockFileUpdater_UpdateProgress_Call {
 	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockDevicesSaver_SaveDevices_Call) RunAndReturn(run func(ctx context.Context, devices ...*domain.Device) error) *MockDevicesSaver_SaveDevices_Call {
+func (_c *MockFileUpdater_UpdateProgress_Call) RunAndReturn(run func(ctx context.Context, source string, name string, rowsProcessed int64) error) *MockFileUpdater_UpdateProgress_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// NewMockTransactor creates a new instance of MockTransactor. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// NewMockDevicesProvider creates a new instance of MockDevicesProvider. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
-func NewMockTransactor(t interface {
+func NewMockDevicesProvider(t interface {
 	mock.TestingT
 	Cleanup(func())
-}) *MockTransactor {
-	mock := &MockTransactor{}
+}) *MockDevicesProvider {
+	mock := &MockDevicesProvider{}
 	mock.Mock.Test(t)
 
 	t.Cleanup(func() { mock.AssertExpectations(t) })
@@ -291,114 +423,1655 @@ func NewMockTransactor(t interface {
 	return mock
 }
 
-// MockTransactor is an autogenerated mock type for the Transactor type
-type MockTransactor struct {
+// MockDevicesProvider is an autogenerated mock type for the DevicesProvider type
+type MockDevicesProvider struct {
 	mock.Mock
 }
 
-type MockTransactor_Expecter struct {
+type MockDevicesProvider_Expecter struct {
 	mock *mock.Mock
 }
 
-func (_m *MockTransactor) EXPECT() *MockTransactor_Expecter {
-	return &MockTransactor_Expecter{mock: &_m.Mock}
+func (_m *MockDevicesProvider) EXPECT() *MockDevicesProvider_Expecter {
+	return &MockDevicesProvider_Expecter{mock: &_m.Mock}
 }
 
-// WithTransaction provides a mock function for the type MockTransactor
-func (_mock *MockTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
-	ret := _mock.Called(ctx, fn)
+// DevicesByFile provides a mock function for the type MockDevicesProvider
+func (_mock *MockDevicesProvider) DevicesByFile(ctx context.Context, source string, fileName string, unitGUID string) ([]*domain.Device, error) {
+	ret := _mock.Called(ctx, source, fileName, unitGUID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for WithTransaction")
+		panic("no return value specified for DevicesByFile")
 	}
 
-	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, func(ctx context.Context) error) error); ok {
-		r0 = returnFunc(ctx, fn)
+	var r0 []*domain.Device
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string) ([]*domain.Device, error)); ok {
+		return returnFunc(ctx, source, fileName, unitGUID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string) []*domain.Device); ok {
+		r0 = returnFunc(ctx, source, fileName, unitGUID)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Device)
+		}
 	}
-	return r0
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = returnFunc(ctx, source, fileName, unitGUID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
 }
 
-// MockTransactor_WithTransaction_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WithTransaction'
-type MockTransactor_WithTransaction_Call struct {
+// MockDevicesProvider_DevicesByFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DevicesByFile'
+type MockDevicesProvider_DevicesByFile_Call struct {
 	*mock.Call
 }
 
-// WithTransaction is a helper method to define mock.On call
+// DevicesByFile is a helper method to define mock.On call
 //   - ctx context.Context
-//   - fn func(ctx context.Context) error
-func (_e *MockTransactor_Expecter) WithTransaction(ctx interface{}, fn interface{}) *MockTransactor_WithTransaction_Call {
-	return &MockTransactor_WithTransaction_Call{Call: _e.mock.On("WithTransaction", ctx, fn)}
+//   - source string
+//   - fileName string
+//   - unitGUID string
+func (_e *MockDevicesProvider_Expecter) DevicesByFile(ctx interface{}, source interface{}, fileName interface{}, unitGUID interface{}) *MockDevicesProvider_DevicesByFile_Call {
+	return &MockDevicesProvider_DevicesByFile_Call{Call: _e.mock.On("DevicesByFile", ctx, source, fileName, unitGUID)}
 }
 
-func (_c *MockTransactor_WithTransaction_Call) Run(run func(ctx context.Context, fn func(ctx context.Context) error)) *MockTransactor_WithTransaction_Call {
+func (_c *MockDevicesProvider_DevicesByFile_Call) Run(run func(ctx context.Context, source string, fileName string, unitGUID string)) *MockDevicesProvider_DevicesByFile_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
-		var arg1 func(ctx context.Context) error
+		var arg1 string
 		if args[1] != nil {
-			arg1 = args[1].(func(ctx context.Context) error)
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 string
+		if args[3] != nil {
+			arg3 = args[3].(string)
 		}
 		run(
 			arg0,
 			arg1,
+			arg2,
+			arg3,
 		)
 	})
 	return _c
 }
 
-func (_c *MockTransactor_WithTransaction_Call) Return(err error) *MockTransactor_WithTransaction_Call {
-	_c.Call.Return(err)
+func (_c *MockDevicesProvider_DevicesByFile_Call) Return(devices []*domain.Device, err error) *MockDevicesProvider_DevicesByFile_Call {
+	_c.Call.Return(devices, err)
 	return _c
 }
 
-func (_c *MockTransactor_WithTransaction_Call) RunAndReturn(run func(ctx context.Context, fn func(ctx context.Context) error) error) *MockTransactor_WithTransaction_Call {
+func (_c *MockDevicesProvider_DevicesByFile_Call) RunAndReturn(run func(ctx context.Context, source string, fileName string, unitGUID string) ([]*domain.Device, error)) *MockDevicesProvider_DevicesByFile_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// NewMockReportGenerator creates a new instance of MockReportGenerator. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
-// The first argument is typically a *testing.T value.
-func NewMockReportGenerator(t interface {
-	mock.TestingT
-	Cleanup(func())
-}) *MockReportGenerator {
-	mock := &MockReportGenerator{}
-	mock.Mock.Test(t)
+// DevicesByUnit provides a mock function for the type MockDevicesProvider
+func (_mock *MockDevicesProvider) DevicesByUnit(ctx context.Context, source string, unitGUID string, since time.Time) ([]*domain.Device, error) {
+	ret := _mock.Called(ctx, source, unitGUID, since)
 
-	t.Cleanup(func() { mock.AssertExpectations(t) })
+	if len(ret) == 0 {
+		panic("no return value specified for DevicesByUnit")
+	}
 
-	return mock
+	var r0 []*domain.Device
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, time.Time) ([]*domain.Device, error)); ok {
+		return returnFunc(ctx, source, unitGUID, since)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, time.Time) []*domain.Device); ok {
+		r0 = returnFunc(ctx, source, unitGUID, since)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Device)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = returnFunc(ctx, source, unitGUID, since)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
 }
 
-// MockReportGenerator is an autogenerated mock type for the ReportGenerator type
-type MockReportGenerator struct {
-	mock.Mock
+// MockDevicesProvider_DevicesByUnit_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DevicesByUnit'
+type MockDevicesProvider_DevicesByUnit_Call struct {
+	*mock.Call
 }
 
-type MockReportGenerator_Expecter struct {
-	mock *mock.Mock
+// DevicesByUnit is a helper method to define mock.On call
+//   - ctx context.Context
+//   - source string
+//   - unitGUID string
+//   - since time.Time
+func (_e *MockDevicesProvider_Expecter) DevicesByUnit(ctx interface{}, source interface{}, unitGUID interface{}, since interface{}) *MockDevicesProvider_DevicesByUnit_Call {
+	return &MockDevicesProvider_DevicesByUnit_Call{Call: _e.mock.On("DevicesByUnit", ctx, source, unitGUID, since)}
 }
 
-func (_m *MockReportGenerator) EXPECT() *MockReportGenerator_Expecter {
+func (_c *MockDevicesProvider_DevicesByUnit_Call) Run(run func(ctx context.Context, source string, unitGUID string, since time.Time)) *MockDevicesProvider_DevicesByUnit_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 time.Time
+		if args[3] != nil {
+			arg3 = args[3].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDevicesProvider_DevicesByUnit_Call) Return(devices []*domain.Device, err error) *MockDevicesProvider_DevicesByUnit_Call {
+	_c.Call.Return(devices, err)
+	return _c
+}
+
+func (_c *MockDevicesProvider_DevicesByUnit_Call) RunAndReturn(run func(ctx context.Context, source string, unitGUID string, since time.Time) ([]*domain.Device, error)) *MockDevicesProvider_DevicesByUnit_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockDevicesSaver creates a new instance of MockDevicesSaver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockDevicesSaver(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockDevicesSaver {
+	mock := &MockDevicesSaver{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockDevicesSaver is an autogenerated mock type for the DevicesSaver type
+type MockDevicesSaver struct {
+	mock.Mock
+}
+
+type MockDevicesSaver_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockDevicesSaver) EXPECT() *MockDevicesSaver_Expecter {
+	return &MockDevicesSaver_Expecter{mock: &_m.Mock}
+}
+
+// DeleteDevicesByFile provides a mock function for the type MockDevicesSaver
+func (_mock *MockDevicesSaver) DeleteDevicesByFile(ctx context.Context, source string, fileName string) error {
+	ret := _mock.Called(ctx, source, fileName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteDevicesByFile")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = returnFunc(ctx, source, fileName)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockDevicesSaver_DeleteDevicesByFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteDevicesByFile'
+type MockDevicesSaver_DeleteDevicesByFile_Call struct {
+	*mock.Call
+}
+
+// DeleteDevicesByFile is a helper method to define mock.On call
+//   - ctx context.Context
+//   - source string
+//   - fileName string
+func (_e *MockDevicesSaver_Expecter) DeleteDevicesByFile(ctx interface{}, source interface{}, fileName interface{}) *MockDevicesSaver_DeleteDevicesByFile_Call {
+	return &MockDevicesSaver_DeleteDevicesByFile_Call{Call: _e.mock.On("DeleteDevicesByFile", ctx, source, fileName)}
+}
+
+func (_c *MockDevicesSaver_DeleteDevicesByFile_Call) Run(run func(ctx context.Context, source string, fileName string)) *MockDevicesSaver_DeleteDevicesByFile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDevicesSaver_DeleteDevicesByFile_Call) Return(err error) *MockDevicesSaver_DeleteDevicesByFile_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockDevicesSaver_DeleteDevicesByFile_Call) RunAndReturn(run func(ctx context.Context, source string, fileName string) error) *MockDevicesSaver_DeleteDevicesByFile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveDevices provides a mock function for the type MockDevicesSaver
+func (_mock *MockDevicesSaver) SaveDevices(ctx context.Context, devices ...*domain.Device) error {
+	var tmpRet mock.Arguments
+	if len(devices) > 0 {
+		tmpRet = _mock.Called(ctx, devices)
+	} else {
+		tmpRet = _mock.Called(ctx)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveDevices")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, ...*domain.Device) error); ok {
+		r0 = returnFunc(ctx, devices...)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockDevicesSaver_SaveDevices_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveDevices'
+type MockDevicesSaver_SaveDevices_Call struct {
+	*mock.Call
+}
+
+// SaveDevices is a helper method to define mock.On call
+//   - ctx context.Context
+//   - devices ...*domain.Device
+func (_e *MockDevicesSaver_Expecter) SaveDevices(ctx interface{}, devices ...interface{}) *MockDevicesSaver_SaveDevices_Call {
+	return &MockDevicesSaver_SaveDevices_Call{Call: _e.mock.On("SaveDevices",
+		append([]interface{}{ctx}, devices...)...)}
+}
+
+func (_c *MockDevicesSaver_SaveDevices_Call) Run(run func(ctx context.Context, devices ...*domain.Device)) *MockDevicesSaver_SaveDevices_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 []*domain.Device
+		var variadicArgs []*domain.Device
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*domain.Device)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDevicesSaver_SaveDevices_Call) Return(err error) *MockDevicesSaver_SaveDevices_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockDevicesSaver_SaveDevices_Call) RunAndReturn(run func(ctx context.Context, devices ...*domain.Device) error) *MockDevicesSaver_SaveDevices_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockRowErrorsSaver creates a new instance of MockRowErrorsSaver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockRowErrorsSaver(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockRowErrorsSaver {
+	mock := &MockRowErrorsSaver{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockRowErrorsSaver is an autogenerated mock type for the RowErrorsSaver type
+type MockRowErrorsSaver struct {
+	mock.Mock
+}
+
+type MockRowErrorsSaver_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockRowErrorsSaver) EXPECT() *MockRowErrorsSaver_Expecter {
+	return &MockRowErrorsSaver_Expecter{mock: &_m.Mock}
+}
+
+// ReplaceRowErrors provides a mock function for the type MockRowErrorsSaver
+func (_mock *MockRowErrorsSaver) ReplaceRowErrors(ctx context.Context, source string, fileName string, rowErrors []*domain.RowError) error {
+	ret := _mock.Called(ctx, source, fileName, rowErrors)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReplaceRowErrors")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, []*domain.RowError) error); ok {
+		r0 = returnFunc(ctx, source, fileName, rowErrors)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockRowErrorsSaver_ReplaceRowErrors_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ReplaceRowErrors'
+type MockRowErrorsSaver_ReplaceRowErrors_Call struct {
+	*mock.Call
+}
+
+// ReplaceRowErrors is a helper method to define mock.On call
+//   - ctx context.Context
+//   - source string
+//   - fileName string
+//   - rowErrors []*domain.RowError
+func (_e *MockRowErrorsSaver_Expecter) ReplaceRowErrors(ctx interface{}, source interface{}, fileName interface{}, rowErrors interface{}) *MockRowErrorsSaver_ReplaceRowErrors_Call {
+	return &MockRowErrorsSaver_ReplaceRowErrors_Call{Call: _e.mock.On("ReplaceRowErrors", ctx, source, fileName, rowErrors)}
+}
+
+func (_c *MockRowErrorsSaver_ReplaceRowErrors_Call) Run(run func(ctx context.Context, source string, fileName string, rowErrors []*domain.RowError)) *MockRowErrorsSaver_ReplaceRowErrors_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 []*domain.RowError
+		if args[3] != nil {
+			arg3 = args[3].([]*domain.RowError)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRowErrorsSaver_ReplaceRowErrors_Call) Return(err error) *MockRowErrorsSaver_ReplaceRowErrors_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockRowErrorsSaver_ReplaceRowErrors_Call) RunAndReturn(run func(ctx context.Context, source string, fileName string, rowErrors []*domain.RowError) error) *MockRowErrorsSaver_ReplaceRowErrors_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockRejectedRowsSaver creates a new instance of MockRejectedRowsSaver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockRejectedRowsSaver(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockRejectedRowsSaver {
+	mock := &MockRejectedRowsSaver{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockRejectedRowsSaver is an autogenerated mock type for the RejectedRowsSaver type
+type MockRejectedRowsSaver struct {
+	mock.Mock
+}
+
+type MockRejectedRowsSaver_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockRejectedRowsSaver) EXPECT() *MockRejectedRowsSaver_Expecter {
+	return &MockRejectedRowsSaver_Expecter{mock: &_m.Mock}
+}
+
+// ReplaceRejectedRows provides a mock function for the type MockRejectedRowsSaver
+func (_mock *MockRejectedRowsSaver) ReplaceRejectedRows(ctx context.Context, source string, fileName string, rows []*domain.RejectedRow) error {
+	ret := _mock.Called(ctx, source, fileName, rows)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReplaceRejectedRows")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, []*domain.RejectedRow) error); ok {
+		r0 = returnFunc(ctx, source, fileName, rows)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockRejectedRowsSaver_ReplaceRejectedRows_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ReplaceRejectedRows'
+type MockRejectedRowsSaver_ReplaceRejectedRows_Call struct {
+	*mock.Call
+}
+
+// ReplaceRejectedRows is a helper method to define mock.On call
+//   - ctx context.Context
+//   - source string
+//   - fileName string
+//   - rows []*domain.RejectedRow
+func (_e *MockRejectedRowsSaver_Expecter) ReplaceRejectedRows(ctx interface{}, source interface{}, fileName interface{}, rows interface{}) *MockRejectedRowsSaver_ReplaceRejectedRows_Call {
+	return &MockRejectedRowsSaver_ReplaceRejectedRows_Call{Call: _e.mock.On("ReplaceRejectedRows", ctx, source, fileName, rows)}
+}
+
+func (_c *MockRejectedRowsSaver_ReplaceRejectedRows_Call) Run(run func(ctx context.Context, source string, fileName string, rows []*domain.RejectedRow)) *MockRejectedRowsSaver_ReplaceRejectedRows_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 []*domain.RejectedRow
+		if args[3] != nil {
+			arg3 = args[3].([]*domain.RejectedRow)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRejectedRowsSaver_ReplaceRejectedRows_Call) Return(err error) *MockRejectedRowsSaver_ReplaceRejectedRows_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockRejectedRowsSaver_ReplaceRejectedRows_Call) RunAndReturn(run func(ctx context.Context, source string, fileName string, rows []*domain.RejectedRow) error) *MockRejectedRowsSaver_ReplaceRejectedRows_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockTransactor creates a new instance of MockTransactor. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockTransactor(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockTransactor {
+	mock := &MockTransactor{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockTransactor is an autogenerated mock type for the Transactor type
+type MockTransactor struct {
+	mock.Mock
+}
+
+type MockTransactor_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockTransactor) EXPECT() *MockTransactor_Expecter {
+	return &MockTransactor_Expecter{mock: &_m.Mock}
+}
+
+// WithTransaction provides a mock function for the type MockTransactor
+func (_mock *MockTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	ret := _mock.Called(ctx, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WithTransaction")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, func(ctx context.Context) error) error); ok {
+		r0 = returnFunc(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockTransactor_WithTransaction_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WithTransaction'
+type MockTransactor_WithTransaction_Call struct {
+	*mock.Call
+}
+
+// WithTransaction is a helper method to define mock.On call
+//   - ctx context.Context
+//   - fn func(ctx context.Context) error
+func (_e *MockTransactor_Expecter) WithTransaction(ctx interface{}, fn interface{}) *MockTransactor_WithTransaction_Call {
+	return &MockTransactor_WithTransaction_Call{Call: _e.mock.On("WithTransaction", ctx, fn)}
+}
+
+func (_c *MockTransactor_WithTransaction_Call) Run(run func(ctx context.Context, fn func(ctx context.Context) error)) *MockTransactor_WithTransaction_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 func(ctx context.Context) error
+		if args[1] != nil {
+			arg1 = args[1].(func(ctx context.Context) error)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockTransactor_WithTransaction_Call) Return(err error) *MockTransactor_WithTransaction_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockTransactor_WithTransaction_Call) RunAndReturn(run func(ctx context.Context, fn func(ctx context.Context) error) error) *MockTransactor_WithTransaction_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockReportsScheduler creates a new instance of MockReportsScheduler. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockReportsScheduler(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockReportsScheduler {
+	mock := &MockReportsScheduler{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockReportsScheduler is an autogenerated mock type for the ReportsScheduler type
+type MockReportsScheduler struct {
+	mock.Mock
+}
+
+type MockReportsScheduler_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockReportsScheduler) EXPECT() *MockReportsScheduler_Expecter {
+	return &MockReportsScheduler_Expecter{mock: &_m.Mock}
+}
+
+// ScheduleReports provides a mock function for the type MockReportsScheduler
+func (_mock *MockReportsScheduler) ScheduleReports(ctx context.Context, source string, fileName string) error {
+	ret := _mock.Called(ctx, source, fileName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ScheduleReports")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = returnFunc(ctx, source, fileName)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockReportsScheduler_ScheduleReports_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ScheduleReports'
+type MockReportsScheduler_ScheduleReports_Call struct {
+	*mock.Call
+}
+
+// ScheduleReports is a helper method to define mock.On call
+//   - ctx context.Context
+//   - source string
+//   - fileName string
+func (_e *MockReportsScheduler_Expecter) ScheduleReports(ctx interface{}, source interface{}, fileName interface{}) *MockReportsScheduler_ScheduleReports_Call {
+	return &MockReportsScheduler_ScheduleReports_Call{Call: _e.mock.On("ScheduleReports", ctx, source, fileName)}
+}
+
+func (_c *MockReportsScheduler_ScheduleReports_Call) Run(run func(ctx context.Context, source string, fileName string)) *MockReportsScheduler_ScheduleReports_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockReportsScheduler_ScheduleReports_Call) Return(err error) *MockReportsScheduler_ScheduleReports_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockReportsScheduler_ScheduleReports_Call) RunAndReturn(run func(ctx context.Context, source string, fileName string) error) *MockReportsScheduler_ScheduleReports_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockReportsTracker creates a new instance of MockReportsTracker. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockReportsTracker(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockReportsTracker {
+	mock := &MockReportsTracker{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockReportsTracker is an autogenerated mock type for the ReportsTracker type
+type MockReportsTracker struct {
+	mock.Mock
+}
+
+type MockReportsTracker_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockReportsTracker) EXPECT() *MockReportsTracker_Expecter {
+	return &MockReportsTracker_Expecter{mock: &_m.Mock}
+}
+
+// AddReportVersion provides a mock function for the type MockReportsTracker
+func (_mock *MockReportsTracker) AddReportVersion(ctx context.Context, version *domain.ReportVersion) error {
+	ret := _mock.Called(ctx, version)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddReportVersion")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *domain.ReportVersion) error); ok {
+		r0 = returnFunc(ctx, version)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockReportsTracker_AddReportVersion_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddReportVersion'
+type MockReportsTracker_AddReportVersion_Call struct {
+	*mock.Call
+}
+
+// AddReportVersion is a helper method to define mock.On call
+//   - ctx context.Context
+//   - version *domain.ReportVersion
+func (_e *MockReportsTracker_Expecter) AddReportVersion(ctx interface{}, version interface{}) *MockReportsTracker_AddReportVersion_Call {
+	return &MockReportsTracker_AddReportVersion_Call{Call: _e.mock.On("AddReportVersion", ctx, version)}
+}
+
+func (_c *MockReportsTracker_AddReportVersion_Call) Run(run func(ctx context.Context, version *domain.ReportVersion)) *MockReportsTracker_AddReportVersion_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *domain.ReportVersion
+		if args[1] != nil {
+			arg1 = args[1].(*domain.ReportVersion)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockReportsTracker_AddReportVersion_Call) Return(err error) *MockReportsTracker_AddReportVersion_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockReportsTracker_AddReportVersion_Call) RunAndReturn(run func(ctx context.Context, version *domain.ReportVersion) error) *MockReportsTracker_AddReportVersion_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// MoveReportFile provides a mock function for the type MockReportsTracker
+func (_mock *MockReportsTracker) MoveReportFile(ctx context.Context, source string, from string, to string) error {
+	ret := _mock.Called(ctx, source, from, to)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MoveReportFile")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
+		r0 = returnFunc(ctx, source, from, to)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockReportsTracker_MoveReportFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'MoveReportFile'
+type MockReportsTracker_MoveReportFile_Call struct {
+	*mock.Call
+}
+
+// MoveReportFile is a helper method to define mock.On call
+//   - ctx context.Context
+//   - source string
+//   - from string
+//   - to string
+func (_e *MockReportsTracker_Expecter) MoveReportFile(ctx interface{}, source interface{}, from interface{}, to interface{}) *MockReportsTracker_MoveReportFile_Call {
+	return &MockReportsTracker_MoveReportFile_Call{Call: _e.mock.On("MoveReportFile", ctx, source, from, to)}
+}
+
+func (_c *MockReportsTracker_MoveReportFile_Call) Run(run func(ctx context.Context, source string, from string, to string)) *MockReportsTracker_MoveReportFile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 string
+		if args[3] != nil {
+			arg3 = args[3].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockReportsTracker_MoveReportFile_Call) Return(err error) *MockReportsTracker_MoveReportFile_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockReportsTracker_MoveReportFile_Call) RunAndReturn(run func(ctx context.Context, source string, from string, to string) error) *MockReportsTracker_MoveReportFile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NextReportVersion provides a mock function for the type MockReportsTracker
+func (_mock *MockReportsTracker) NextReportVersion(ctx context.Context, source string, unitGUID string) (int, error) {
+	ret := _mock.Called(ctx, source, unitGUID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NextReportVersion")
+	}
+
+	var r0 int
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) (int, error)); ok {
+		return returnFunc(ctx, source, unitGUID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) int); ok {
+		r0 = returnFunc(ctx, source, unitGUID)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, source, unitGUID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockReportsTracker_NextReportVersion_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'NextReportVersion'
+type MockReportsTracker_NextReportVersion_Call struct {
+	*mock.Call
+}
+
+// NextReportVersion is a helper method to define mock.On call
+//   - ctx context.Context
+//   - source string
+//   - unitGUID string
+func (_e *MockReportsTracker_Expecter) NextReportVersion(ctx interface{}, source interface{}, unitGUID interface{}) *MockReportsTracker_NextReportVersion_Call {
+	return &MockReportsTracker_NextReportVersion_Call{Call: _e.mock.On("NextReportVersion", ctx, source, unitGUID)}
+}
+
+func (_c *MockReportsTracker_NextReportVersion_Call) Run(run func(ctx context.Context, source string, unitGUID string)) *MockReportsTracker_NextReportVersion_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockReportsTracker_NextReportVersion_Call) Return(version int, err error) *MockReportsTracker_NextReportVersion_Call {
+	_c.Call.Return(version, err)
+	return _c
+}
+
+func (_c *MockReportsTracker_NextReportVersion_Call) RunAndReturn(run func(ctx context.Context, source string, unitGUID string) (int, error)) *MockReportsTracker_NextReportVersion_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PendingReports provides a mock function for the type MockReportsTracker
+func (_mock *MockReportsTracker) PendingReports(ctx context.Context, source string, fileName string) ([]*domain.Report, error) {
+	ret := _mock.Called(ctx, source, fileName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PendingReports")
+	}
+
+	var r0 []*domain.Report
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) ([]*domain.Report, error)); ok {
+		return returnFunc(ctx, source, fileName)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) []*domain.Report); ok {
+		r0 = returnFunc(ctx, source, fileName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Report)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, source, fileName)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockReportsTracker_PendingReports_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PendingReports'
+type MockReportsTracker_PendingReports_Call struct {
+	*mock.Call
+}
+
+// PendingReports is a helper method to define mock.On call
+//   - ctx context.Context
+//   - source string
+//   - fileName string
+func (_e *MockReportsTracker_Expecter) PendingReports(ctx interface{}, source interface{}, fileName interface{}) *MockReportsTracker_PendingReports_Call {
+	return &MockReportsTracker_PendingReports_Call{Call: _e.mock.On("PendingReports", ctx, source, fileName)}
+}
+
+func (_c *MockReportsTracker_PendingReports_Call) Run(run func(ctx context.Context, source string, fileName string)) *MockReportsTracker_PendingReports_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockReportsTracker_PendingReports_Call) Return(reports []*domain.Report, err error) *MockReportsTracker_PendingReports_Call {
+	_c.Call.Return(reports, err)
+	return _c
+}
+
+func (_c *MockReportsTracker_PendingReports_Call) RunAndReturn(run func(ctx context.Context, source string, fileName string) ([]*domain.Report, error)) *MockReportsTracker_PendingReports_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateReport provides a mock function for the type MockReportsTracker
+func (_mock *MockReportsTracker) UpdateReport(ctx context.Context, report *domain.Report) error {
+	ret := _mock.Called(ctx, report)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateReport")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *domain.Report) error); ok {
+		r0 = returnFunc(ctx, report)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockReportsTracker_UpdateReport_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateReport'
+type MockReportsTracker_UpdateReport_Call struct {
+	*mock.Call
+}
+
+// UpdateReport is a helper method to define mock.On call
+//   - ctx context.Context
+//   - report *domain.Report
+func (_e *MockReportsTracker_Expecter) UpdateReport(ctx interface{}, report interface{}) *MockReportsTracker_UpdateReport_Call {
+	return &MockReportsTracker_UpdateReport_Call{Call: _e.mock.On("UpdateReport", ctx, report)}
+}
+
+func (_c *MockReportsTracker_UpdateReport_Call) Run(run func(ctx context.Context, report *domain.Report)) *MockReportsTracker_UpdateReport_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *domain.Report
+		if args[1] != nil {
+			arg1 = args[1].(*domain.Report)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockReportsTracker_UpdateReport_Call) Return(err error) *MockReportsTracker_UpdateReport_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockReportsTracker_UpdateReport_Call) RunAndReturn(run func(ctx context.Context, report *domain.Report) error) *MockReportsTracker_UpdateReport_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockReportJobs creates a new instance of MockReportJobs. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockReportJobs(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockReportJobs {
+	mock := &MockReportJobs{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockReportJobs is an autogenerated mock type for the ReportJobs type
+type MockReportJobs struct {
+	mock.Mock
+}
+
+type MockReportJobs_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockReportJobs) EXPECT() *MockReportJobs_Expecter {
+	return &MockReportJobs_Expecter{mock: &_m.Mock}
+}
+
+// AckReportJob provides a mock function for the type MockReportJobs
+func (_mock *MockReportJobs) AckReportJob(ctx context.Context, id int64) error {
+	ret := _mock.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AckReportJob")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = returnFunc(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockReportJobs_AckReportJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AckReportJob'
+type MockReportJobs_AckReportJob_Call struct {
+	*mock.Call
+}
+
+// AckReportJob is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id int64
+func (_e *MockReportJobs_Expecter) AckReportJob(ctx interface{}, id interface{}) *MockReportJobs_AckReportJob_Call {
+	return &MockReportJobs_AckReportJob_Call{Call: _e.mock.On("AckReportJob", ctx, id)}
+}
+
+func (_c *MockReportJobs_AckReportJob_Call) Run(run func(ctx context.Context, id int64)) *MockReportJobs_AckReportJob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 int64
+		if args[1] != nil {
+			arg1 = args[1].(int64)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockReportJobs_AckReportJob_Call) Return(err error) *MockReportJobs_AckReportJob_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockReportJobs_AckReportJob_Call) RunAndReturn(run func(ctx context.Context, id int64) error) *MockReportJobs_AckReportJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DueReportJobs provides a mock function for the type MockReportJobs
+func (_mock *MockReportJobs) DueReportJobs(ctx context.Context, limit uint64, maxAttempts int) ([]*domain.ReportJob, error) {
+	ret := _mock.Called(ctx, limit, maxAttempts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DueReportJobs")
+	}
+
+	var r0 []*domain.ReportJob
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint64, int) ([]*domain.ReportJob, error)); ok {
+		return returnFunc(ctx, limit, maxAttempts)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint64, int) []*domain.ReportJob); ok {
+		r0 = returnFunc(ctx, limit, maxAttempts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.ReportJob)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uint64, int) error); ok {
+		r1 = returnFunc(ctx, limit, maxAttempts)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockReportJobs_DueReportJobs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DueReportJobs'
+type MockReportJobs_DueReportJobs_Call struct {
+	*mock.Call
+}
+
+// DueReportJobs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - limit uint64
+//   - maxAttempts int
+func (_e *MockReportJobs_Expecter) DueReportJobs(ctx interface{}, limit interface{}, maxAttempts interface{}) *MockReportJobs_DueReportJobs_Call {
+	return &MockReportJobs_DueReportJobs_Call{Call: _e.mock.On("DueReportJobs", ctx, limit, maxAttempts)}
+}
+
+func (_c *MockReportJobs_DueReportJobs_Call) Run(run func(ctx context.Context, limit uint64, maxAttempts int)) *MockReportJobs_DueReportJobs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint64
+		if args[1] != nil {
+			arg1 = args[1].(uint64)
+		}
+		var arg2 int
+		if args[2] != nil {
+			arg2 = args[2].(int)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockReportJobs_DueReportJobs_Call) Return(jobs []*domain.ReportJob, err error) *MockReportJobs_DueReportJobs_Call {
+	_c.Call.Return(jobs, err)
+	return _c
+}
+
+func (_c *MockReportJobs_DueReportJobs_Call) RunAndReturn(run func(ctx context.Context, limit uint64, maxAttempts int) ([]*domain.ReportJob, error)) *MockReportJobs_DueReportJobs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResetReportJobs provides a mock function for the type MockReportJobs
+func (_mock *MockReportJobs) ResetReportJobs(ctx context.Context) error {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetReportJobs")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockReportJobs_ResetReportJobs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResetReportJobs'
+type MockReportJobs_ResetReportJobs_Call struct {
+	*mock.Call
+}
+
+// ResetReportJobs is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockReportJobs_Expecter) ResetReportJobs(ctx interface{}) *MockReportJobs_ResetReportJobs_Call {
+	return &MockReportJobs_ResetReportJobs_Call{Call: _e.mock.On("ResetReportJobs", ctx)}
+}
+
+func (_c *MockReportJobs_ResetReportJobs_Call) Run(run func(ctx context.Context)) *MockReportJobs_ResetReportJobs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockReportJobs_ResetReportJobs_Call) Return(err error) *MockReportJobs_ResetReportJobs_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockReportJobs_ResetReportJobs_Call) RunAndReturn(run func(ctx context.Context) error) *MockReportJobs_ResetReportJobs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RetryReportJob provides a mock function for the type MockReportJobs
+func (_mock *MockReportJobs) RetryReportJob(ctx context.Context, id int64, retryAt time.Time, reason string) error {
+	ret := _mock.Called(ctx, id, retryAt, reason)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RetryReportJob")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, int64, time.Time, string) error); ok {
+		r0 = returnFunc(ctx, id, retryAt, reason)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockReportJobs_RetryReportJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RetryReportJob'
+type MockReportJobs_RetryReportJob_Call struct {
+	*mock.Call
+}
+
+// RetryReportJob is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id int64
+//   - retryAt time.Time
+//   - reason string
+func (_e *MockReportJobs_Expecter) RetryReportJob(ctx interface{}, id interface{}, retryAt interface{}, reason interface{}) *MockReportJobs_RetryReportJob_Call {
+	return &MockReportJobs_RetryReportJob_Call{Call: _e.mock.On("RetryReportJob", ctx, id, retryAt, reason)}
+}
+
+func (_c *MockReportJobs_RetryReportJob_Call) Run(run func(ctx context.Context, id int64, retryAt time.Time, reason string)) *MockReportJobs_RetryReportJob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 int64
+		if args[1] != nil {
+			arg1 = args[1].(int64)
+		}
+		var arg2 time.Time
+		if args[2] != nil {
+			arg2 = args[2].(time.Time)
+		}
+		var arg3 string
+		if args[3] != nil {
+			arg3 = args[3].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockReportJobs_RetryReportJob_Call) Return(err error) *MockReportJobs_RetryReportJob_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockReportJobs_RetryReportJob_Call) RunAndReturn(run func(ctx context.Context, id int64, retryAt time.Time, reason string) error) *MockReportJobs_RetryReportJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockFleetProvider creates a new instance of MockFleetProvider. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockFleetProvider(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockFleetProvider {
+	mock := &MockFleetProvider{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockFleetProvider is an autogenerated mock type for the FleetProvider type
+type MockFleetProvider struct {
+	mock.Mock
+}
+
+type MockFleetProvider_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockFleetProvider) EXPECT() *MockFleetProvider_Expecter {
+	return &MockFleetProvider_Expecter{mock: &_m.Mock}
+}
+
+// UnitsByFile provides a mock function for the type MockFleetProvider
+func (_mock *MockFleetProvider) UnitsByFile(ctx context.Context, source string, fileName string) ([]*domain.FleetUnit, error) {
+	ret := _mock.Called(ctx, source, fileName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnitsByFile")
+	}
+
+	var r0 []*domain.FleetUnit
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) ([]*domain.FleetUnit, error)); ok {
+		return returnFunc(ctx, source, fileName)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) []*domain.FleetUnit); ok {
+		r0 = returnFunc(ctx, source, fileName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.FleetUnit)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, source, fileName)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockFleetProvider_UnitsByFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UnitsByFile'
+type MockFleetProvider_UnitsByFile_Call struct {
+	*mock.Call
+}
+
+// UnitsByFile is a helper method to define mock.On call
+//   - ctx context.Context
+//   - source string
+//   - fileName string
+func (_e *MockFleetProvider_Expecter) UnitsByFile(ctx interface{}, source interface{}, fileName interface{}) *MockFleetProvider_UnitsByFile_Call {
+	return &MockFleetProvider_UnitsByFile_Call{Call: _e.mock.On("UnitsByFile", ctx, source, fileName)}
+}
+
+func (_c *MockFleetProvider_UnitsByFile_Call) Run(run func(ctx context.Context, source string, fileName string)) *MockFleetProvider_UnitsByFile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockFleetProvider_UnitsByFile_Call) Return(units []*domain.FleetUnit, err error) *MockFleetProvider_UnitsByFile_Call {
+	_c.Call.Return(units, err)
+	return _c
+}
+
+func (_c *MockFleetProvider_UnitsByFile_Call) RunAndReturn(run func(ctx context.Context, source string, fileName string) ([]*domain.FleetUnit, error)) *MockFleetProvider_UnitsByFile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UnitsByInvID provides a mock function for the type MockFleetProvider
+func (_mock *MockFleetProvider) UnitsByInvID(ctx context.Context, source string, invID string) ([]*domain.FleetUnit, error) {
+	ret := _mock.Called(ctx, source, invID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnitsByInvID")
+	}
+
+	var r0 []*domain.FleetUnit
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) ([]*domain.FleetUnit, error)); ok {
+		return returnFunc(ctx, source, invID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) []*domain.FleetUnit); ok {
+		r0 = returnFunc(ctx, source, invID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.FleetUnit)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, source, invID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockFleetProvider_UnitsByInvID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UnitsByInvID'
+type MockFleetProvider_UnitsByInvID_Call struct {
+	*mock.Call
+}
+
+// UnitsByInvID is a helper method to define mock.On call
+//   - ctx context.Context
+//   - source string
+//   - invID string
+func (_e *MockFleetProvider_Expecter) UnitsByInvID(ctx interface{}, source interface{}, invID interface{}) *MockFleetProvider_UnitsByInvID_Call {
+	return &MockFleetProvider_UnitsByInvID_Call{Call: _e.mock.On("UnitsByInvID", ctx, source, invID)}
+}
+
+func (_c *MockFleetProvider_UnitsByInvID_Call) Run(run func(ctx context.Context, source string, invID string)) *MockFleetProvider_UnitsByInvID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockFleetProvider_UnitsByInvID_Call) Return(units []*domain.FleetUnit, err error) *MockFleetProvider_UnitsByInvID_Call {
+	_c.Call.Return(units, err)
+	return _c
+}
+
+func (_c *MockFleetProvider_UnitsByInvID_Call) RunAndReturn(run func(ctx context.Context, source string, invID string) ([]*domain.FleetUnit, error)) *MockFleetProvider_UnitsByInvID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockReportGenerator creates a new instance of MockReportGenerator. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockReportGenerator(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockReportGenerator {
+	mock := &MockReportGenerator{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockReportGenerator is an autogenerated mock type for the ReportGenerator type
+type MockReportGenerator struct {
+	mock.Mock
+}
+
+type MockReportGenerator_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockReportGenerator) EXPECT() *MockReportGenerator_Expecter {
 	return &MockReportGenerator_Expecter{mock: &_m.Mock}
 }
 
+// Format provides a mock function for the type MockReportGenerator
+func (_mock *MockReportGenerator) Format() string {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Format")
+	}
+
+	var r0 string
+	if returnFunc, ok := ret.Get(0).(func() string); ok {
+		r0 = returnFunc()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+	return r0
+}
+
+// MockReportGenerator_Format_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Format'
+type MockReportGenerator_Format_Call struct {
+	*mock.Call
+}
+
+// Format is a helper method to define mock.On call
+func (_e *MockReportGenerator_Expecter) Format() *MockReportGenerator_Format_Call {
+	return &MockReportGenerator_Format_Call{Call: _e.mock.On("Format")}
+}
+
+func (_c *MockReportGenerator_Format_Call) Run(run func()) *MockReportGenerator_Format_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockReportGenerator_Format_Call) Return(format string) *MockReportGenerator_Format_Call {
+	_c.Call.Return(format)
+	return _c
+}
+
+func (_c *MockReportGenerator_Format_Call) RunAndReturn(run func() string) *MockReportGenerator_Format_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GenerateFleetReport provides a mock function for the type MockReportGenerator
+func (_mock *MockReportGenerator) GenerateFleetReport(outputPath string, style config.ReportStyle, fleet *domain.FleetReport) error {
+	ret := _mock.Called(outputPath, style, fleet)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GenerateFleetReport")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string, config.ReportStyle, *domain.FleetReport) error); ok {
+		r0 = returnFunc(outputPath, style, fleet)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockReportGenerator_GenerateFleetReport_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GenerateFleetReport'
+type MockReportGenerator_GenerateFleetReport_Call struct {
+	*mock.Call
+}
+
+// GenerateFleetReport is a helper method to define mock.On call
+//   - outputPath string
+//   - style config.ReportStyle
+//   - fleet *domain.FleetReport
+func (_e *MockReportGenerator_Expecter) GenerateFleetReport(outputPath interface{}, style interface{}, fleet interface{}) *MockReportGenerator_GenerateFleetReport_Call {
+	return &MockReportGenerator_GenerateFleetReport_Call{Call: _e.mock.On("GenerateFleetReport", outputPath, style, fleet)}
+}
+
+func (_c *MockReportGenerator_GenerateFleetReport_Call) Run(run func(outputPath string, style config.ReportStyle, fleet *domain.FleetReport)) *MockReportGenerator_GenerateFleetReport_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 config.ReportStyle
+		if args[1] != nil {
+			arg1 = args[1].(config.ReportStyle)
+		}
+		var arg2 *domain.FleetReport
+		if args[2] != nil {
+			arg2 = args[2].(*domain.FleetReport)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockReportGenerator_GenerateFleetReport_Call) Return(err error) *MockReportGenerator_GenerateFleetReport_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockReportGenerator_GenerateFleetReport_Call) RunAndReturn(run func(outputPath string, style config.ReportStyle, fleet *domain.FleetReport) error) *MockReportGenerator_GenerateFleetReport_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GenerateReport provides a mock function for the type MockReportGenerator
-func (_mock *MockReportGenerator) GenerateReport(outputPath string, unitGUID string, sourceFile string, devices []*domain.Device) error {
-	ret := _mock.Called(outputPath, unitGUID, sourceFile, devices)
+func (_mock *MockReportGenerator) GenerateReport(outputPath string, style config.ReportStyle, unitGUID string, sourceFile string, devices []*domain.Device) error {
+	ret := _mock.Called(outputPath, style, unitGUID, sourceFile, devices)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GenerateReport")
 	}
 
 	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(string, string, string, []*domain.Device) error); ok {
-		r0 = returnFunc(outputPath, unitGUID, sourceFile, devices)
+	if returnFunc, ok := ret.Get(0).(func(string, config.ReportStyle, string, string, []*domain.Device) error); ok {
+		r0 = returnFunc(outputPath, style, unitGUID, sourceFile, devices)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -412,36 +2085,42 @@ type MockReportGenerator_GenerateReport_Call struct {
 
 // GenerateReport is a helper method to define mock.On call
 //   - outputPath string
+//   - style config.ReportStyle
 //   - unitGUID string
 //   - sourceFile string
 //   - devices []*domain.Device
-func (_e *MockReportGenerator_Expecter) GenerateReport(outputPath interface{}, unitGUID interface{}, sourceFile interface{}, devices interface{}) *MockReportGenerator_GenerateReport_Call {
-	return &MockReportGenerator_GenerateReport_Call{Call: _e.mock.On("GenerateReport", outputPath, unitGUID, sourceFile, devices)}
+func (_e *MockReportGenerator_Expecter) GenerateReport(outputPath interface{}, style interface{}, unitGUID interface{}, sourceFile interface{}, devices interface{}) *MockReportGenerator_GenerateReport_Call {
+	return &MockReportGenerator_GenerateReport_Call{Call: _e.mock.On("GenerateReport", outputPath, style, unitGUID, sourceFile, devices)}
 }
 
-func (_c *MockReportGenerator_GenerateReport_Call) Run(run func(outputPath string, unitGUID string, sourceFile string, devices []*domain.Device)) *MockReportGenerator_GenerateReport_Call {
+func (_c *MockReportGenerator_GenerateReport_Call) Run(run func(outputPath string, style config.ReportStyle, unitGUID string, sourceFile string, devices []*domain.Device)) *MockReportGenerator_GenerateReport_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 string
 		if args[0] != nil {
 			arg0 = args[0].(string)
 		}
-		var arg1 string
+		var arg1 config.ReportStyle
 		if args[1] != nil {
-			arg1 = args[1].(string)
+			arg1 = args[1].(config.ReportStyle)
 		}
 		var arg2 string
 		if args[2] != nil {
 			arg2 = args[2].(string)
 		}
-		var arg3 []*domain.Device
+		var arg3 string
 		if args[3] != nil {
-			arg3 = args[3].([]*domain.Device)
+			arg3 = args[3].(string)
+		}
+		var arg4 []*domain.Device
+		if args[4] != nil {
+			arg4 = args[4].([]*domain.Device)
 		}
 		run(
 			arg0,
 			arg1,
 			arg2,
 			arg3,
+			arg4,
 		)
 	})
 	return _c
@@ -452,7 +2131,85 @@ func (_c *MockReportGenerator_GenerateReport_Call) Return(err error) *MockReport
 	return _c
 }
 
-func (_c *MockReportGenerator_GenerateReport_Call) RunAndReturn(run func(outputPath string, unitGUID string, sourceFile string, devices []*domain.Device) error) *MockReportGenerator_GenerateReport_Call {
+func (_c *MockReportGenerator_GenerateReport_Call) RunAndReturn(run func(outputPath string, style config.ReportStyle, unitGUID string, sourceFile string, devices []*domain.Device) error) *MockReportGenerator_GenerateReport_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockFileArchiver creates a new instance of MockFileArchiver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockFileArchiver(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockFileArchiver {
+	mock := &MockFileArchiver{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockFileArchiver is an autogenerated mock type for the FileArchiver type
+type MockFileArchiver struct {
+	mock.Mock
+}
+
+type MockFileArchiver_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockFileArchiver) EXPECT() *MockFileArchiver_Expecter {
+	return &MockFileArchiver_Expecter{mock: &_m.Mock}
+}
+
+// Archive provides a mock function for the type MockFileArchiver
+func (_mock *MockFileArchiver) Archive(file *domain.File) error {
+	ret := _mock.Called(file)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Archive")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(*domain.File) error); ok {
+		r0 = returnFunc(file)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockFileArchiver_Archive_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Archive'
+type MockFileArchiver_Archive_Call struct {
+	*mock.Call
+}
+
+// Archive is a helper method to define mock.On call
+//   - file *domain.File
+func (_e *MockFileArchiver_Expecter) Archive(file interface{}) *MockFileArchiver_Archive_Call {
+	return &MockFileArchiver_Archive_Call{Call: _e.mock.On("Archive", file)}
+}
+
+func (_c *MockFileArchiver_Archive_Call) Run(run func(file *domain.File)) *MockFileArchiver_Archive_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 *domain.File
+		if args[0] != nil {
+			arg0 = args[0].(*domain.File)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockFileArchiver_Archive_Call) Return(err error) *MockFileArchiver_Archive_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockFileArchiver_Archive_Call) RunAndReturn(run func(file *domain.File) error) *MockFileArchiver_Archive_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -1,53 +1,112 @@
 package pipeline
 
 import (
+	"bufio"
 	"context"
 	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"os"
+	"path"
+	"slices"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/jszwec/csvutil"
+	"github.com/kurochkinivan/device_reporter/internal/config"
 	"github.com/kurochkinivan/device_reporter/internal/domain"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// sniffSize is how many leading bytes formats get to recognise a file by content.
+	sniffSize = 512
+	// encodingSniffSize is larger: the header is usually ASCII, so the encoding
+	// only shows in the rows below it.
+	encodingSniffSize = 64 << 10
+	// maxRowErrors bounds the row errors kept for one file, so a file in a wrong format
+	// does not produce an error per cell.
+	maxRowErrors = 10000
+	// chunkSize is how many devices are sent to the writer at once.
+	chunkSize = 1000
+	// chunksBuffer lets the parser decode the next chunk while the writer copies the previous one.
+	chunksBuffer = 1
 )
 
 type Parser struct {
 	log          *slog.Logger
-	files        <-chan string
+	workers      int
+	settings     map[string]config.Parser // по имени источника
+	formats      []Format
+	files        <-chan *domain.File
 	parseResults chan<- *domain.ParseResult
 }
 
-func NewParser(log *slog.Logger, files <-chan string, parseResults chan<- *domain.ParseResult) *Parser {
+func NewParser(
+	log *slog.Logger,
+	workers int,
+	settings map[string]config.Parser,
+	formats []Format,
+	files <-chan *domain.File,
+	parseResults chan<- *domain.ParseResult,
+) *Parser {
 	return &Parser{
 		log:          log,
+		workers:      max(workers, 1),
+		settings:     settings,
+		formats:      formats,
 		files:        files,
 		parseResults: parseResults,
 	}
 }
 
+// Run parses files in the configured number of workers. Each file is parsed by one worker
+// from start to end, and parseResults is closed once every worker has stopped.
 func (p *Parser) Run(ctx context.Context) error {
 	defer close(p.parseResults)
 
+	erg, ctx := errgroup.WithContext(ctx)
+	for i := range p.workers {
+		erg.Go(func() error {
+			return p.work(ctx, p.log.With(slog.Int("worker", i)))
+		})
+	}
+
+	return erg.Wait()
+}
+
+func (p *Parser) work(ctx context.Context, log *slog.Logger) error {
 	for {
 		select {
-		case filename, ok := <-p.files:
+		case file, ok := <-p.files:
 			if !ok {
 				return nil
 			}
 
-			p.log.DebugContext(ctx, "received file to parse", slog.String("filename", filename))
+			log.DebugContext(ctx, "received file to parse", slog.String("filename", file.Path))
+
+			chunks := make(chan []*domain.Device, chunksBuffer)
+			result := &domain.ParseResult{File: file, Chunks: chunks}
 
-			devices, err := p.parseRecordsFromFile(filename)
+			// результат отправляется сразу, устройства идут следом по мере разбора
+			select {
+			case p.parseResults <- result:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			err := p.parseRecordsFromFile(ctx, log, file, result, chunks)
 			if err != nil {
-				p.log.ErrorContext(ctx, "failed to parse records", slog.String("err", err.Error()))
+				log.ErrorContext(ctx, "failed to parse records", slog.String("err", err.Error()))
 			}
 
-			p.parseResults <- &domain.ParseResult{
-				Filename: filename,
-				Devices:  devices,
-				Error:    err,
+			// итог должен быть записан до закрытия канала: Writer читает его после
+			result.Error = err
+			close(chunks)
+
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
 
 		case <-ctx.Done():
@@ -56,28 +115,123 @@ func (p *Parser) Run(ctx context.Context) error {
 	}
 }
 
-func (p *Parser) parseRecordsFromFile(filename string) (_ []*domain.Device, err error) {
-	f, err := os.Open(filename)
+func (p *Parser) parseRecordsFromFile(
+	ctx context.Context,
+	log *slog.Logger,
+	file *domain.File,
+	result *domain.ParseResult,
+	chunks chan<- []*domain.Device,
+) (err error) {
+	f, err := openFile(file)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer func() { err = errors.Join(err, f.Close()) }()
 
-	return p.parseRecords(f)
+	name := file.Name
+	if file.Member != "" {
+		name = file.Member
+	}
+
+	return p.parseRecords(ctx, log, f, name, p.settings[file.Source], result, chunks)
 }
 
-func (p *Parser) parseRecords(r io.Reader) ([]*domain.Device, error) {
-	reader := csv.NewReader(r)
-	reader.Comma = '\t'
+// parseRecords sends the devices to chunks and fills the row errors of the result.
+func (p *Parser) parseRecords(
+	ctx context.Context,
+	log *slog.Logger,
+	r io.Reader,
+	name string,
+	settings config.Parser,
+	result *domain.ParseResult,
+	chunks chan<- []*domain.Device,
+) (err error) {
+	br := bufio.NewReaderSize(r, encodingSniffSize)
+
+	// ошибку Peek не проверяем: короткий файл просто даст меньше байт для распознавания
+	head, _ := br.Peek(encodingSniffSize)
+
+	format, err := p.detectFormat(name, head[:min(len(head), sniffSize)])
+	if err != nil {
+		return err
+	}
+
+	log.Debug("detected input format", slog.String("format", format.Name()))
+
+	var input io.Reader = br
+	if !format.Binary() {
+		encoding := detectEncoding(settings.Encoding, head)
+		log.Debug("detected input encoding", slog.String("encoding", string(encoding)))
+
+		input = decodeText(br, encoding)
+	}
+
+	reader, err := format.NewReader(input, settings)
+	if err != nil {
+		return fmt.Errorf("failed to open %s reader: %w", format.Name(), err)
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer func() { err = errors.Join(err, closer.Close()) }()
+	}
+
+	lines, ok := reader.(lineReader)
+	if !ok {
+		return fmt.Errorf("%s reader does not report line numbers", format.Name())
+	}
+
+	mapper := newColumnMapper(reader, settings.Columns)
 
-	dec, err := csvutil.NewDecoder(reader)
+	dec, err := csvutil.NewDecoder(mapper)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create decoder: %w", err)
+		return fmt.Errorf("failed to create decoder: %w", err)
 	}
 
-	p.log.Debug("parsing records")
+	rules, err := newRuleChecker(settings.Rules)
+	if err != nil {
+		return fmt.Errorf("failed to compile validation rules: %w", err)
+	}
+
+	log.Debug("parsing records")
+
+	acceptValid := settings.RowPolicy == config.RowPolicyAcceptValid
+	collect := settings.CollectRowErrors || acceptValid
+
+	var (
+		chunk        = make([]*domain.Device, 0, chunkSize)
+		total        int
+		rowErrors    []*domain.RowError
+		rejectedRows []*domain.RejectedRow
+	)
+
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+
+		// при отказе от файла целиком устройства после первой ошибки уже не нужны
+		if len(rowErrors) == 0 || acceptValid {
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		chunk = make([]*domain.Device, 0, chunkSize)
+		return nil
+	}
+
+	add := func(device *domain.Device) error {
+		chunk = append(chunk, device)
+		total++
+
+		if len(chunk) < chunkSize {
+			return nil
+		}
+
+		return flush()
+	}
 
-	var devices []*domain.Device
 	for {
 		var device domain.Device
 
@@ -86,18 +240,155 @@ func (p *Parser) parseRecords(r io.Reader) ([]*domain.Device, error) {
 			break
 		}
 
+		// номер строки знает только формат: в JSONL первый объект — и заголовок, и первая запись
+		line := lines.Line()
+
+		// байты, не ставшие текстом, нельзя сохранить в базу
+		invalidText := encodingErrors(dec.Header(), mapper.Last())
+
+		if !collect {
+			if err != nil {
+				return fmt.Errorf("failed to decode device record: %w", err)
+			}
+
+			if len(invalidText) > 0 {
+				return fmt.Errorf("invalid device record #%d: %s", total+1, invalidText[0].Message())
+			}
+
+			if err := device.Validate(); err != nil {
+				return fmt.Errorf("invalid device record #%d: %w", total+1, err)
+			}
+
+			if violations := rules.Check(line, dec.Header(), dec.Record()); len(violations) > 0 {
+				return fmt.Errorf("invalid device record #%d: %s", total+1, violations[0].Message())
+			}
+
+			if err := add(&device); err != nil {
+				return err
+			}
+			continue
+		}
+
+		decodeErr := err
+
+		violations, err := rowErrorsOf(dec, &device, decodeErr)
 		if err != nil {
-			return devices, fmt.Errorf("failed to decode device record: %w", err)
+			return fmt.Errorf("failed to decode device record: %w", err)
+		}
+		violations = append(invalidText, violations...)
+
+		// правила проверяют только строки, которые удалось разобрать
+		if decodeErr == nil {
+			violations = append(violations, rules.Check(line, dec.Header(), dec.Record())...)
+		}
+
+		if len(violations) == 0 {
+			if err := add(&device); err != nil {
+				return err
+			}
+			continue
+		}
+
+		reasons := make([]string, 0, len(violations))
+		for _, v := range violations {
+			v.Line = line
+			reasons = append(reasons, v.Message())
+		}
+		rowErrors = append(rowErrors, violations...)
+
+		rejectedRows = append(rejectedRows, &domain.RejectedRow{
+			Line:   line,
+			Raw:    strings.ToValidUTF8(strings.Join(mapper.Last(), "\t"), string(utf8.RuneError)),
+			Reason: strings.Join(reasons, "; "),
+		})
+
+		if len(rowErrors) >= maxRowErrors {
+			result.RowErrors = rowErrors[:maxRowErrors]
+			return fmt.Errorf("stopped at line %d after %d row errors", line, maxRowErrors)
+		}
+	}
+
+	if len(rowErrors) > 0 {
+		result.RowErrors = rowErrors
+
+		if !acceptValid {
+			return fmt.Errorf("found %d row errors, first at %s", len(rowErrors), rowErrors[0])
+		}
+
+		result.RejectedRows = rejectedRows
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	log.Debug("successfully parsed records",
+		slog.Int("device_count", total),
+		slog.Int("rejected_count", len(rejectedRows)),
+	)
+
+	return nil
+}
+
+// detectFormat picks the format by extension first and falls back to sniffing the content,
+// in the order the formats were registered.
+func (p *Parser) detectFormat(name string, head []byte) (Format, error) {
+	ext := strings.ToLower(path.Ext(strings.TrimSuffix(name, ".gz")))
+
+	for _, format := range p.formats {
+		for _, e := range format.Extensions() {
+			if e == ext {
+				return format, nil
+			}
 		}
+	}
 
-		if err := device.Validate(); err != nil {
-			return nil, fmt.Errorf("invalid device record #%d: %w", len(devices)+1, err)
+	for _, format := range p.formats {
+		if format.Sniff(head) {
+			return format, nil
 		}
+	}
 
-		devices = append(devices, &device)
+	return nil, fmt.Errorf("unknown input format of %q", name)
+}
+
+// rowErrorsOf turns the outcome of decoding one row into row errors. Errors that
+// do not belong to a single row, such as a failed read, are returned as is.
+func rowErrorsOf(dec *csvutil.Decoder, device *domain.Device, err error) ([]*domain.RowError, error) {
+	if err == nil {
+		return device.Violations(), nil
 	}
 
-	p.log.Debug("successfully parsed records", slog.Int("device_count", len(devices)))
+	var (
+		parseErr  *csv.ParseError
+		decodeErr *csvutil.DecodeError
+		typeErr   *csvutil.UnmarshalTypeError
+	)
+
+	switch {
+	case errors.As(err, &parseErr):
+		return []*domain.RowError{{Reason: parseErr.Err.Error()}}, nil
+
+	case errors.Is(err, csvutil.ErrFieldCount):
+		return []*domain.RowError{{Reason: err.Error()}}, nil
+
+	case errors.As(err, &decodeErr):
+		rowErr := &domain.RowError{Column: decodeErr.Field, Reason: decodeErr.Err.Error()}
+
+		if i := slices.Index(dec.Header(), decodeErr.Field); i >= 0 && i < len(dec.Record()) {
+			rowErr.Value = dec.Record()[i]
+		}
+
+		if errors.As(err, &typeErr) {
+			rowErr.Value = typeErr.Value
+			rowErr.Reason = "is not a valid " + typeErr.Type.String()
+		}
+
+		rowErr.Value = strings.ToValidUTF8(rowErr.Value, string(utf8.RuneError))
 
-	return devices, nil
+		return []*domain.RowError{rowErr}, nil
+
+	default:
+		return nil, err
+	}
 }
@@ -1,17 +1,28 @@
 package pipeline_test
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/kurochkinivan/device_reporter/internal/config"
 	"github.com/kurochkinivan/device_reporter/internal/domain"
+	"github.com/kurochkinivan/device_reporter/internal/infrastructure/formats"
 	"github.com/kurochkinivan/device_reporter/internal/pipeline"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
 )
 
 func TestParser_Run_HappyPath(t *testing.T) {
@@ -38,14 +49,14 @@ func TestParser_Run_HappyPath(t *testing.T) {
 	}
 
 	filename := createTSV(t, expected)
-	files := make(chan string, 1)
+	files := make(chan *domain.File, 1)
 	go func() {
-		files <- filename
+		files <- &domain.File{Name: filepath.Base(filename), Path: filename}
 	}()
 
 	parseResults := make(chan *domain.ParseResult, 1)
 
-	parser := pipeline.NewParser(log, files, parseResults)
+	parser := pipeline.NewParser(log, 1, nil, inputFormats(), files, parseResults)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -58,8 +69,9 @@ func TestParser_Run_HappyPath(t *testing.T) {
 	select {
 	case result := <-parseResults:
 		require.NotNil(t, result)
-		assert.Len(t, result.Devices, 1)
-		assert.Equal(t, expected, result.Devices[0])
+		devices := collectDevices(result)
+		assert.Len(t, devices, 1)
+		assert.Equal(t, expected, devices[0])
 	case err := <-errChan:
 		t.Fatalf("unexpected error: %v", err)
 	case <-time.After(10 * time.Millisecond):
@@ -82,14 +94,14 @@ func TestParser_Run_InvalidData(t *testing.T) {
 	log := slog.New(slog.DiscardHandler)
 
 	filename := createInvalidTSV(t)
-	files := make(chan string, 1)
+	files := make(chan *domain.File, 1)
 	go func() {
-		files <- filename
+		files <- &domain.File{Name: filepath.Base(filename), Path: filename}
 	}()
 
 	parseResults := make(chan *domain.ParseResult, 1)
 
-	parser := pipeline.NewParser(log, files, parseResults)
+	parser := pipeline.NewParser(log, 1, nil, inputFormats(), files, parseResults)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -102,6 +114,7 @@ func TestParser_Run_InvalidData(t *testing.T) {
 	select {
 	case result := <-parseResults:
 		require.NotNil(t, result)
+		collectDevices(result)
 		require.Error(t, result.Error)
 
 	case err := <-errChan:
@@ -127,14 +140,14 @@ func TestParser_Run_EmptyFile(t *testing.T) {
 	log := slog.New(slog.DiscardHandler)
 
 	filename := createEmptyTSV(t)
-	files := make(chan string, 1)
+	files := make(chan *domain.File, 1)
 	go func() {
-		files <- filename
+		files <- &domain.File{Name: filepath.Base(filename), Path: filename}
 	}()
 
 	parseResults := make(chan *domain.ParseResult, 1)
 
-	parser := pipeline.NewParser(log, files, parseResults)
+	parser := pipeline.NewParser(log, 1, nil, inputFormats(), files, parseResults)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -147,8 +160,9 @@ func TestParser_Run_EmptyFile(t *testing.T) {
 	select {
 	case result := <-parseResults:
 		require.NotNil(t, result)
+		devices := collectDevices(result)
 		require.NoError(t, result.Error)
-		assert.Empty(t, result.Devices)
+		assert.Empty(t, devices)
 	case err := <-errChan:
 		t.Fatalf("unexpected error: %v", err)
 	case <-time.After(10 * time.Millisecond):
@@ -165,6 +179,781 @@ func TestParser_Run_EmptyFile(t *testing.T) {
 	}
 }
 
+func TestParser_Run_CompressedInput(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	expected := &domain.Device{
+		N:        1,
+		InvID:    "G-044322",
+		UnitGUID: "01749246-95f6-57db-b7c3-2ae0e8be671f",
+		MsgID:    "cold7_Defrost_status",
+		Text:     "Разморозка",
+		Class:    "waiting",
+		Level:    100,
+		Area:     "LOCAL",
+		Addr:     "cold7_status.Defrost_status",
+	}
+
+	content, err := os.ReadFile(createTSV(t, expected))
+	require.NoError(t, err)
+
+	gz := createGzip(t, content)
+	bundle := createZip(t, map[string][]byte{"site/unit7.tsv": content})
+
+	tests := []struct {
+		name string
+		file *domain.File
+	}{
+		{
+			name: "gzip",
+			file: &domain.File{Name: filepath.Base(gz), Path: gz},
+		},
+		{
+			name: "zip member",
+			file: &domain.File{Name: filepath.Base(bundle) + "!/site/unit7.tsv", Path: bundle, Member: "site/unit7.tsv"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			files := make(chan *domain.File, 1)
+			files <- tt.file
+			close(files)
+
+			parseResults := make(chan *domain.ParseResult, 1)
+
+			parser := pipeline.NewParser(log, 1, nil, inputFormats(), files, parseResults)
+			require.NoError(t, parser.Run(context.Background()))
+
+			result := <-parseResults
+			require.NotNil(t, result)
+			devices := collectDevices(result)
+			require.NoError(t, result.Error)
+			assert.Len(t, devices, 1)
+			assert.Equal(t, expected, devices[0])
+		})
+	}
+}
+
+func TestParser_Run_SourceDelimiter(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	expected := &domain.Device{
+		N:        1,
+		InvID:    "G-044322",
+		UnitGUID: "01749246-95f6-57db-b7c3-2ae0e8be671f",
+		MsgID:    "cold7_Defrost_status",
+		Class:    "waiting",
+		Level:    100,
+	}
+
+	// Источник присылает файлы с разделителем ';'
+	content, err := os.ReadFile(createTSV(t, expected))
+	require.NoError(t, err)
+
+	filename := filepath.Join(t.TempDir(), "plant.csv")
+	require.NoError(t, os.WriteFile(filename, bytes.ReplaceAll(content, []byte("\t"), []byte(";")), 0o644))
+
+	files := make(chan *domain.File, 1)
+	files <- &domain.File{Source: "plant-b", Name: "plant.csv", Path: filename}
+	close(files)
+
+	parseResults := make(chan *domain.ParseResult, 1)
+
+	settings := map[string]config.Parser{"plant-b": {Delimiter: ';'}}
+	parser := pipeline.NewParser(log, 1, settings, inputFormats(), files, parseResults)
+	require.NoError(t, parser.Run(context.Background()))
+
+	result := <-parseResults
+	require.NotNil(t, result)
+	devices := collectDevices(result)
+	require.NoError(t, result.Error)
+	require.Len(t, devices, 1)
+	assert.Equal(t, expected, devices[0])
+}
+
+func TestParser_Run_Formats(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	expected := &domain.Device{
+		N:        1,
+		InvID:    "G-044322",
+		UnitGUID: "01749246-95f6-57db-b7c3-2ae0e8be671f",
+		MsgID:    "cold7_Defrost_status",
+		Text:     "Разморозка",
+		Class:    "waiting",
+		Level:    100,
+		Area:     "LOCAL",
+	}
+
+	tsv, err := os.ReadFile(createTSV(t, expected))
+	require.NoError(t, err)
+
+	jsonl := `{"n": 1, "invid": "G-044322", "unit_guid": "01749246-95f6-57db-b7c3-2ae0e8be671f", ` +
+		`"msg_id": "cold7_Defrost_status", "text": "Разморозка", "class": "waiting", "level": 100, "area": "LOCAL"}` + "\n"
+
+	tests := []struct {
+		name     string
+		filename string
+		content  []byte
+	}{
+		{
+			name:     "semicolon csv",
+			filename: "data.csv",
+			content:  bytes.ReplaceAll(tsv, []byte("\t"), []byte(";")),
+		},
+		{
+			name:     "comma csv",
+			filename: "data.csv",
+			content:  bytes.ReplaceAll(tsv, []byte("\t"), []byte(",")),
+		},
+		{
+			name:     "jsonl",
+			filename: "data.jsonl",
+			content:  []byte(jsonl),
+		},
+		{
+			name:     "jsonl sniffed",
+			filename: "data.log",
+			content:  []byte(jsonl),
+		},
+		{
+			name:     "xlsx",
+			filename: "data.xlsx",
+			content:  createXLSX(t, tsv),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			filename := filepath.Join(t.TempDir(), tt.filename)
+			require.NoError(t, os.WriteFile(filename, tt.content, 0o644))
+
+			files := make(chan *domain.File, 1)
+			files <- &domain.File{Name: tt.filename, Path: filename}
+			close(files)
+
+			parseResults := make(chan *domain.ParseResult, 1)
+
+			parser := pipeline.NewParser(log, 1, nil, inputFormats(), files, parseResults)
+			require.NoError(t, parser.Run(context.Background()))
+
+			result := <-parseResults
+			require.NotNil(t, result)
+			devices := collectDevices(result)
+			require.NoError(t, result.Error)
+			require.Len(t, devices, 1)
+			assert.Equal(t, expected, devices[0])
+		})
+	}
+}
+
+func TestParser_Run_ColumnMapping(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	columns := map[string]config.Column{
+		"n":         {Aliases: []string{"№", "Номер"}, Required: true},
+		"unit_guid": {Aliases: []string{"GUID устройства"}, Required: true},
+		"class":     {Aliases: []string{"Класс"}, Required: true},
+		"area":      {Default: "LOCAL"},
+		"level":     {Aliases: []string{"Уровень"}},
+	}
+
+	expected := &domain.Device{
+		N:        1,
+		UnitGUID: "01749246-95f6-57db-b7c3-2ae0e8be671f",
+		Class:    "waiting",
+		Level:    100,
+		Area:     "LOCAL",
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "aliases",
+			content: "№\tGUID устройства\tКласс\tУровень\n" +
+				"1\t01749246-95f6-57db-b7c3-2ae0e8be671f\twaiting\t100\n",
+		},
+		{
+			// Регистр и пробелы вокруг заголовков не важны
+			name: "case insensitive",
+			content: " НОМЕР \tUnit_GUID\tCLASS\tlevel\n" +
+				"1\t01749246-95f6-57db-b7c3-2ae0e8be671f\twaiting\t100\n",
+		},
+		{
+			name: "missing required",
+			content: "№\tУровень\n" +
+				"1\t100\n",
+			wantErr: "missing required columns: class, unit_guid",
+		},
+		{
+			name: "ambiguous",
+			content: "№\tn\tGUID устройства\tКласс\n" +
+				"1\t1\t01749246-95f6-57db-b7c3-2ae0e8be671f\twaiting\n",
+			wantErr: `both map to "n"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			filename := filepath.Join(t.TempDir(), "data.tsv")
+			require.NoError(t, os.WriteFile(filename, []byte(tt.content), 0o644))
+
+			files := make(chan *domain.File, 1)
+			files <- &domain.File{Source: "plant-c", Name: "data.tsv", Path: filename}
+			close(files)
+
+			parseResults := make(chan *domain.ParseResult, 1)
+
+			settings := map[string]config.Parser{"plant-c": {Delimiter: '\t', Columns: columns}}
+			parser := pipeline.NewParser(log, 1, settings, inputFormats(), files, parseResults)
+			require.NoError(t, parser.Run(context.Background()))
+
+			result := <-parseResults
+			require.NotNil(t, result)
+			devices := collectDevices(result)
+
+			if tt.wantErr != "" {
+				require.Error(t, result.Error)
+				assert.Contains(t, result.Error.Error(), tt.wantErr)
+				return
+			}
+
+			require.NoError(t, result.Error)
+			require.Len(t, devices, 1)
+			assert.Equal(t, expected, devices[0])
+		})
+	}
+}
+
+func TestParser_Run_CollectRowErrors(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	// Плохие строки не прерывают разбор: собираются все ошибки файла
+	content := "n\tunit_guid\tclass\tlevel\n" +
+		"1\t01749246-95f6-57db-b7c3-2ae0e8be671f\twaiting\t100\n" +
+		"2\t\t\t100\n" +
+		"3\t01749246-95f6-57db-b7c3-2ae0e8be671f\twaiting\thigh\n" +
+		"4\t01749246-95f6-57db-b7c3-2ae0e8be671f\n" +
+		"5\t01749246-95f6-57db-b7c3-2ae0e8be671f\talarm\t50\n"
+
+	filename := filepath.Join(t.TempDir(), "data.tsv")
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+
+	files := make(chan *domain.File, 1)
+	files <- &domain.File{Source: "plant-a", Name: "data.tsv", Path: filename}
+	close(files)
+
+	parseResults := make(chan *domain.ParseResult, 1)
+
+	settings := map[string]config.Parser{"plant-a": {Delimiter: '\t', CollectRowErrors: true}}
+	parser := pipeline.NewParser(log, 1, settings, inputFormats(), files, parseResults)
+	require.NoError(t, parser.Run(context.Background()))
+
+	result := <-parseResults
+	require.NotNil(t, result)
+	devices := collectDevices(result)
+	require.Error(t, result.Error)
+	assert.Contains(t, result.Error.Error(), "found 4 row errors, first at line 3: unit_guid is required")
+	assert.Empty(t, devices)
+
+	expected := []*domain.RowError{
+		{Line: 3, Column: "unit_guid", Reason: "is required"},
+		{Line: 3, Column: "class", Reason: "is required"},
+		{Line: 4, Column: "level", Value: "high", Reason: "is not a valid int"},
+		{Line: 5, Reason: "wrong number of fields"},
+	}
+	assert.Equal(t, expected, result.RowErrors)
+}
+
+func TestParser_Run_RowErrorLines(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	guid := "01749246-95f6-57db-b7c3-2ae0e8be671f"
+
+	// номер строки ошибки — строка самого файла, а не порядковый номер записи
+	tests := []struct {
+		name     string
+		filename string
+		content  []byte
+		wantLine int
+	}{
+		{
+			name:     "csv with a multiline field",
+			filename: "data.tsv",
+			content: []byte("n\tunit_guid\tclass\ttext\n" +
+				"1\t" + guid + "\twaiting\t\"first\nsecond\"\n" +
+				"2\t\twaiting\tok\n"),
+			wantLine: 4,
+		},
+		{
+			// первый объект — одновременно заголовок и первая запись
+			name:     "jsonl with a blank line",
+			filename: "data.jsonl",
+			content: []byte(`{"n": 1, "unit_guid": "` + guid + `", "class": "waiting"}` + "\n" +
+				"\n" +
+				`{"n": 2, "unit_guid": "", "class": "waiting"}` + "\n"),
+			wantLine: 3,
+		},
+		{
+			name:     "xlsx",
+			filename: "data.xlsx",
+			content: createXLSX(t, []byte("n\tunit_guid\tclass\n"+
+				"1\t"+guid+"\twaiting\n"+
+				"2\t\twaiting\n")),
+			wantLine: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			filename := filepath.Join(t.TempDir(), tt.filename)
+			require.NoError(t, os.WriteFile(filename, tt.content, 0o644))
+
+			files := make(chan *domain.File, 1)
+			files <- &domain.File{Source: "plant-a", Name: tt.filename, Path: filename}
+			close(files)
+
+			parseResults := make(chan *domain.ParseResult, 1)
+
+			settings := map[string]config.Parser{"plant-a": {CollectRowErrors: true}}
+			parser := pipeline.NewParser(log, 1, settings, inputFormats(), files, parseResults)
+			require.NoError(t, parser.Run(context.Background()))
+
+			result := <-parseResults
+			require.NotNil(t, result)
+			collectDevices(result)
+			require.Error(t, result.Error)
+
+			expected := []*domain.RowError{{Line: tt.wantLine, Column: "unit_guid", Reason: "is required"}}
+			assert.Equal(t, expected, result.RowErrors)
+		})
+	}
+}
+
+func TestParser_Run_AcceptValidRows(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	content := "n\tunit_guid\tclass\tlevel\n" +
+		"1\t01749246-95f6-57db-b7c3-2ae0e8be671f\twaiting\t100\n" +
+		"2\t\t\t100\n" +
+		"3\t01749246-95f6-57db-b7c3-2ae0e8be671f\talarm\thigh\n" +
+		"4\t01749246-95f6-57db-b7c3-2ae0e8be671f\talarm\t50\n"
+
+	filename := filepath.Join(t.TempDir(), "data.tsv")
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+
+	files := make(chan *domain.File, 1)
+	files <- &domain.File{Source: "plant-a", Name: "data.tsv", Path: filename}
+	close(files)
+
+	parseResults := make(chan *domain.ParseResult, 1)
+
+	settings := map[string]config.Parser{"plant-a": {Delimiter: '\t', RowPolicy: config.RowPolicyAcceptValid}}
+	parser := pipeline.NewParser(log, 1, settings, inputFormats(), files, parseResults)
+	require.NoError(t, parser.Run(context.Background()))
+
+	result := <-parseResults
+	require.NotNil(t, result)
+	devices := collectDevices(result)
+	require.NoError(t, result.Error)
+
+	require.Len(t, devices, 2)
+	assert.Equal(t, 1, devices[0].N)
+	assert.Equal(t, 4, devices[1].N)
+
+	expected := []*domain.RejectedRow{
+		{Line: 3, Raw: "2\t\t\t100", Reason: "unit_guid is required; class is required"},
+		{Line: 4, Raw: "3\t01749246-95f6-57db-b7c3-2ae0e8be671f\talarm\thigh", Reason: "level is not a valid int"},
+	}
+	assert.Equal(t, expected, result.RejectedRows)
+	assert.Len(t, result.RowErrors, 3)
+}
+
+func TestParser_Run_JSONLUnknownKeys(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	guid := "01749246-95f6-57db-b7c3-2ae0e8be671f"
+
+	// заголовок берётся из первого объекта, ключ не из него делает строку ошибочной
+	content := `{"n": 1, "unit_guid": "` + guid + `", "class": "waiting"}` + "\n" +
+		`{"n": 2, "unit_guid": "` + guid + `", "class": "alarm", "zone": "A", "area": "LOCAL"}` + "\n" +
+		`{"n": 3, "unit_guid": "` + guid + `", "class": "working"}` + "\n"
+
+	filename := filepath.Join(t.TempDir(), "data.jsonl")
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+
+	files := make(chan *domain.File, 1)
+	files <- &domain.File{Source: "plant-a", Name: "data.jsonl", Path: filename}
+	close(files)
+
+	parseResults := make(chan *domain.ParseResult, 1)
+
+	settings := map[string]config.Parser{"plant-a": {RowPolicy: config.RowPolicyAcceptValid}}
+	parser := pipeline.NewParser(log, 1, settings, inputFormats(), files, parseResults)
+	require.NoError(t, parser.Run(context.Background()))
+
+	result := <-parseResults
+	require.NotNil(t, result)
+	devices := collectDevices(result)
+	require.NoError(t, result.Error)
+
+	require.Len(t, devices, 2)
+	assert.Equal(t, 1, devices[0].N)
+	assert.Equal(t, 3, devices[1].N)
+
+	expected := []*domain.RejectedRow{
+		{Line: 2, Raw: "alarm\t2\t" + guid, Reason: "unknown keys area, zone, the header is taken from the first object"},
+	}
+	assert.Equal(t, expected, result.RejectedRows)
+}
+
+func TestParser_Run_JSONLBrokenLines(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	guid := "01749246-95f6-57db-b7c3-2ae0e8be671f"
+
+	// битые строки отклоняются по одной, остальные записи файла сохраняются
+	content := `{"n": 1, "unit_guid": "` + guid + `", "class": "waiting"}` + "\n" +
+		`{"n": 2, "unit_guid": "` + guid + `", "class": ` + "\n" +
+		`[1, 2]` + "\n" +
+		`{"n": 4, "unit_guid": "` + guid + `", "class": "alarm"} {"n": 5}` + "\n" +
+		`{"n": 6, "unit_guid": "` + guid + `", "class": "working"}` + "\n"
+
+	filename := filepath.Join(t.TempDir(), "data.jsonl")
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+
+	files := make(chan *domain.File, 1)
+	files <- &domain.File{Source: "plant-a", Name: "data.jsonl", Path: filename}
+	close(files)
+
+	parseResults := make(chan *domain.ParseResult, 1)
+
+	settings := map[string]config.Parser{"plant-a": {RowPolicy: config.RowPolicyAcceptValid}}
+	parser := pipeline.NewParser(log, 1, settings, inputFormats(), files, parseResults)
+	require.NoError(t, parser.Run(context.Background()))
+
+	result := <-parseResults
+	require.NotNil(t, result)
+	devices := collectDevices(result)
+	require.NoError(t, result.Error)
+
+	require.Len(t, devices, 2)
+	assert.Equal(t, 1, devices[0].N)
+	assert.Equal(t, 6, devices[1].N)
+
+	// строка, не ставшая объектом, сохраняется как есть
+	expected := []*domain.RejectedRow{
+		{Line: 2, Raw: `{"n": 2, "unit_guid": "` + guid + `", "class":`, Reason: "invalid JSON: unexpected EOF"},
+		{Line: 3, Raw: `[1, 2]`, Reason: "expected an object"},
+		{Line: 4, Raw: `{"n": 4, "unit_guid": "` + guid + `", "class": "alarm"} {"n": 5}`, Reason: "unexpected data after the object"},
+	}
+	assert.Equal(t, expected, result.RejectedRows)
+	assert.Len(t, result.RowErrors, 3)
+}
+
+func TestParser_Run_Chunks(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	var content strings.Builder
+	content.WriteString("n\tunit_guid\tclass\n")
+	for i := 1; i <= 2500; i++ {
+		fmt.Fprintf(&content, "%d\t01749246-95f6-57db-b7c3-2ae0e8be671f\twaiting\n", i)
+	}
+
+	filename := filepath.Join(t.TempDir(), "big.tsv")
+	require.NoError(t, os.WriteFile(filename, []byte(content.String()), 0o644))
+
+	files := make(chan *domain.File, 1)
+	files <- &domain.File{Name: "big.tsv", Path: filename}
+	close(files)
+
+	parseResults := make(chan *domain.ParseResult, 1)
+
+	parser := pipeline.NewParser(log, 1, nil, inputFormats(), files, parseResults)
+
+	// Parser отдаёт устройства частями и ждёт, пока их прочитают
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- parser.Run(t.Context())
+	}()
+
+	result := <-parseResults
+	require.NotNil(t, result)
+
+	var sizes []int
+	for chunk := range result.Chunks {
+		sizes = append(sizes, len(chunk))
+	}
+
+	require.NoError(t, result.Error)
+	assert.Equal(t, []int{1000, 1000, 500}, sizes)
+
+	select {
+	case err := <-errChan:
+		require.NoError(t, err)
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("timeout: parser did not stop")
+	}
+}
+
+func TestParser_Run_Workers(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	var content strings.Builder
+	content.WriteString("n\tunit_guid\tclass\n")
+	for i := 1; i <= 3000; i++ {
+		fmt.Fprintf(&content, "%d\t01749246-95f6-57db-b7c3-2ae0e8be671f\twaiting\n", i)
+	}
+
+	dir := t.TempDir()
+	big := filepath.Join(dir, "big.tsv")
+	require.NoError(t, os.WriteFile(big, []byte(content.String()), 0o644))
+
+	files := make(chan *domain.File, 2)
+	files <- &domain.File{Name: "big.tsv", Path: big}
+	files <- &domain.File{Name: "small.tsv", Path: createTSV(t, &domain.Device{N: 1, UnitGUID: "01749246-95f6-57db-b7c3-2ae0e8be671f", Class: "waiting"})}
+	close(files)
+
+	parseResults := make(chan *domain.ParseResult, 2)
+
+	parser := pipeline.NewParser(log, 2, nil, inputFormats(), files, parseResults)
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- parser.Run(t.Context())
+	}()
+
+	// большой файл ждёт, пока прочитают его устройства, но второй воркер разбирает следующий
+	results := make(map[string]*domain.ParseResult, 2)
+	for range 2 {
+		select {
+		case result := <-parseResults:
+			results[result.File.Name] = result
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("timeout: files were not parsed concurrently")
+		}
+	}
+
+	assert.Len(t, collectDevices(results["small.tsv"]), 1)
+	assert.Len(t, collectDevices(results["big.tsv"]), 3000)
+	require.NoError(t, results["small.tsv"].Error)
+	require.NoError(t, results["big.tsv"].Error)
+
+	select {
+	case err := <-errChan:
+		require.NoError(t, err)
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("timeout: parser did not stop")
+	}
+
+	_, ok := <-parseResults
+	assert.False(t, ok, "parse results must be closed after the last worker stops")
+}
+
+func TestParser_Run_Encodings(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	expected := &domain.Device{
+		N:        1,
+		InvID:    "G-044322",
+		UnitGUID: "01749246-95f6-57db-b7c3-2ae0e8be671f",
+		MsgID:    "cold7_Defrost_status",
+		Text:     "Разморозка",
+		Context:  "Компрессор",
+		Class:    "waiting",
+		Level:    100,
+	}
+
+	content, err := os.ReadFile(createTSV(t, expected))
+	require.NoError(t, err)
+
+	encode := func(enc encoding.Encoding) []byte {
+		encoded, err := enc.NewEncoder().Bytes(content)
+		require.NoError(t, err)
+		return encoded
+	}
+
+	tests := []struct {
+		name     string
+		encoding config.Encoding
+		content  []byte
+	}{
+		{
+			name:     "utf-8 with bom",
+			encoding: config.EncodingAuto,
+			content:  append([]byte{0xEF, 0xBB, 0xBF}, content...),
+		},
+		{
+			name:     "windows-1251 detected",
+			encoding: config.EncodingAuto,
+			content:  encode(charmap.Windows1251),
+		},
+		{
+			name:     "utf-16le with bom",
+			encoding: config.EncodingAuto,
+			content:  encode(unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)),
+		},
+		{
+			name:     "utf-16be without bom",
+			encoding: config.EncodingAuto,
+			content:  encode(unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)),
+		},
+		{
+			name:     "windows-1251 configured",
+			encoding: config.EncodingWindows1251,
+			content:  encode(charmap.Windows1251),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			filename := filepath.Join(t.TempDir(), "data.tsv")
+			require.NoError(t, os.WriteFile(filename, tt.content, 0o644))
+
+			files := make(chan *domain.File, 1)
+			files <- &domain.File{Source: "scada", Name: "data.tsv", Path: filename}
+			close(files)
+
+			parseResults := make(chan *domain.ParseResult, 1)
+
+			settings := map[string]config.Parser{"scada": {Encoding: tt.encoding}}
+			parser := pipeline.NewParser(log, 1, settings, inputFormats(), files, parseResults)
+			require.NoError(t, parser.Run(context.Background()))
+
+			result := <-parseResults
+			require.NotNil(t, result)
+			devices := collectDevices(result)
+			require.NoError(t, result.Error)
+			require.Len(t, devices, 1)
+			assert.Equal(t, expected, devices[0])
+		})
+	}
+}
+
+func TestParser_Run_InvalidEncoding(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	// Во второй строке текст оборван посреди символа
+	content := "n\tunit_guid\tclass\ttext\n" +
+		"1\t01749246-95f6-57db-b7c3-2ae0e8be671f\twaiting\tРазморозка\n" +
+		"2\t01749246-95f6-57db-b7c3-2ae0e8be671f\twaiting\tРаз\xd0\n"
+
+	filename := filepath.Join(t.TempDir(), "data.tsv")
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+
+	files := make(chan *domain.File, 1)
+	files <- &domain.File{Source: "scada", Name: "data.tsv", Path: filename}
+	close(files)
+
+	parseResults := make(chan *domain.ParseResult, 1)
+
+	settings := map[string]config.Parser{"scada": {Encoding: config.EncodingUTF8, CollectRowErrors: true}}
+	parser := pipeline.NewParser(log, 1, settings, inputFormats(), files, parseResults)
+	require.NoError(t, parser.Run(context.Background()))
+
+	result := <-parseResults
+	require.NotNil(t, result)
+	collectDevices(result)
+	require.Error(t, result.Error)
+
+	expected := []*domain.RowError{
+		{Line: 3, Column: "text", Value: "Раз\uFFFD", Reason: "contains an invalid byte sequence"},
+	}
+	assert.Equal(t, expected, result.RowErrors)
+}
+
+func TestParser_Run_Rules(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	content := "n\tunit_guid\tmsg_id\tclass\tlevel\n" +
+		"1\t01749246-95f6-57db-b7c3-2ae0e8be671f\tcold7_Defrost\twaiting\t100\n" +
+		"2\tnot-a-guid\tcold7_Vent\tunknown\t150\n" +
+		"3\t01749246-95f6-57db-b7c3-2ae0e8be671f\tcold7 Temp\talarm\t50\n" +
+		"3\t01749246-95f6-57db-b7c3-2ae0e8be671f\tcold7_Defrost\talarm\t10\n"
+
+	filename := filepath.Join(t.TempDir(), "data.tsv")
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+
+	files := make(chan *domain.File, 1)
+	files <- &domain.File{Source: "plant-a", Name: "data.tsv", Path: filename}
+	close(files)
+
+	parseResults := make(chan *domain.ParseResult, 1)
+
+	minLevel, maxLevel := 0, 100
+	rules := []config.Rule{
+		{Name: "unit_guid_uuid", Kind: config.RuleUUID, Columns: []string{"unit_guid"}},
+		{Name: "class_allowed", Kind: config.RuleOneOf, Columns: []string{"class"}, Values: []string{"alarm", "waiting"}},
+		{Name: "level_range", Kind: config.RuleRange, Columns: []string{"level"}, Min: &minLevel, Max: &maxLevel},
+		{Name: "msg_id_format", Kind: config.RuleRegex, Columns: []string{"msg_id"}, Pattern: `^\w+$`},
+		{Name: "unique_message", Kind: config.RuleUnique, Columns: []string{"unit_guid", "msg_id"}},
+		{Name: "unique_n", Kind: config.RuleUnique, Columns: []string{"n"}},
+	}
+
+	settings := map[string]config.Parser{"plant-a": {Delimiter: '\t', CollectRowErrors: true, Rules: rules}}
+	parser := pipeline.NewParser(log, 1, settings, inputFormats(), files, parseResults)
+	require.NoError(t, parser.Run(context.Background()))
+
+	result := <-parseResults
+	require.NotNil(t, result)
+	collectDevices(result)
+	require.Error(t, result.Error)
+	assert.Contains(t, result.Error.Error(), "line 3: unit_guid is not a valid UUID (rule unit_guid_uuid)")
+
+	guid := "01749246-95f6-57db-b7c3-2ae0e8be671f"
+	expected := []*domain.RowError{
+		{Line: 3, Column: "unit_guid", Value: "not-a-guid", Reason: "is not a valid UUID", Rule: "unit_guid_uuid"},
+		{Line: 3, Column: "class", Value: "unknown", Reason: "is not one of alarm, waiting", Rule: "class_allowed"},
+		{Line: 3, Column: "level", Value: "150", Reason: "is greater than 100", Rule: "level_range"},
+		{Line: 4, Column: "msg_id", Value: "cold7 Temp", Reason: `does not match ^\w+$`, Rule: "msg_id_format"},
+		{Line: 5, Column: "unit_guid,msg_id", Value: guid + ",cold7_Defrost", Reason: "duplicates line 2", Rule: "unique_message"},
+		{Line: 5, Column: "n", Value: "3", Reason: "duplicates line 4", Rule: "unique_n"},
+	}
+	assert.Equal(t, expected, result.RowErrors)
+}
+
 func createTSV(t *testing.T, devices ...*domain.Device) string {
 	f, err := os.CreateTemp(t.TempDir(), "*.tsv")
 	require.NoError(t, err)
@@ -224,3 +1013,73 @@ func createEmptyTSV(t *testing.T) string {
 
 	return f.Name()
 }
+
+func createGzip(t *testing.T, content []byte) string {
+	f, err := os.CreateTemp(t.TempDir(), "*.tsv.gz")
+	require.NoError(t, err)
+
+	w := gzip.NewWriter(f)
+	_, err = w.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	require.NoError(t, f.Close())
+
+	return f.Name()
+}
+
+func createZip(t *testing.T, members map[string][]byte) string {
+	f, err := os.CreateTemp(t.TempDir(), "*.zip")
+	require.NoError(t, err)
+
+	w := zip.NewWriter(f)
+	for name, content := range members {
+		mw, err := w.Create(name)
+		require.NoError(t, err)
+
+		_, err = mw.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	require.NoError(t, f.Close())
+
+	return f.Name()
+}
+
+// createXLSX puts TSV content into the first sheet of a workbook.
+func createXLSX(t *testing.T, tsv []byte) []byte {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := f.GetSheetName(0)
+	for i, line := range strings.Split(strings.TrimSpace(string(tsv)), "\n") {
+		row := make([]any, 0)
+		for _, cell := range strings.Split(line, "\t") {
+			row = append(row, cell)
+		}
+
+		cellName, err := excelize.CoordinatesToCellName(1, i+1)
+		require.NoError(t, err)
+		require.NoError(t, f.SetSheetRow(sheet, cellName, &row))
+	}
+
+	buf, err := f.WriteToBuffer()
+	require.NoError(t, err)
+
+	return buf.Bytes()
+}
+
+// collectDevices reads every chunk of the result; its outcome is final only after that.
+func collectDevices(result *domain.ParseResult) []*domain.Device {
+	var devices []*domain.Device
+	for chunk := range result.Chunks {
+		devices = append(devices, chunk...)
+	}
+
+	return devices
+}
+
+func inputFormats() []pipeline.Format {
+	return []pipeline.Format{formats.NewJSONL(), formats.NewXLSX(), formats.NewCSV()}
+}
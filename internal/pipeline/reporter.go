@@ -2,35 +2,102 @@ package pipeline
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 
+	"github.com/kurochkinivan/device_reporter/internal/config"
 	"github.com/kurochkinivan/device_reporter/internal/domain"
+	"golang.org/x/sync/errgroup"
 )
 
+const (
+	// jobsBatch is how many outbox jobs are fetched at once.
+	jobsBatch = 10
+	// maxJobAttempts bounds the retries of a failing job until the next start of the service.
+	maxJobAttempts = 5
+	// jobsPollInterval is how often the outbox is checked for postponed jobs.
+	jobsPollInterval = 30 * time.Second
+	// jobRetryDelay is the delay before the first retry, doubled with every next attempt.
+	jobRetryDelay = time.Minute
+	// reportTimestamp is the layout of the {timestamp} placeholder.
+	reportTimestamp = "20060102T150405Z"
+	// fleetDir holds the fleet reports within the reports directory.
+	fleetDir = "fleet"
+	// legacyReportName is the name a report written before report history gets in the directory of its unit.
+	legacyReportName = "legacy"
+)
+
+// Reporter generates the reports enqueued to the outbox by the Writer. A parse result on
+// the reports channel only signals new jobs, so jobs committed right before a crash are
+// picked up after the restart.
 type Reporter struct {
 	log             *slog.Logger
-	outputDir       string
+	workers         int
+	settings        config.Reports
+	outputDirs      map[string]string             // по имени источника
+	styles          map[string]config.ReportStyle // по имени источника
 	reports         <-chan *domain.ParseResult
-	reportGenerator ReportGenerator
+	devicesProvider DevicesProvider
+	fleetProvider   FleetProvider
+	reportsTracker  ReportsTracker
+	reportJobs      ReportJobs
+	generators      map[string]ReportGenerator // по формату
 }
 
 func NewReporter(
 	log *slog.Logger,
-	outputDir string,
+	workers int,
+	settings config.Reports,
+	outputDirs map[string]string,
+	styles map[string]config.ReportStyle,
 	reports <-chan *domain.ParseResult,
-	reportGenerator ReportGenerator,
+	devicesProvider DevicesProvider,
+	fleetProvider FleetProvider,
+	reportsTracker ReportsTracker,
+	reportJobs ReportJobs,
+	reportGenerators []ReportGenerator,
 ) *Reporter {
+	generators := make(map[string]ReportGenerator, len(reportGenerators))
+	for _, generator := range reportGenerators {
+		generators[generator.Format()] = generator
+	}
+
 	return &Reporter{
 		log:             log,
-		outputDir:       outputDir,
+		workers:         max(workers, 1),
+		settings:        settings,
+		outputDirs:      outputDirs,
+		styles:          styles,
 		reports:         reports,
-		reportGenerator: reportGenerator,
+		devicesProvider: devicesProvider,
+		fleetProvider:   fleetProvider,
+		reportsTracker:  reportsTracker,
+		reportJobs:      reportJobs,
+		generators:      generators,
 	}
 }
 
 func (r *Reporter) Run(ctx context.Context) error {
+	// после перезапуска задания, исчерпавшие попытки, получают новые
+	if err := r.reportJobs.ResetReportJobs(ctx); err != nil {
+		r.log.ErrorContext(ctx, "failed to reset report jobs", slog.String("err", err.Error()))
+	}
+
+	r.processJobs(ctx)
+
+	ticker := time.NewTicker(jobsPollInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case result, ok := <-r.reports:
@@ -38,16 +105,14 @@ func (r *Reporter) Run(ctx context.Context) error {
 				return nil
 			}
 
-			log := r.log.With(
-				slog.String("filename", result.Filename),
-				slog.Int("devices_count", len(result.Devices)),
+			r.log.DebugContext(ctx, "received parse result, processing report jobs",
+				slog.String("filename", result.File.Name),
 			)
 
-			log.InfoContext(ctx, "received parse result, generating report")
+			r.processJobs(ctx)
 
-			if err := r.processResult(result); err != nil {
-				log.InfoContext(ctx, "failed to generate report", slog.String("err", err.Error()))
-			}
+		case <-ticker.C:
+			r.processJobs(ctx)
 
 		case <-ctx.Done():
 			return ctx.Err()
@@ -55,20 +120,443 @@ func (r *Reporter) Run(ctx context.Context) error {
 	}
 }
 
-func (r *Reporter) processResult(result *domain.ParseResult) error {
-	// группируем устройства по unit_guid
-	byGUID := make(map[string][]*domain.Device)
-	for _, d := range result.Devices {
-		byGUID[d.UnitGUID] = append(byGUID[d.UnitGUID], d)
+// processJobs handles the due jobs until none are left. A job is acknowledged once
+// the statuses of its reports are saved, and postponed if any of them failed.
+func (r *Reporter) processJobs(ctx context.Context) {
+	for ctx.Err() == nil {
+		jobs, err := r.reportJobs.DueReportJobs(ctx, jobsBatch, maxJobAttempts)
+		if err != nil {
+			r.log.ErrorContext(ctx, "failed to get report jobs", slog.String("err", err.Error()))
+			return
+		}
+
+		if len(jobs) == 0 {
+			return
+		}
+
+		for _, job := range jobs {
+			if err := r.processJob(ctx, job); err != nil {
+				// не подтверждённое задание вернётся при следующей проверке
+				r.log.ErrorContext(ctx, "failed to complete report job",
+					slog.Int64("job_id", job.ID),
+					slog.String("err", err.Error()),
+				)
+				return
+			}
+		}
+	}
+}
+
+func (r *Reporter) processJob(ctx context.Context, job *domain.ReportJob) error {
+	log := r.log.With(
+		slog.String("source", job.Source),
+		slog.String("filename", job.FileName),
+		slog.Int64("job_id", job.ID),
+	)
+
+	log.InfoContext(ctx, "generating reports", slog.Int("attempt", job.Attempts+1))
+
+	err := r.generateReports(ctx, log, &domain.File{Source: job.Source, Name: job.FileName})
+	if err == nil {
+		return r.reportJobs.AckReportJob(ctx, job.ID)
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	log.ErrorContext(ctx, "failed to generate report", slog.String("err", err.Error()))
+
+	retryAt := time.Now().Add(jobRetryDelay << job.Attempts)
+
+	return r.reportJobs.RetryReportJob(ctx, job.ID, retryAt, err.Error())
+}
+
+// generateReports renders the pending reports of the file. A failed unit does not stop
+// the others: each unit gets its own status, and the errors of all units are returned together.
+func (r *Reporter) generateReports(ctx context.Context, log *slog.Logger, file *domain.File) error {
+	outputDir, ok := r.outputDirs[file.Source]
+	if !ok {
+		return fmt.Errorf("no reports directory for source %q", file.Source)
+	}
+
+	// Writer заводит по отчёту на каждый unit_guid в одной транзакции с сохранением файла
+	reports, err := r.reportsTracker.PendingReports(ctx, file.Source, file.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get pending reports: %w", err)
+	}
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+
+	// ошибки не отменяют остальные отчёты, поэтому группа без контекста
+	var erg errgroup.Group
+	erg.SetLimit(r.workers)
+
+	for _, report := range reports {
+		erg.Go(func() error {
+			r.generateReport(ctx, log, outputDir, report)
+
+			var reportErr error
+			if report.Status == domain.ReportStatusError {
+				reportErr = fmt.Errorf("guid %s: %s", report.UnitGUID, report.ErrorMessage)
+			}
+
+			// статус сохраняется сразу, чтобы после краша не строить готовые отчёты заново
+			if err := r.reportsTracker.UpdateReport(ctx, report); err != nil {
+				reportErr = errors.Join(reportErr, fmt.Errorf("guid %s: failed to save report status: %w", report.UnitGUID, err))
+			}
+
+			if reportErr != nil {
+				mu.Lock()
+				errs = append(errs, reportErr)
+				mu.Unlock()
+			}
+
+			return nil
+		})
+	}
+
+	_ = erg.Wait()
+
+	log.InfoContext(ctx, "reports generated",
+		slog.Int("total", len(reports)),
+		slog.Int("failed", len(errs)),
+	)
+
+	if r.settings.Fleet {
+		// сводные отчёты ссылаются на отчёты устройств, поэтому строятся после них
+		if err := r.generateFleetReports(ctx, log, outputDir, file); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// generateFleetReports renders the fleet report of the file and the reports of the inventory
+// IDs of its units. They are rewritten on every run, so a retried job brings them up to date.
+func (r *Reporter) generateFleetReports(ctx context.Context, log *slog.Logger, outputDir string, file *domain.File) error {
+	units, err := r.fleetProvider.UnitsByFile(ctx, file.Source, file.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get units of file: %w", err)
+	}
+
+	if len(units) == 0 {
+		return nil
+	}
+
+	style := r.styles[file.Source]
+	stem := strings.TrimSuffix(filepath.ToSlash(file.Name), path.Ext(file.Name))
+
+	var errs []error
+
+	name := filepath.Join(outputDir, fleetDir, "files", filepath.FromSlash(stem))
+	fleet := &domain.FleetReport{Source: file.Source, FileName: file.Name, Units: units}
+	if err := r.renderFleetReport(name, style, fleet); err != nil {
+		errs = append(errs, fmt.Errorf("fleet report: %w", err))
+	}
+
+	var invIDs []string
+	for _, unit := range units {
+		if unit.InvID != "" && !slices.Contains(invIDs, unit.InvID) {
+			invIDs = append(invIDs, unit.InvID)
+		}
+	}
+
+	for _, invID := range invIDs {
+		// устройства номера собираются по всем файлам источника, а не только по этому
+		units, err := r.fleetProvider.UnitsByInvID(ctx, file.Source, invID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("inventory %s: failed to get units: %w", invID, err))
+			continue
+		}
+
+		name := filepath.Join(outputDir, fleetDir, "inventory", fileSafe(invID))
+		fleet := &domain.FleetReport{Source: file.Source, InvID: invID, Units: units}
+		if err := r.renderFleetReport(name, style, fleet); err != nil {
+			errs = append(errs, fmt.Errorf("inventory %s: %w", invID, err))
+		}
+	}
+
+	log.InfoContext(ctx, "fleet reports generated",
+		slog.Int("inventory_ids", len(invIDs)),
+		slog.Int("failed", len(errs)),
+	)
+
+	return errors.Join(errs...)
+}
+
+// renderFleetReport writes a fleet report in every format. Each file is replaced with
+// a rename, so readers never see a partly written report.
+func (r *Reporter) renderFleetReport(name string, style config.ReportStyle, fleet *domain.FleetReport) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("fleet report generation panicked: %v", p)
+		}
+	}()
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	for _, format := range r.settings.Formats {
+		generator, ok := r.generators[format]
+		if !ok {
+			return fmt.Errorf("no report generator for format %q", format)
+		}
+
+		// временный файл сохраняет расширение: по нему формат проверяет, например, excelize
+		tmp := name + ".tmp." + format
+		if err := generator.GenerateFleetReport(tmp, style, fleet); err != nil {
+			_ = os.Remove(tmp)
+			return fmt.Errorf("%s: %w", format, err)
+		}
+
+		if err := os.Rename(tmp, name+"."+format); err != nil {
+			_ = os.Remove(tmp)
+			return fmt.Errorf("%s: %w", format, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Reporter) generateReport(ctx context.Context, log *slog.Logger, outputDir string, report *domain.Report) {
+	now := time.Now()
+
+	report.Version = 0
+	report.Files = nil
+	report.Status = domain.ReportStatusDone
+	report.ErrorMessage = ""
+	report.GeneratedAt = &now
+
+	if err := r.renderReport(ctx, outputDir, report); err != nil {
+		report.Status = domain.ReportStatusError
+		report.ErrorMessage = err.Error()
+		return
+	}
+
+	if r.settings.LatestLink {
+		// ссылка только для удобства, отчёт уже построен и сохранён в истории
+		for _, file := range report.Files {
+			if err := r.moveLegacyReport(ctx, report.Source, outputDir, report.UnitGUID, file.Format); err != nil {
+				log.WarnContext(ctx, "failed to move legacy report",
+					slog.String("guid", report.UnitGUID),
+					slog.String("format", file.Format),
+					slog.String("err", err.Error()),
+				)
+				continue
+			}
+
+			if err := linkLatest(outputDir, report.UnitGUID, file); err != nil {
+				log.WarnContext(ctx, "failed to link latest report",
+					slog.String("guid", report.UnitGUID),
+					slog.String("format", file.Format),
+					slog.String("err", err.Error()),
+				)
+			}
+		}
+	}
+}
+
+func (r *Reporter) renderReport(ctx context.Context, outputDir string, report *domain.Report) (err error) {
+	// паника при отрисовке одного отчёта не должна остановить остальные
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("report generation panicked: %v", p)
+		}
+	}()
+
+	version, err := r.reportsTracker.NextReportVersion(ctx, report.Source, report.UnitGUID)
+	if err != nil {
+		return fmt.Errorf("failed to get report version: %w", err)
+	}
+
+	name := filepath.Join(outputDir, reportName(r.settings.NameTemplate, report, version))
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	devices, err := r.devices(ctx, report)
+	if err != nil {
+		return fmt.Errorf("failed to get devices: %w", err)
+	}
+
+	if len(devices) == 0 {
+		return fmt.Errorf("no records of %s", report.UnitGUID)
+	}
+
+	// записи читаются один раз и отрисовываются в каждом формате
+	files := make([]*domain.ReportFile, 0, len(r.settings.Formats))
+	for _, format := range r.settings.Formats {
+		file, err := r.renderFile(format, name+"."+format, report, devices)
+		if err != nil {
+			return fmt.Errorf("%s: %w", format, err)
+		}
+		files = append(files, file)
+	}
+
+	// в историю попадает только отчёт, целиком построенный во всех форматах
+	err = r.reportsTracker.AddReportVersion(ctx, &domain.ReportVersion{
+		Source:      report.Source,
+		UnitGUID:    report.UnitGUID,
+		Version:     version,
+		FileName:    report.FileName,
+		Files:       files,
+		GeneratedAt: *report.GeneratedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add report to history: %w", err)
+	}
+	report.Version = version
+	report.Files = files
+
+	return nil
+}
+
+func (r *Reporter) renderFile(format, path string, report *domain.Report, devices []*domain.Device) (*domain.ReportFile, error) {
+	generator, ok := r.generators[format]
+	if !ok {
+		return nil, fmt.Errorf("no report generator for format %q", format)
+	}
+
+	if err := generator.GenerateReport(path, r.styles[report.Source], report.UnitGUID, report.FileName, devices); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat report: %w", err)
+	}
+
+	hash, err := hashFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash report: %w", err)
+	}
+
+	return &domain.ReportFile{Format: format, Path: path, Size: info.Size(), Hash: hash}, nil
+}
+
+// devices returns the records the report covers according to the report mode.
+func (r *Reporter) devices(ctx context.Context, report *domain.Report) ([]*domain.Device, error) {
+	if r.settings.Mode != config.ReportModeCumulative {
+		return r.devicesProvider.DevicesByFile(ctx, report.Source, report.FileName, report.UnitGUID)
 	}
 
-	// для каждого guid генерируем отдельный PDF
-	for guid, devices := range byGUID {
-		path := filepath.Join(r.outputDir, guid+".pdf")
+	var since time.Time
+	if r.settings.Window > 0 {
+		since = report.GeneratedAt.Add(-r.settings.Window)
+	}
+
+	return r.devicesProvider.DevicesByUnit(ctx, report.Source, report.UnitGUID, since)
+}
+
+// reportName substitutes the placeholders of a name template for a report.
+func reportName(template string, report *domain.Report, version int) string {
+	file := path.Base(filepath.ToSlash(report.FileName))
+	file = strings.TrimSuffix(file, path.Ext(file))
+
+	name := strings.NewReplacer(
+		config.ReportNameGUID, report.UnitGUID,
+		config.ReportNameFile, file,
+		config.ReportNameTimestamp, report.GeneratedAt.UTC().Format(reportTimestamp),
+		config.ReportNameVersion, strconv.Itoa(version),
+	).Replace(template)
 
-		if err := r.reportGenerator.GenerateReport(path, guid, result.Filename, devices); err != nil {
-			return fmt.Errorf("guid %s: %w", guid, err)
+	return filepath.FromSlash(name)
+}
+
+// fileSafe replaces the characters of a value that are unsafe in a file name.
+func fileSafe(value string) string {
+	safe := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.' {
+			return r
 		}
+		return '_'
+	}, value)
+
+	// имя из одних точек указывало бы на каталог
+	if strings.Trim(safe, ".") == "" {
+		safe = strings.ReplaceAll(safe, ".", "_")
+	}
+
+	return safe
+}
+
+// moveLegacyReport moves a report written before report history, <unit_guid>.<format> in the
+// reports directory, to <unit_guid>/legacy.<format>, so that the latest link can take its place.
+// The history of the unit is updated to the new path.
+func (r *Reporter) moveLegacyReport(ctx context.Context, source, outputDir, unitGUID, format string) error {
+	legacy := filepath.Join(outputDir, unitGUID+"."+format)
+
+	info, err := os.Lstat(legacy)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil
+	case err != nil:
+		return err
+	case info.Mode()&os.ModeSymlink != 0:
+		return nil
+	case !info.Mode().IsRegular():
+		return fmt.Errorf("%s is not a regular file", legacy)
+	}
+
+	target := filepath.Join(outputDir, unitGUID, legacyReportName+"."+format)
+	if _, err := os.Lstat(target); err == nil {
+		return fmt.Errorf("%s already exists", target)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	if err := os.Rename(legacy, target); err != nil {
+		return err
+	}
+
+	// без новой записи история указывала бы на ссылку, то есть на последний отчёт
+	if err := r.reportsTracker.MoveReportFile(ctx, source, legacy, target); err != nil {
+		return errors.Join(fmt.Errorf("failed to record the moved report: %w", err), os.Rename(target, legacy))
+	}
+
+	return nil
+}
+
+// linkLatest points <unit_guid>.<format> in the reports directory to the report file.
+// The link is replaced with a rename, so it never goes missing for consumers.
+func linkLatest(outputDir, unitGUID string, file *domain.ReportFile) error {
+	link := filepath.Join(outputDir, unitGUID+"."+file.Format)
+
+	info, err := os.Lstat(link)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	case info.Mode()&os.ModeSymlink == 0:
+		// отчёт, построенный до появления истории, не затираем
+		return fmt.Errorf("%s is not a symlink", link)
+	}
+
+	target, err := filepath.Rel(outputDir, file.Path)
+	if err != nil {
+		return err
+	}
+
+	tmp := link + ".tmp"
+	_ = os.Remove(tmp)
+
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, link); err != nil {
+		_ = os.Remove(tmp)
+		return err
 	}
 
 	return nil
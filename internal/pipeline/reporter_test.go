@@ -2,12 +2,19 @@ package pipeline_test
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/kurochkinivan/device_reporter/internal/config"
 	"github.com/kurochkinivan/device_reporter/internal/domain"
 	"github.com/kurochkinivan/device_reporter/internal/pipeline"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -36,23 +43,61 @@ func TestReporter_Run_HappyPath(t *testing.T) {
 	}
 
 	parseResult := &domain.ParseResult{
-		Filename: "test.tsv",
-		Error:    nil,
-		Devices:  []*domain.Device{device},
+		File:  &domain.File{Source: "plant-a", Name: "test.tsv"},
+		Error: nil,
 	}
 
 	reports := make(chan *domain.ParseResult, 1)
 
-	mockReportGenerator := NewMockReportGenerator(t)
+	outputDir := t.TempDir()
+
+	mockReportJobs := NewMockReportJobs(t)
+	mockReportJobs.EXPECT().ResetReportJobs(mock.Anything).Return(nil)
+	// до сигнала от Writer очередь пуста, затем в ней задание на файл
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).
+		Return([]*domain.ReportJob{{ID: 1, Source: "plant-a", FileName: "test.tsv"}}, nil).Once()
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	mockReportJobs.EXPECT().AckReportJob(mock.Anything, int64(1)).Return(nil)
+
+	// отчёты заводит Writer, Reporter берёт ожидающие и читает устройства из базы
+	mockReportsTracker := NewMockReportsTracker(t)
+	mockReportsTracker.EXPECT().PendingReports(mock.Anything, "plant-a", "test.tsv").
+		Return([]*domain.Report{pendingReport("plant-a", "test.tsv", device.UnitGUID)}, nil)
+	mockReportsTracker.EXPECT().NextReportVersion(mock.Anything, "plant-a", device.UnitGUID).Return(3, nil)
+	mockReportsTracker.EXPECT().AddReportVersion(mock.Anything, mock.MatchedBy(func(version *domain.ReportVersion) bool {
+		return version.UnitGUID == device.UnitGUID &&
+			version.Version == 3 &&
+			version.FileName == "test.tsv" &&
+			len(version.Files) == 1 &&
+			version.Files[0].Path == filepath.Join(outputDir, device.UnitGUID, "v3_test.pdf")
+	})).Return(nil)
+	mockReportsTracker.EXPECT().UpdateReport(mock.Anything, mock.MatchedBy(func(report *domain.Report) bool {
+		return report.UnitGUID == device.UnitGUID &&
+			report.Status == domain.ReportStatusDone &&
+			report.Version == 3 &&
+			len(report.Files) == 1 &&
+			report.Files[0].Path == filepath.Join(outputDir, device.UnitGUID, "v3_test.pdf") &&
+			report.Files[0].Format == "pdf" &&
+			report.Files[0].Size == int64(len(reportContent)) &&
+			report.Files[0].Hash != "" &&
+			report.GeneratedAt != nil
+	})).Return(nil)
+
+	mockDevicesProvider := NewMockDevicesProvider(t)
+	mockDevicesProvider.EXPECT().DevicesByFile(mock.Anything, "plant-a", "test.tsv", device.UnitGUID).
+		Return([]*domain.Device{device}, nil)
+
+	mockReportGenerator := newMockReportGenerator(t, "pdf")
 	mockReportGenerator.EXPECT().
 		GenerateReport(mock.MatchedBy(func(path string) bool {
 			return path != ""
-		}), device.UnitGUID, parseResult.Filename, mock.MatchedBy(func(devices []*domain.Device) bool {
+		}), mock.Anything, device.UnitGUID, parseResult.File.Name, mock.MatchedBy(func(devices []*domain.Device) bool {
 			return len(devices) == 1 && devices[0].UnitGUID == device.UnitGUID
 		})).
-		Return(nil)
+		RunAndReturn(writeReport)
 
-	reporter := pipeline.NewReporter(log, "/tmp", reports, mockReportGenerator)
+	reporter := pipeline.NewReporter(log, 1, reportSettings, map[string]string{"plant-a": outputDir}, nil, reports, mockDevicesProvider, nil, mockReportsTracker, mockReportJobs, []pipeline.ReportGenerator{mockReportGenerator})
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -91,18 +136,32 @@ func TestReporter_Run_EmptyDevices(t *testing.T) {
 	log := slog.New(slog.DiscardHandler)
 
 	parseResult := &domain.ParseResult{
-		Filename: "empty.tsv",
-		Error:    nil,
-		Devices:  []*domain.Device{}, // Empty devices list
+		File:  &domain.File{Source: "plant-a", Name: "empty.tsv"},
+		Error: nil,
 	}
 
 	reports := make(chan *domain.ParseResult, 1)
 
-	mockReportGenerator := NewMockReportGenerator(t)
+	mockDevicesProvider := NewMockDevicesProvider(t)
+
+	mockReportJobs := NewMockReportJobs(t)
+	mockReportJobs.EXPECT().ResetReportJobs(mock.Anything).Return(nil)
+	// до сигнала от Writer очередь пуста, затем в ней задание на файл
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).
+		Return([]*domain.ReportJob{{ID: 1, Source: "plant-a", FileName: "empty.tsv"}}, nil).Once()
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	mockReportJobs.EXPECT().AckReportJob(mock.Anything, int64(1)).Return(nil)
+
+	mockReportsTracker := NewMockReportsTracker(t)
+	mockReportsTracker.EXPECT().PendingReports(mock.Anything, "plant-a", "empty.tsv").
+		Return([]*domain.Report{}, nil) // Empty devices list
+
+	mockReportGenerator := newMockReportGenerator(t, "pdf")
 	// GenerateReport should NOT be called when devices list is empty
 	mockReportGenerator.AssertNotCalled(t, "GenerateReport")
 
-	reporter := pipeline.NewReporter(log, "/tmp", reports, mockReportGenerator)
+	reporter := pipeline.NewReporter(log, 1, reportSettings, map[string]string{"plant-a": "/tmp"}, nil, reports, mockDevicesProvider, nil, mockReportsTracker, mockReportJobs, []pipeline.ReportGenerator{mockReportGenerator})
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -142,9 +201,16 @@ func TestReporter_Run_ChannelCloses(t *testing.T) {
 
 	reports := make(chan *domain.ParseResult, 1)
 
-	mockReportGenerator := NewMockReportGenerator(t)
+	mockDevicesProvider := NewMockDevicesProvider(t)
+	mockReportGenerator := newMockReportGenerator(t, "pdf")
 
-	reporter := pipeline.NewReporter(log, "/tmp", reports, mockReportGenerator)
+	mockReportsTracker := NewMockReportsTracker(t)
+
+	mockReportJobs := NewMockReportJobs(t)
+	mockReportJobs.EXPECT().ResetReportJobs(mock.Anything).Return(nil)
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+
+	reporter := pipeline.NewReporter(log, 1, reportSettings, map[string]string{"plant-a": "/tmp"}, nil, reports, mockDevicesProvider, nil, mockReportsTracker, mockReportJobs, []pipeline.ReportGenerator{mockReportGenerator})
 
 	ctx, cancel := context.WithCancel(t.Context())
 	defer cancel()
@@ -164,3 +230,455 @@ func TestReporter_Run_ChannelCloses(t *testing.T) {
 		t.Fatal("timeout: error was not sent to channel")
 	}
 }
+
+func TestReporter_Run_FailedUnit(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	guids := []string{
+		"01749246-95f6-57db-b7c3-2ae0e8be671f",
+		"01749246-960c-5832-b2aa-ed2b4da5e137",
+		"01749246-9617-585e-9e19-157ccad61ee2",
+	}
+
+	reports := make(chan *domain.ParseResult)
+	close(reports)
+
+	mockDevicesProvider := NewMockDevicesProvider(t)
+	mockDevicesProvider.EXPECT().DevicesByFile(mock.Anything, "plant-a", "test.tsv", mock.Anything).
+		RunAndReturn(func(_ context.Context, _, _, guid string) ([]*domain.Device, error) {
+			return []*domain.Device{{UnitGUID: guid}}, nil
+		})
+
+	// отчёт второго устройства не строится, остальные должны быть сгенерированы
+	mockReportGenerator := newMockReportGenerator(t, "pdf")
+	mockReportGenerator.EXPECT().GenerateReport(mock.Anything, mock.Anything, guids[0], "test.tsv", mock.Anything).RunAndReturn(writeReport)
+	mockReportGenerator.EXPECT().GenerateReport(mock.Anything, mock.Anything, guids[1], "test.tsv", mock.Anything).Return(errors.New("broken font"))
+	mockReportGenerator.EXPECT().GenerateReport(mock.Anything, mock.Anything, guids[2], "test.tsv", mock.Anything).RunAndReturn(writeReport)
+
+	pending := make([]*domain.Report, 0, len(guids))
+	for _, guid := range guids {
+		pending = append(pending, pendingReport("plant-a", "test.tsv", guid))
+	}
+
+	var (
+		mu    sync.Mutex
+		saved = make(map[string]domain.Report)
+	)
+
+	mockReportsTracker := NewMockReportsTracker(t)
+	mockReportsTracker.EXPECT().PendingReports(mock.Anything, "plant-a", "test.tsv").Return(pending, nil)
+	mockReportsTracker.EXPECT().NextReportVersion(mock.Anything, "plant-a", mock.Anything).Return(1, nil)
+	mockReportsTracker.EXPECT().AddReportVersion(mock.Anything, mock.Anything).Return(nil)
+	mockReportsTracker.EXPECT().UpdateReport(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, report *domain.Report) error {
+			mu.Lock()
+			defer mu.Unlock()
+			saved[report.UnitGUID] = *report
+			return nil
+		})
+
+	// задание с неудавшимся отчётом не подтверждается, а откладывается
+	mockReportJobs := NewMockReportJobs(t)
+	mockReportJobs.EXPECT().ResetReportJobs(mock.Anything).Return(nil)
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).
+		Return([]*domain.ReportJob{{ID: 7, Source: "plant-a", FileName: "test.tsv", Attempts: 1}}, nil).Once()
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	mockReportJobs.EXPECT().RetryReportJob(mock.Anything, int64(7), mock.MatchedBy(func(retryAt time.Time) bool {
+		return retryAt.After(time.Now())
+	}), mock.MatchedBy(func(reason string) bool {
+		return strings.Contains(reason, guids[1]) && strings.Contains(reason, "broken font")
+	})).Return(nil)
+
+	reporter := pipeline.NewReporter(log, 2, reportSettings, map[string]string{"plant-a": t.TempDir()}, nil, reports, mockDevicesProvider, nil, mockReportsTracker, mockReportJobs, []pipeline.ReportGenerator{mockReportGenerator})
+	require.NoError(t, reporter.Run(t.Context()))
+
+	require.Len(t, saved, 3)
+	assert.Equal(t, domain.ReportStatusDone, saved[guids[0]].Status)
+	assert.Equal(t, domain.ReportStatusError, saved[guids[1]].Status)
+	assert.Equal(t, "pdf: broken font", saved[guids[1]].ErrorMessage)
+	assert.Equal(t, domain.ReportStatusDone, saved[guids[2]].Status)
+}
+
+func TestReporter_Run_JobsFromOutbox(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	guid := "01749246-95f6-57db-b7c3-2ae0e8be671f"
+
+	// файл сохранён до краша, сигнал Writer'а потерян, но задание осталось в outbox
+	reports := make(chan *domain.ParseResult)
+	close(reports)
+
+	mockDevicesProvider := NewMockDevicesProvider(t)
+	mockDevicesProvider.EXPECT().DevicesByFile(mock.Anything, "plant-a", "old.tsv", guid).
+		Return([]*domain.Device{{UnitGUID: guid}}, nil)
+
+	mockReportGenerator := newMockReportGenerator(t, "pdf")
+	mockReportGenerator.EXPECT().GenerateReport(mock.Anything, mock.Anything, guid, "old.tsv", mock.Anything).RunAndReturn(writeReport)
+
+	mockReportsTracker := NewMockReportsTracker(t)
+	mockReportsTracker.EXPECT().PendingReports(mock.Anything, "plant-a", "old.tsv").
+		Return([]*domain.Report{pendingReport("plant-a", "old.tsv", guid)}, nil)
+	mockReportsTracker.EXPECT().NextReportVersion(mock.Anything, "plant-a", guid).Return(1, nil)
+	mockReportsTracker.EXPECT().AddReportVersion(mock.Anything, mock.Anything).Return(nil)
+	mockReportsTracker.EXPECT().UpdateReport(mock.Anything, mock.MatchedBy(func(report *domain.Report) bool {
+		return report.UnitGUID == guid && report.Status == domain.ReportStatusDone
+	})).Return(nil)
+
+	mockReportJobs := NewMockReportJobs(t)
+	mockReportJobs.EXPECT().ResetReportJobs(mock.Anything).Return(nil)
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).
+		Return([]*domain.ReportJob{{ID: 3, Source: "plant-a", FileName: "old.tsv"}}, nil).Once()
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	mockReportJobs.EXPECT().AckReportJob(mock.Anything, int64(3)).Return(nil)
+
+	reporter := pipeline.NewReporter(log, 1, reportSettings, map[string]string{"plant-a": t.TempDir()}, nil, reports, mockDevicesProvider, nil, mockReportsTracker, mockReportJobs, []pipeline.ReportGenerator{mockReportGenerator})
+	require.NoError(t, reporter.Run(t.Context()))
+}
+
+func TestReporter_Run_KeepsReportHistory(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	guid := "01749246-95f6-57db-b7c3-2ae0e8be671f"
+	outputDir := t.TempDir()
+
+	reports := make(chan *domain.ParseResult)
+	close(reports)
+
+	mockDevicesProvider := NewMockDevicesProvider(t)
+	mockDevicesProvider.EXPECT().DevicesByFile(mock.Anything, "plant-a", mock.Anything, guid).
+		Return([]*domain.Device{{UnitGUID: guid}}, nil)
+
+	mockReportGenerator := newMockReportGenerator(t, "pdf")
+	mockReportGenerator.EXPECT().GenerateReport(mock.Anything, mock.Anything, guid, mock.Anything, mock.Anything).RunAndReturn(writeReport)
+
+	// устройство есть в двух файлах, отчёт второго не должен затереть первый
+	mockReportsTracker := NewMockReportsTracker(t)
+	mockReportsTracker.EXPECT().PendingReports(mock.Anything, "plant-a", "first.tsv").
+		Return([]*domain.Report{pendingReport("plant-a", "first.tsv", guid)}, nil)
+	mockReportsTracker.EXPECT().PendingReports(mock.Anything, "plant-a", "second.tsv").
+		Return([]*domain.Report{pendingReport("plant-a", "second.tsv", guid)}, nil)
+	mockReportsTracker.EXPECT().NextReportVersion(mock.Anything, "plant-a", guid).Return(1, nil).Once()
+	mockReportsTracker.EXPECT().NextReportVersion(mock.Anything, "plant-a", guid).Return(2, nil).Once()
+	mockReportsTracker.EXPECT().AddReportVersion(mock.Anything, mock.Anything).Return(nil).Times(2)
+	mockReportsTracker.EXPECT().UpdateReport(mock.Anything, mock.MatchedBy(func(report *domain.Report) bool {
+		return report.Status == domain.ReportStatusDone
+	})).Return(nil).Times(2)
+
+	mockReportJobs := NewMockReportJobs(t)
+	mockReportJobs.EXPECT().ResetReportJobs(mock.Anything).Return(nil)
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).
+		Return([]*domain.ReportJob{
+			{ID: 1, Source: "plant-a", FileName: "first.tsv"},
+			{ID: 2, Source: "plant-a", FileName: "second.tsv"},
+		}, nil).Once()
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	mockReportJobs.EXPECT().AckReportJob(mock.Anything, mock.Anything).Return(nil).Times(2)
+
+	reporter := pipeline.NewReporter(log, 1, reportSettings, map[string]string{"plant-a": outputDir}, nil, reports, mockDevicesProvider, nil, mockReportsTracker, mockReportJobs, []pipeline.ReportGenerator{mockReportGenerator})
+	require.NoError(t, reporter.Run(t.Context()))
+
+	assert.FileExists(t, filepath.Join(outputDir, guid, "v1_first.pdf"))
+	assert.FileExists(t, filepath.Join(outputDir, guid, "v2_second.pdf"))
+
+	// ссылка на последний отчёт указывает на вторую версию
+	target, err := os.Readlink(filepath.Join(outputDir, guid+".pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(guid, "v2_second.pdf"), target)
+}
+
+func TestReporter_Run_MovesLegacyReport(t *testing.T) {
+	t.Parallel()
+
+	guid := "01749246-95f6-57db-b7c3-2ae0e8be671f"
+
+	tests := []struct {
+		name    string
+		moveErr error
+		// wantLink is the target of the latest link, empty if the legacy report stays in place.
+		wantLink string
+	}{
+		{
+			name:     "moved",
+			wantLink: filepath.Join(guid, "v2_first.pdf"),
+		},
+		{
+			// Без записи в истории отчёт возвращается на место, ссылка не создаётся
+			name:    "history not updated",
+			moveErr: assert.AnError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			log := slog.New(slog.DiscardHandler)
+
+			// отчёт, построенный до появления истории, лежит на месте ссылки
+			outputDir := t.TempDir()
+			legacy := filepath.Join(outputDir, guid+".pdf")
+			require.NoError(t, os.WriteFile(legacy, []byte("legacy"), 0o644))
+
+			reports := make(chan *domain.ParseResult)
+			close(reports)
+
+			mockDevicesProvider := NewMockDevicesProvider(t)
+			mockDevicesProvider.EXPECT().DevicesByFile(mock.Anything, "plant-a", "first.tsv", guid).
+				Return([]*domain.Device{{UnitGUID: guid}}, nil)
+
+			mockReportGenerator := newMockReportGenerator(t, "pdf")
+			mockReportGenerator.EXPECT().GenerateReport(mock.Anything, mock.Anything, guid, mock.Anything, mock.Anything).RunAndReturn(writeReport)
+
+			// миграция записала старый отчёт первой версией
+			mockReportsTracker := NewMockReportsTracker(t)
+			mockReportsTracker.EXPECT().PendingReports(mock.Anything, "plant-a", "first.tsv").
+				Return([]*domain.Report{pendingReport("plant-a", "first.tsv", guid)}, nil)
+			mockReportsTracker.EXPECT().NextReportVersion(mock.Anything, "plant-a", guid).Return(2, nil)
+			mockReportsTracker.EXPECT().AddReportVersion(mock.Anything, mock.Anything).Return(nil)
+			mockReportsTracker.EXPECT().MoveReportFile(mock.Anything, "plant-a", legacy, filepath.Join(outputDir, guid, "legacy.pdf")).
+				Return(tt.moveErr).
+				Once()
+			mockReportsTracker.EXPECT().UpdateReport(mock.Anything, mock.MatchedBy(func(report *domain.Report) bool {
+				return report.Status == domain.ReportStatusDone
+			})).Return(nil)
+
+			mockReportJobs := NewMockReportJobs(t)
+			mockReportJobs.EXPECT().ResetReportJobs(mock.Anything).Return(nil)
+			mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).
+				Return([]*domain.ReportJob{{ID: 1, Source: "plant-a", FileName: "first.tsv"}}, nil).Once()
+			mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			mockReportJobs.EXPECT().AckReportJob(mock.Anything, int64(1)).Return(nil)
+
+			reporter := pipeline.NewReporter(log, 1, reportSettings, map[string]string{"plant-a": outputDir}, nil, reports, mockDevicesProvider, nil, mockReportsTracker, mockReportJobs, []pipeline.ReportGenerator{mockReportGenerator})
+			require.NoError(t, reporter.Run(t.Context()))
+
+			if tt.wantLink == "" {
+				content, err := os.ReadFile(legacy)
+				require.NoError(t, err)
+				assert.Equal(t, "legacy", string(content))
+				assert.NoFileExists(t, filepath.Join(outputDir, guid, "legacy.pdf"))
+				return
+			}
+
+			content, err := os.ReadFile(filepath.Join(outputDir, guid, "legacy.pdf"))
+			require.NoError(t, err)
+			assert.Equal(t, "legacy", string(content))
+
+			target, err := os.Readlink(legacy)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantLink, target)
+		})
+	}
+}
+
+func TestReporter_Run_CumulativeReport(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	guid := "01749246-95f6-57db-b7c3-2ae0e8be671f"
+
+	reports := make(chan *domain.ParseResult)
+	close(reports)
+
+	// в отчёт попадают записи устройства из всех файлов источника за окно
+	history := []*domain.Device{
+		{N: 1, UnitGUID: guid, Class: "alarm", Source: "plant-a", FileName: "first.tsv"},
+		{N: 1, UnitGUID: guid, Class: "working", Source: "plant-a", FileName: "second.tsv"},
+	}
+
+	mockDevicesProvider := NewMockDevicesProvider(t)
+	mockDevicesProvider.EXPECT().DevicesByUnit(mock.Anything, "plant-a", guid, mock.MatchedBy(func(since time.Time) bool {
+		ago := time.Since(since)
+		return ago >= 24*time.Hour && ago < 25*time.Hour
+	})).Return(history, nil)
+
+	mockReportGenerator := newMockReportGenerator(t, "pdf")
+	mockReportGenerator.EXPECT().GenerateReport(mock.Anything, mock.Anything, guid, "second.tsv", history).RunAndReturn(writeReport)
+
+	mockReportsTracker := NewMockReportsTracker(t)
+	mockReportsTracker.EXPECT().PendingReports(mock.Anything, "plant-a", "second.tsv").
+		Return([]*domain.Report{pendingReport("plant-a", "second.tsv", guid)}, nil)
+	mockReportsTracker.EXPECT().NextReportVersion(mock.Anything, "plant-a", guid).Return(2, nil)
+	mockReportsTracker.EXPECT().AddReportVersion(mock.Anything, mock.Anything).Return(nil)
+	mockReportsTracker.EXPECT().UpdateReport(mock.Anything, mock.MatchedBy(func(report *domain.Report) bool {
+		return report.Status == domain.ReportStatusDone
+	})).Return(nil)
+
+	mockReportJobs := NewMockReportJobs(t)
+	mockReportJobs.EXPECT().ResetReportJobs(mock.Anything).Return(nil)
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).
+		Return([]*domain.ReportJob{{ID: 1, Source: "plant-a", FileName: "second.tsv"}}, nil).Once()
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	mockReportJobs.EXPECT().AckReportJob(mock.Anything, int64(1)).Return(nil)
+
+	settings := reportSettings
+	settings.Mode = config.ReportModeCumulative
+	settings.Window = 24 * time.Hour
+
+	reporter := pipeline.NewReporter(log, 1, settings, map[string]string{"plant-a": t.TempDir()}, nil, reports, mockDevicesProvider, nil, mockReportsTracker, mockReportJobs, []pipeline.ReportGenerator{mockReportGenerator})
+	require.NoError(t, reporter.Run(t.Context()))
+}
+
+func TestReporter_Run_Formats(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	guid := "01749246-95f6-57db-b7c3-2ae0e8be671f"
+	outputDir := t.TempDir()
+
+	reports := make(chan *domain.ParseResult)
+	close(reports)
+
+	// записи читаются один раз на все форматы
+	mockDevicesProvider := NewMockDevicesProvider(t)
+	mockDevicesProvider.EXPECT().DevicesByFile(mock.Anything, "plant-a", "test.tsv", guid).
+		Return([]*domain.Device{{UnitGUID: guid}}, nil).Once()
+
+	// оформление источника передаётся генератору каждого формата
+	pdfGenerator := newMockReportGenerator(t, "pdf")
+	pdfGenerator.EXPECT().GenerateReport(filepath.Join(outputDir, guid, "v1_test.pdf"), config.ReportStyle{Layout: "branded"}, guid, "test.tsv", mock.Anything).RunAndReturn(writeReport)
+
+	jsonGenerator := newMockReportGenerator(t, "json")
+	jsonGenerator.EXPECT().GenerateReport(filepath.Join(outputDir, guid, "v1_test.json"), config.ReportStyle{Layout: "branded"}, guid, "test.tsv", mock.Anything).RunAndReturn(writeReport)
+
+	// генератор формата, которого нет в настройках, не вызывается
+	htmlGenerator := newMockReportGenerator(t, "html")
+
+	var saved *domain.Report
+
+	mockReportsTracker := NewMockReportsTracker(t)
+	mockReportsTracker.EXPECT().PendingReports(mock.Anything, "plant-a", "test.tsv").
+		Return([]*domain.Report{pendingReport("plant-a", "test.tsv", guid)}, nil)
+	mockReportsTracker.EXPECT().NextReportVersion(mock.Anything, "plant-a", guid).Return(1, nil)
+	mockReportsTracker.EXPECT().AddReportVersion(mock.Anything, mock.MatchedBy(func(version *domain.ReportVersion) bool {
+		return len(version.Files) == 2
+	})).Return(nil)
+	mockReportsTracker.EXPECT().UpdateReport(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, report *domain.Report) error {
+			saved = report
+			return nil
+		})
+
+	mockReportJobs := NewMockReportJobs(t)
+	mockReportJobs.EXPECT().ResetReportJobs(mock.Anything).Return(nil)
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).
+		Return([]*domain.ReportJob{{ID: 1, Source: "plant-a", FileName: "test.tsv"}}, nil).Once()
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	mockReportJobs.EXPECT().AckReportJob(mock.Anything, int64(1)).Return(nil)
+
+	settings := reportSettings
+	settings.Formats = []string{"pdf", "json"}
+
+	generators := []pipeline.ReportGenerator{pdfGenerator, jsonGenerator, htmlGenerator}
+	reporter := pipeline.NewReporter(log, 1, settings, map[string]string{"plant-a": outputDir}, map[string]config.ReportStyle{"plant-a": {Layout: "branded"}}, reports, mockDevicesProvider, nil, mockReportsTracker, mockReportJobs, generators)
+	require.NoError(t, reporter.Run(t.Context()))
+
+	require.NotNil(t, saved)
+	assert.Equal(t, domain.ReportStatusDone, saved.Status)
+	require.Len(t, saved.Files, 2)
+	assert.Equal(t, "pdf", saved.Files[0].Format)
+	assert.Equal(t, "json", saved.Files[1].Format)
+
+	for _, format := range settings.Formats {
+		target, err := os.Readlink(filepath.Join(outputDir, guid+"."+format))
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(guid, "v1_test."+format), target)
+	}
+}
+
+func TestReporter_Run_FleetReports(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	guid := "01749246-95f6-57db-b7c3-2ae0e8be671f"
+	outputDir := t.TempDir()
+
+	reports := make(chan *domain.ParseResult)
+	close(reports)
+
+	mockDevicesProvider := NewMockDevicesProvider(t)
+	mockDevicesProvider.EXPECT().DevicesByFile(mock.Anything, "plant-a", "test.tsv", guid).
+		Return([]*domain.Device{{UnitGUID: guid, InvID: "INV/7"}}, nil)
+
+	// отчёт по номеру собирается из устройств всех файлов источника
+	fileUnits := []*domain.FleetUnit{{UnitGUID: guid, InvID: "INV/7", Records: 1}}
+	invUnits := []*domain.FleetUnit{fileUnits[0], {UnitGUID: "other", InvID: "INV/7", Records: 3, Alarms: 1}}
+
+	mockFleetProvider := NewMockFleetProvider(t)
+	mockFleetProvider.EXPECT().UnitsByFile(mock.Anything, "plant-a", "test.tsv").Return(fileUnits, nil)
+	mockFleetProvider.EXPECT().UnitsByInvID(mock.Anything, "plant-a", "INV/7").Return(invUnits, nil)
+
+	writeFleetReport := func(outputPath string, _ config.ReportStyle, _ *domain.FleetReport) error {
+		return os.WriteFile(outputPath, []byte(reportContent), 0o644)
+	}
+
+	pdfGenerator := newMockReportGenerator(t, "pdf")
+	pdfGenerator.EXPECT().GenerateReport(mock.Anything, mock.Anything, guid, "test.tsv", mock.Anything).RunAndReturn(writeReport)
+	pdfGenerator.EXPECT().GenerateFleetReport(filepath.Join(outputDir, "fleet", "files", "test.tmp.pdf"), mock.Anything, &domain.FleetReport{
+		Source: "plant-a", FileName: "test.tsv", Units: fileUnits,
+	}).RunAndReturn(writeFleetReport)
+	// номер с разделителем пути не создаёт вложенный каталог
+	pdfGenerator.EXPECT().GenerateFleetReport(filepath.Join(outputDir, "fleet", "inventory", "INV_7.tmp.pdf"), mock.Anything, &domain.FleetReport{
+		Source: "plant-a", InvID: "INV/7", Units: invUnits,
+	}).RunAndReturn(writeFleetReport)
+
+	mockReportsTracker := NewMockReportsTracker(t)
+	mockReportsTracker.EXPECT().PendingReports(mock.Anything, "plant-a", "test.tsv").
+		Return([]*domain.Report{pendingReport("plant-a", "test.tsv", guid)}, nil)
+	mockReportsTracker.EXPECT().NextReportVersion(mock.Anything, "plant-a", guid).Return(1, nil)
+	mockReportsTracker.EXPECT().AddReportVersion(mock.Anything, mock.Anything).Return(nil)
+	mockReportsTracker.EXPECT().UpdateReport(mock.Anything, mock.Anything).Return(nil)
+
+	mockReportJobs := NewMockReportJobs(t)
+	mockReportJobs.EXPECT().ResetReportJobs(mock.Anything).Return(nil)
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).
+		Return([]*domain.ReportJob{{ID: 1, Source: "plant-a", FileName: "test.tsv"}}, nil).Once()
+	mockReportJobs.EXPECT().DueReportJobs(mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	mockReportJobs.EXPECT().AckReportJob(mock.Anything, int64(1)).Return(nil)
+
+	settings := reportSettings
+	settings.Fleet = true
+
+	reporter := pipeline.NewReporter(log, 1, settings, map[string]string{"plant-a": outputDir}, nil, reports, mockDevicesProvider, mockFleetProvider, mockReportsTracker, mockReportJobs, []pipeline.ReportGenerator{pdfGenerator})
+	require.NoError(t, reporter.Run(t.Context()))
+
+	for _, name := range []string{filepath.Join("files", "test.pdf"), filepath.Join("inventory", "INV_7.pdf")} {
+		content, err := os.ReadFile(filepath.Join(outputDir, "fleet", name))
+		require.NoError(t, err)
+		assert.Equal(t, reportContent, string(content))
+	}
+}
+
+var reportSettings = config.Reports{
+	Mode:         config.ReportModeFile,
+	Formats:      []string{"pdf"},
+	NameTemplate: "{guid}/v{version}_{file}",
+	LatestLink:   true,
+}
+
+const reportContent = "%PDF-1.4"
+
+// writeReport stands in for the generator: the reporter stats and hashes the written file.
+func writeReport(outputPath string, _ config.ReportStyle, _, _ string, _ []*domain.Device) error {
+	return os.WriteFile(outputPath, []byte(reportContent), 0o644)
+}
+
+func newMockReportGenerator(t *testing.T, format string) *MockReportGenerator {
+	generator := NewMockReportGenerator(t)
+	generator.EXPECT().Format().Return(format)
+
+	return generator
+}
+
+func pendingReport(source, fileName, guid string) *domain.Report {
+	return &domain.Report{Source: source, FileName: fileName, UnitGUID: guid, Status: domain.ReportStatusPending}
+}
@@ -0,0 +1,128 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/kurochkinivan/device_reporter/internal/config"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+)
+
+// ruleChecker applies the configured validation rules to the rows of one file.
+// It keeps the values seen by unique rules, so a new checker is needed per file.
+type ruleChecker struct {
+	rules []*rule
+}
+
+type rule struct {
+	config.Rule
+	pattern *regexp.Regexp
+	seen    map[string]int // значение -> строка, где оно встретилось впервые
+}
+
+func newRuleChecker(rules []config.Rule) (*ruleChecker, error) {
+	checker := &ruleChecker{rules: make([]*rule, 0, len(rules))}
+
+	for _, r := range rules {
+		compiled := &rule{Rule: r}
+
+		switch r.Kind {
+		case config.RuleRegex:
+			pattern, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: invalid pattern: %w", r.Name, err)
+			}
+			compiled.pattern = pattern
+		case config.RuleUnique:
+			compiled.seen = make(map[string]int)
+		}
+
+		checker.rules = append(checker.rules, compiled)
+	}
+
+	return checker, nil
+}
+
+// Check returns the violations of a decoded row. header holds the canonical column names.
+func (c *ruleChecker) Check(line int, header, record []string) []*domain.RowError {
+	var violations []*domain.RowError
+
+	for _, r := range c.rules {
+		values := make([]string, len(r.Columns))
+		for i, column := range r.Columns {
+			if j := slices.Index(header, column); j >= 0 && j < len(record) {
+				values[i] = record[j]
+			}
+		}
+
+		reason := r.check(line, values)
+		if reason == "" {
+			continue
+		}
+
+		violations = append(violations, &domain.RowError{
+			Column: strings.Join(r.Columns, ","),
+			Value:  strings.Join(values, ","),
+			Reason: reason,
+			Rule:   r.Name,
+		})
+	}
+
+	return violations
+}
+
+// check returns why the values violate the rule, or an empty string.
+func (r *rule) check(line int, values []string) string {
+	if r.Kind == config.RuleUnique {
+		if !slices.ContainsFunc(values, func(v string) bool { return v != "" }) {
+			return ""
+		}
+
+		key := strings.Join(values, "\x00")
+		if first, ok := r.seen[key]; ok {
+			return fmt.Sprintf("duplicates line %d", first)
+		}
+		r.seen[key] = line
+
+		return ""
+	}
+
+	value := values[0]
+	if value == "" {
+		return ""
+	}
+
+	switch r.Kind {
+	case config.RuleUUID:
+		if uuid.Validate(value) != nil {
+			return "is not a valid UUID"
+		}
+
+	case config.RuleOneOf:
+		if !slices.Contains(r.Values, value) {
+			return "is not one of " + strings.Join(r.Values, ", ")
+		}
+
+	case config.RuleRange:
+		n, err := strconv.Atoi(value)
+		switch {
+		case err != nil:
+			return "is not a number"
+		case r.Min != nil && n < *r.Min:
+			return fmt.Sprintf("is less than %d", *r.Min)
+		case r.Max != nil && n > *r.Max:
+			return fmt.Sprintf("is greater than %d", *r.Max)
+		}
+
+	case config.RuleRegex:
+		if !r.pattern.MatchString(value) {
+			return "does not match " + r.Pattern
+		}
+	}
+
+	return ""
+}
@@ -2,125 +2,716 @@ package pipeline
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
+	"github.com/kurochkinivan/device_reporter/internal/config"
 	"github.com/kurochkinivan/device_reporter/internal/domain"
 )
 
+const doneMarkerSuffix = ".done"
+
 type Scanner struct {
 	log           *slog.Logger
-	watchDir      string
-	scanInterval  time.Duration
-	files         chan<- string
+	source        string
+	cfg           config.Scanner
+	files         chan<- *domain.File
 	filesProvider FilesProvider
 	fileUpdater   FileUpdater
+	fileArchiver  FileArchiver
+	observations  map[string]*observation
+	// bundles remembers each bundle whose members have been looked at,
+	// so an unchanged bundle is not reopened on every scan.
+	bundles map[string]*bundle
+}
+
+// observation tracks a file that has not settled yet.
+type observation struct {
+	size    int64
+	modTime time.Time
+	count   int
+}
+
+// bundle is a bundle expanded at its version. It is archived as a whole
+// once every member has reached a final status.
+type bundle struct {
+	version observation
+	// members are the names of the members that are processed, the ignored ones are left out.
+	members  []string
+	archived bool
 }
 
 func NewScanner(
 	log *slog.Logger,
-	watchDir string,
-	scanInterval time.Duration,
-	files chan<- string,
+	source string,
+	cfg config.Scanner,
+	files chan<- *domain.File,
 	filesProvider FilesProvider,
 	fileUpdater FileUpdater,
+	fileArchiver FileArchiver,
 ) *Scanner {
 	return &Scanner{
 		log:           log,
-		watchDir:      watchDir,
-		scanInterval:  scanInterval,
+		source:        source,
+		cfg:           cfg,
 		files:         files,
 		filesProvider: filesProvider,
 		fileUpdater:   fileUpdater,
+		fileArchiver:  fileArchiver,
+		observations:  make(map[string]*observation),
+		bundles:       make(map[string]*bundle),
 	}
 }
 
 func (s *Scanner) Run(ctx context.Context) error {
-	defer close(s.files)
+	if s.cfg.WatchMode == config.WatchModeNotify {
+		return s.watch(ctx)
+	}
+
+	return s.poll(ctx)
+}
 
-	ticker := time.NewTicker(s.scanInterval)
+func (s *Scanner) poll(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.ScanInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			s.log.DebugContext(ctx, "scan cycle started")
+			s.scan(ctx)
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// watch reacts to inotify events on the watch directory. The full scan still runs
+// on start and every reconcile interval to pick up files whose events were lost.
+func (s *Scanner) watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := s.addWatches(watcher, s.cfg.WatchDirectory); err != nil {
+		return fmt.Errorf("failed to watch directory %q: %w", s.cfg.WatchDirectory, err)
+	}
+
+	s.scan(ctx)
+
+	ticker := time.NewTicker(s.cfg.ReconcileInterval)
+	defer ticker.Stop()
+
+	settleTicker := time.NewTicker(s.cfg.Settle.Interval)
+	defer settleTicker.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return errors.New("watcher events channel closed")
+			}
+
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+				continue
+			}
+
+			if s.cfg.Recursive && event.Has(fsnotify.Create) {
+				if info, err := os.Lstat(event.Name); err == nil && info.IsDir() {
+					if err := s.addWatches(watcher, event.Name); err != nil {
+						s.log.ErrorContext(ctx, "failed to watch directory",
+							slog.String("dir", event.Name),
+							slog.String("err", err.Error()),
+						)
+					}
+
+					// файлы могли появиться до того, как каталог попал под наблюдение
+					s.scan(ctx)
+					continue
+				}
+			}
 
-			err := s.scanFiles(ctx)
-			if err != nil {
-				s.log.ErrorContext(ctx, "failed to scan files", slog.String("err", err.Error()))
+			filename := event.Name
+			if s.cfg.Settle.RequireDoneMarker && strings.HasSuffix(filename, doneMarkerSuffix) {
+				filename = strings.TrimSuffix(filename, doneMarkerSuffix)
 			}
 
+			// файл ещё дописывается: его перепроверит settleTicker, хешировать на каждую запись незачем
+			if _, ok := s.observations[s.name(filename)]; ok && !event.Has(fsnotify.Create) {
+				continue
+			}
+
+			s.scanFileLogged(ctx, filename)
+
+		case <-settleTicker.C:
+			for name := range s.observations {
+				s.scanFileLogged(ctx, s.path(name))
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return errors.New("watcher errors channel closed")
+			}
+
+			s.log.ErrorContext(ctx, "watcher error", slog.String("err", err.Error()))
+
+			// часть событий потеряна, сверяем директорию целиком
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				s.scan(ctx)
+			}
+
+		case <-ticker.C:
+			s.scan(ctx)
+
 		case <-ctx.Done():
 			return ctx.Err()
 		}
 	}
 }
 
+// addWatches adds root and, in recursive mode, every nested directory to the watcher.
+func (s *Scanner) addWatches(watcher *fsnotify.Watcher, root string) error {
+	if !s.cfg.Recursive {
+		return watcher.Add(root)
+	}
+
+	return filepath.WalkDir(root, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		if filename != s.cfg.WatchDirectory && s.ignoredDir(s.name(filename)) {
+			return filepath.SkipDir
+		}
+
+		return watcher.Add(filename)
+	})
+}
+
+func (s *Scanner) scan(ctx context.Context) {
+	s.log.DebugContext(ctx, "scan cycle started")
+
+	if err := s.scanFiles(ctx); err != nil {
+		s.log.ErrorContext(ctx, "failed to scan files", slog.String("err", err.Error()))
+	}
+}
+
 func (s *Scanner) scanFiles(ctx context.Context) error {
-	filesMap, err := s.extractFilesFromDB(ctx)
+	snapshot, err := s.extractFilesFromDB(ctx)
 	if err != nil {
 		return err
 	}
 
-	entries, err := os.ReadDir(s.watchDir)
-	if err != nil {
-		return fmt.Errorf("failed to read directory %q", s.watchDir)
-	}
+	seen := make(map[string]struct{})
 
-	for _, entry := range entries {
-		err := s.processEntry(ctx, entry, filesMap)
+	err = filepath.WalkDir(s.cfg.WatchDirectory, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if filename == s.cfg.WatchDirectory {
+				return fmt.Errorf("failed to read directory %q: %w", s.cfg.WatchDirectory, err)
+			}
+
+			s.log.ErrorContext(ctx, "failed to read directory, skipping",
+				slog.String("dir", filename),
+				slog.String("err", err.Error()),
+			)
+			return nil
+		}
+
+		if filename == s.cfg.WatchDirectory {
+			return nil
+		}
+
+		name := s.name(filename)
+
+		if entry.IsDir() {
+			if !s.cfg.Recursive || s.ignoredDir(name) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		seen[name] = struct{}{}
+
+		if s.ignored(name) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err == nil {
+			err = s.processFile(ctx, name, info, snapshot)
+		}
 
 		if err != nil {
 			s.log.ErrorContext(ctx, "failed process entry, skipping file",
-				slog.String("filename", entry.Name()),
+				slog.String("filename", name),
 				slog.String("err", err.Error()),
 			)
-			continue
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// забываем файлы, которые удалили, не дождавшись их стабилизации
+	for name := range s.observations {
+		if _, ok := seen[name]; !ok {
+			delete(s.observations, name)
+		}
+	}
+
+	for name := range s.bundles {
+		if _, ok := seen[name]; !ok {
+			delete(s.bundles, name)
 		}
 	}
 
 	return nil
 }
 
-func (s *Scanner) extractFilesFromDB(ctx context.Context) (map[string]domain.Status, error) {
-	files, err := s.filesProvider.Files(ctx)
+func (s *Scanner) scanFileLogged(ctx context.Context, filename string) {
+	if err := s.scanFile(ctx, filename); err != nil {
+		s.log.ErrorContext(ctx, "failed to process event, skipping file",
+			slog.String("filename", filename),
+			slog.String("err", err.Error()),
+		)
+	}
+}
+
+// scanFile processes a single file reported by the watcher,
+// looking up only its own rows instead of the whole files table.
+func (s *Scanner) scanFile(ctx context.Context, filename string) error {
+	name := s.name(filename)
+
+	info, err := os.Lstat(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			delete(s.observations, name)
+			delete(s.bundles, name)
+			return nil
+		}
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	if info.IsDir() || s.ignored(name) {
+		return nil
+	}
+
+	return s.processFile(ctx, name, info, filesDB{provider: s.filesProvider, source: s.source})
+}
+
+func (s *Scanner) extractFilesFromDB(ctx context.Context) (*filesSnapshot, error) {
+	files, err := s.filesProvider.Files(ctx, s.source)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get files: %w", err)
 	}
 
-	filesMap := make(map[string]domain.Status, len(files))
+	snapshot := &filesSnapshot{
+		byName: make(map[string]*domain.File, len(files)),
+		byHash: make(map[string]*domain.File, len(files)),
+	}
 	for _, file := range files {
-		filesMap[file.Name] = file.Status
+		snapshot.add(file)
 	}
 
-	return filesMap, nil
+	return snapshot, nil
 }
 
-func (s *Scanner) processEntry(ctx context.Context, entry os.DirEntry, filesMap map[string]domain.Status) error {
-	if entry.IsDir() {
-		return nil
+// processFile claims the file if it is new or its content has changed. Byte-identical
+// copies of already known files are recorded as duplicates and never parsed.
+func (s *Scanner) processFile(ctx context.Context, name string, info fs.FileInfo, lookup filesLookup) error {
+	if bundleKindOf(name) != bundleNone {
+		return s.processBundle(ctx, name, info, lookup)
+	}
+
+	known, err := lookup.File(ctx, name)
+	if err != nil && !errors.Is(err, domain.ErrFileNotFound) {
+		return fmt.Errorf("failed to get file: %w", err)
 	}
 
-	status, ok := filesMap[entry.Name()]
-	if ok && status != domain.StatusPending {
+	if !s.changed(known, info) {
 		return nil
 	}
 
-	err := s.fileUpdater.UpdateOrCreateFile(ctx, &domain.File{
-		Name:   entry.Name(),
-		Status: domain.StatusProcessing,
+	settled, err := s.settled(name, info)
+	if err != nil || !settled {
+		return err
+	}
+
+	modifiedAt := modTime(info)
+	file := &domain.File{
+		Source:     s.source,
+		Name:       name,
+		Path:       s.path(name),
+		Size:       info.Size(),
+		ModifiedAt: &modifiedAt,
+	}
+
+	file.Hash, err = hashFile(file.Path)
+	if err != nil {
+		return err
+	}
+
+	return s.processContent(ctx, file, known, lookup)
+}
+
+// processBundle treats every member of a zip or tar.gz bundle as its own logical file.
+func (s *Scanner) processBundle(ctx context.Context, name string, info fs.FileInfo, lookup filesLookup) error {
+	version := observation{size: info.Size(), modTime: info.ModTime()}
+	if expanded, ok := s.bundles[name]; ok && expanded.version.size == version.size && expanded.version.modTime.Equal(version.modTime) {
+		return s.archiveBundle(ctx, name, expanded, lookup)
+	}
+
+	settled, err := s.settled(name, info)
+	if err != nil || !settled {
+		return err
+	}
+
+	filename := s.path(name)
+	failed := false
+	expanded := &bundle{version: version}
+
+	err = walkBundle(filename, func(member string, info fs.FileInfo, r io.Reader) error {
+		if s.ignoredMember(member) {
+			return nil
+		}
+
+		memberName := name + memberSeparator + member
+		expanded.members = append(expanded.members, memberName)
+
+		if err := s.processMember(ctx, filename, memberName, member, info, r, lookup); err != nil {
+			failed = true
+			s.log.ErrorContext(ctx, "failed process bundle member, skipping",
+				slog.String("filename", name),
+				slog.String("member", member),
+				slog.String("err", err.Error()),
+			)
+		}
+
+		return nil
 	})
 	if err != nil {
+		// битый архив не открываем заново, пока он не изменится; архивировать его нечего
+		s.bundles[name] = &bundle{version: version}
+		return fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	// при ошибке по отдельным файлам бандл перечитается на следующем сканировании
+	if failed {
+		return nil
+	}
+
+	s.bundles[name] = expanded
+
+	return s.archiveBundle(ctx, name, expanded, lookup)
+}
+
+// archiveBundle archives the bundle once all of its members are processed. A bundle with
+// a failed member counts as failed, the error messages of its members go to the sidecar.
+func (s *Scanner) archiveBundle(ctx context.Context, name string, b *bundle, lookup filesLookup) error {
+	if b.archived || len(b.members) == 0 {
+		return nil
+	}
+
+	file := &domain.File{Source: s.source, Name: name, Path: s.path(name), Status: domain.StatusDone}
+	var messages []string
+
+	for _, member := range b.members {
+		known, err := lookup.File(ctx, member)
+		if errors.Is(err, domain.ErrFileNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get bundle member: %w", err)
+		}
+
+		switch known.Status {
+		case domain.StatusError:
+			file.Status = domain.StatusError
+		case domain.StatusDoneWithErrors:
+			if file.Status == domain.StatusDone {
+				file.Status = domain.StatusDoneWithErrors
+			}
+		case domain.StatusDone, domain.StatusDuplicate:
+		default:
+			// файл ещё обрабатывается, бандл нужен парсеру
+			return nil
+		}
+
+		if known.ErrorMessage != "" {
+			messages = append(messages, strings.TrimPrefix(member, name+memberSeparator)+": "+known.ErrorMessage)
+		}
+	}
+	slices.Sort(messages)
+	file.ErrorMessage = strings.Join(messages, "\n")
+
+	if err := s.fileArchiver.Archive(file); err != nil {
+		return fmt.Errorf("failed to archive bundle: %w", err)
+	}
+	b.archived = true
+
+	s.log.InfoContext(ctx, "bundle processed", slog.String("filename", name), slog.String("status", string(file.Status)))
+
+	return nil
+}
+
+func (s *Scanner) processMember(
+	ctx context.Context,
+	filename, name, member string,
+	info fs.FileInfo,
+	r io.Reader,
+	lookup filesLookup,
+) error {
+	known, err := lookup.File(ctx, name)
+	if err != nil && !errors.Is(err, domain.ErrFileNotFound) {
+		return fmt.Errorf("failed to get file: %w", err)
+	}
+
+	if !s.changed(known, info) {
+		return nil
+	}
+
+	modifiedAt := modTime(info)
+	file := &domain.File{
+		Source:     s.source,
+		Name:       name,
+		Path:       filename,
+		Member:     member,
+		Size:       info.Size(),
+		ModifiedAt: &modifiedAt,
+	}
+
+	file.Hash, err = hashReader(r)
+	if err != nil {
+		return err
+	}
+
+	return s.processContent(ctx, file, known, lookup)
+}
+
+func (s *Scanner) processContent(ctx context.Context, file, known *domain.File, lookup filesLookup) error {
+	if known != nil && known.Status != domain.StatusPending && (known.Hash == file.Hash || known.Hash == "") {
+		// содержимое не изменилось (или файл обработан до появления хешей):
+		// запоминаем размер и mtime, чтобы не хешировать файл повторно
+		known.Size, known.ModifiedAt, known.Hash = file.Size, file.ModifiedAt, file.Hash
+		return s.fileUpdater.UpdateOrCreateFile(ctx, known)
+	}
+
+	original, err := lookup.FileByHash(ctx, file.Hash)
+	switch {
+	case err == nil && original.Name != file.Name:
+		return s.markDuplicate(ctx, file, original, lookup)
+	case err != nil && !errors.Is(err, domain.ErrFileNotFound):
+		return fmt.Errorf("failed to get file by hash: %w", err)
+	}
+
+	if known != nil && known.Status != domain.StatusPending {
+		s.log.InfoContext(ctx, "file content changed, reingesting", slog.String("filename", file.Name))
+	}
+
+	return s.claim(ctx, file, lookup)
+}
+
+// changed reports whether the file on disk may differ from what has been processed.
+// Size and mtime are compared first so unchanged files are never re-hashed.
+func (s *Scanner) changed(known *domain.File, info fs.FileInfo) bool {
+	switch {
+	case known == nil, known.Status == domain.StatusPending:
+		return true
+	case known.Status == domain.StatusProcessing, s.cfg.OnChange != config.ChangePolicyReingest:
+		return false
+	case known.ModifiedAt == nil:
+		return true
+	default:
+		return known.Size != info.Size() || !known.ModifiedAt.Equal(modTime(info))
+	}
+}
+
+// name converts a path inside the watch directory into the name stored in the files table:
+// slash-separated and relative to the watch directory.
+func (s *Scanner) name(filename string) string {
+	rel, err := filepath.Rel(s.cfg.WatchDirectory, filename)
+	if err != nil {
+		return filepath.Base(filename)
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+func (s *Scanner) path(name string) string {
+	return filepath.Join(s.cfg.WatchDirectory, filepath.FromSlash(name))
+}
+
+func (s *Scanner) ignored(name string) bool {
+	if s.cfg.IgnoreHidden && strings.HasPrefix(path.Base(name), ".") {
+		return true
+	}
+
+	if s.cfg.Settle.RequireDoneMarker && strings.HasSuffix(name, doneMarkerSuffix) {
+		return true
+	}
+
+	if matchAny(s.cfg.Exclude, name) {
+		return true
+	}
+
+	// include проверяется для файлов внутри бандла, а у .gz — для имени без расширения
+	switch {
+	case bundleKindOf(name) != bundleNone:
+		return false
+	case gzipped(name):
+		name = strings.TrimSuffix(name, ".gz")
+	}
+
+	return len(s.cfg.Include) > 0 && !matchAny(s.cfg.Include, name)
+}
+
+func (s *Scanner) ignoredMember(member string) bool {
+	if s.cfg.IgnoreHidden && strings.HasPrefix(path.Base(member), ".") {
+		return true
+	}
+
+	if matchAny(s.cfg.Exclude, member) {
+		return true
+	}
+
+	return len(s.cfg.Include) > 0 && !matchAny(s.cfg.Include, member)
+}
+
+func (s *Scanner) ignoredDir(name string) bool {
+	if s.cfg.IgnoreHidden && strings.HasPrefix(path.Base(name), ".") {
+		return true
+	}
+
+	return matchAny(s.cfg.Exclude, name)
+}
+
+// matchAny matches patterns against the base name, or against the whole
+// relative name if the pattern contains a slash.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		target := path.Base(name)
+		if strings.Contains(pattern, "/") {
+			target = name
+		}
+
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// settled records one more observation of the file and reports whether it has kept
+// the same size and mtime long enough and, if required, got its done marker.
+func (s *Scanner) settled(name string, info fs.FileInfo) (bool, error) {
+	obs, ok := s.observations[name]
+	if !ok || obs.size != info.Size() || !obs.modTime.Equal(info.ModTime()) {
+		obs = &observation{size: info.Size(), modTime: info.ModTime()}
+		s.observations[name] = obs
+	}
+	obs.count++
+
+	if obs.count < s.cfg.Settle.Observations {
+		return false, nil
+	}
+
+	if s.cfg.Settle.RequireDoneMarker {
+		if _, err := os.Stat(s.path(name) + doneMarkerSuffix); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to stat done marker: %w", err)
+		}
+	}
+
+	delete(s.observations, name)
+
+	return true, nil
+}
+
+func (s *Scanner) claim(ctx context.Context, file *domain.File, lookup filesLookup) error {
+	file.Status = domain.StatusProcessing
+
+	if err := s.fileUpdater.UpdateOrCreateFile(ctx, file); err != nil {
 		return fmt.Errorf("failed to update file status: %w", err)
 	}
 
-	s.log.DebugContext(ctx, "updated file status to processing", slog.String("filename", entry.Name()))
+	lookup.add(file)
 
-	s.files <- filepath.Join(s.watchDir, entry.Name())
+	s.log.DebugContext(ctx, "updated file status to processing", slog.String("filename", file.Name))
+
+	s.files <- file
 
 	return nil
 }
+
+func (s *Scanner) markDuplicate(ctx context.Context, file, original *domain.File, lookup filesLookup) error {
+	now := time.Now()
+	file.Status = domain.StatusDuplicate
+	file.DuplicateOf = original.Name
+	file.ProcessedAt = &now
+
+	if err := s.fileUpdater.UpdateOrCreateFile(ctx, file); err != nil {
+		return fmt.Errorf("failed to update file status: %w", err)
+	}
+
+	lookup.add(file)
+
+	s.log.InfoContext(ctx, "skipped duplicate file",
+		slog.String("filename", file.Name),
+		slog.String("duplicate_of", original.Name),
+	)
+
+	// дубликат убирается, как и обработанный файл, только после того, как статус сохранён
+	if err := s.fileArchiver.Archive(file); err != nil {
+		s.log.ErrorContext(ctx, "failed to archive file", slog.String("filename", file.Name), slog.String("err", err.Error()))
+	}
+
+	return nil
+}
+
+func hashFile(filename string) (_ string, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() { err = errors.Join(err, f.Close()) }()
+
+	return hashReader(f)
+}
+
+func hashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// modTime truncates mtime to the precision of a PostgreSQL timestamp
+// so that values read back from the files table compare equal.
+func modTime(info fs.FileInfo) time.Time {
+	return info.ModTime().Truncate(time.Microsecond)
+}
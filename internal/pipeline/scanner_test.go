@@ -2,12 +2,15 @@ package pipeline_test
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/kurochkinivan/device_reporter/internal/config"
 	"github.com/kurochkinivan/device_reporter/internal/domain"
 	"github.com/kurochkinivan/device_reporter/internal/pipeline"
 	"github.com/stretchr/testify/assert"
@@ -29,23 +32,23 @@ func TestScanner_Run_HappyPath(t *testing.T) {
 	filename := f.Name()
 
 	scanInterval := 1 * time.Millisecond
-	files := make(chan string, 1)
+	files := make(chan *domain.File, 1)
 
 	// Файла еще нет в БД
 	filesProvider := NewMockFilesProvider(t)
 	filesProvider.EXPECT().
-		Files(mock.Anything).
+		Files(mock.Anything, testSource).
 		Return([]*domain.File{}, nil)
 
 	// Ожидается запрос на изменение нашего файла
 	filesStatusUpdater := NewMockFileUpdater(t)
 	filesStatusUpdater.EXPECT().
 		UpdateOrCreateFile(mock.Anything, mock.MatchedBy(func(f *domain.File) bool {
-			return f.Name == filepath.Base(filename) && f.Status == domain.StatusProcessing
+			return f.Source == testSource && f.Name == filepath.Base(filename) && f.Status == domain.StatusProcessing
 		})).
 		Return(nil)
 
-	scanner := pipeline.NewScanner(log, tmpDir, scanInterval, files, filesProvider, filesStatusUpdater)
+	scanner := pipeline.NewScanner(log, testSource, pollConfig(tmpDir, scanInterval), files, filesProvider, filesStatusUpdater, nil)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -58,7 +61,8 @@ func TestScanner_Run_HappyPath(t *testing.T) {
 	// Ждем файл в канале
 	select {
 	case got := <-files:
-		assert.Equal(t, filename, got)
+		assert.Equal(t, filename, got.Path)
+		assert.Equal(t, filepath.Base(filename), got.Name)
 	case <-time.After(10 * time.Millisecond):
 		t.Fatal("timeout: file was not sent to channel")
 	}
@@ -89,12 +93,12 @@ func TestScanner_Run_FileInDBWithPendingStatus(t *testing.T) {
 	filename := f.Name()
 
 	scanInterval := 1 * time.Millisecond
-	files := make(chan string, 1)
+	files := make(chan *domain.File, 1)
 
 	// Файла в БД со статусом Pending
 	filesProvider := NewMockFilesProvider(t)
 	filesProvider.EXPECT().
-		Files(mock.Anything).
+		Files(mock.Anything, testSource).
 		Return([]*domain.File{{Name: filepath.Base(filename), Status: domain.StatusPending}}, nil)
 
 	// Ожидается запрос на изменение нашего файла
@@ -105,7 +109,7 @@ func TestScanner_Run_FileInDBWithPendingStatus(t *testing.T) {
 		})).
 		Return(nil)
 
-	scanner := pipeline.NewScanner(log, tmpDir, scanInterval, files, filesProvider, fileUpdater)
+	scanner := pipeline.NewScanner(log, testSource, pollConfig(tmpDir, scanInterval), files, filesProvider, fileUpdater, nil)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -118,7 +122,8 @@ func TestScanner_Run_FileInDBWithPendingStatus(t *testing.T) {
 	// Ждем файл в канале
 	select {
 	case got := <-files:
-		assert.Equal(t, filename, got)
+		assert.Equal(t, filename, got.Path)
+		assert.Equal(t, filepath.Base(filename), got.Name)
 	case <-time.After(10 * time.Millisecond):
 		t.Fatal("timeout: file was not sent to channel")
 	}
@@ -153,12 +158,12 @@ func TestScanner_Run_FilesAlreadyInDB(t *testing.T) {
 	}
 
 	scanInterval := 1 * time.Millisecond
-	files := make(chan string, 1)
+	files := make(chan *domain.File, 1)
 
 	// Файлы уже в БД со статусами НЕ pending
 	filesProvider := NewMockFilesProvider(t)
 	filesProvider.EXPECT().
-		Files(mock.Anything).
+		Files(mock.Anything, testSource).
 		Return([]*domain.File{
 			{Name: filenames[0], Status: domain.StatusProcessing},
 			{Name: filenames[1], Status: domain.StatusDone},
@@ -168,7 +173,7 @@ func TestScanner_Run_FilesAlreadyInDB(t *testing.T) {
 	// Не ожидается запросов на изменение файла
 	filesStatusUpdater := NewMockFileUpdater(t)
 
-	scanner := pipeline.NewScanner(log, tmpDir, scanInterval, files, filesProvider, filesStatusUpdater)
+	scanner := pipeline.NewScanner(log, testSource, pollConfig(tmpDir, scanInterval), files, filesProvider, filesStatusUpdater, nil)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -181,7 +186,7 @@ func TestScanner_Run_FilesAlreadyInDB(t *testing.T) {
 	// Ждем окончания сканирования
 	select {
 	case got := <-files:
-		t.Fatalf("didn't expect files, got %q", got)
+		t.Fatalf("didn't expect files, got %q", got.Name)
 	case <-time.After(scanInterval * 10):
 	}
 
@@ -196,3 +201,647 @@ func TestScanner_Run_FilesAlreadyInDB(t *testing.T) {
 		t.Fatal("timeout: scanner did not stop")
 	}
 }
+
+func TestScanner_Run_NotifyMode(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	tmpDir := t.TempDir()
+
+	// Сверка по таймеру не должна успеть сработать, а интервал сканирования в режиме notify не используется
+	cfg := config.Scanner{
+		WatchDirectory:    tmpDir,
+		WatchMode:         config.WatchModeNotify,
+		ScanInterval:      time.Millisecond,
+		ReconcileInterval: time.Hour,
+		Settle:            config.Settle{Interval: time.Hour},
+	}
+	files := make(chan *domain.File, 1)
+
+	// Стартовая сверка видит пустую директорию
+	scanned := make(chan struct{})
+	filesProvider := NewMockFilesProvider(t)
+	filesProvider.EXPECT().
+		Files(mock.Anything, testSource).
+		Run(func(context.Context, string) { close(scanned) }).
+		Return([]*domain.File{}, nil).
+		Once()
+
+	filename := filepath.Join(tmpDir, "new.tsv")
+
+	// Файл из события ищется в БД по имени
+	filesProvider.EXPECT().
+		File(mock.Anything, testSource, filepath.Base(filename)).
+		Return(nil, domain.ErrFileNotFound)
+	filesProvider.EXPECT().
+		FileByHash(mock.Anything, testSource, mock.Anything).
+		Return(nil, domain.ErrFileNotFound)
+
+	fileUpdater := NewMockFileUpdater(t)
+	fileUpdater.EXPECT().
+		UpdateOrCreateFile(mock.Anything, mock.MatchedBy(func(f *domain.File) bool {
+			return f.Name == filepath.Base(filename) && f.Status == domain.StatusProcessing
+		})).
+		Return(nil)
+
+	scanner := pipeline.NewScanner(log, testSource, cfg, files, filesProvider, fileUpdater, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- scanner.Run(ctx)
+	}()
+
+	// Ждем стартовую сверку, после нее наблюдатель уже запущен
+	select {
+	case <-scanned:
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("timeout: initial scan did not happen")
+	}
+
+	require.NoError(t, os.WriteFile(filename, nil, 0o644))
+
+	// Ждем файл в канале
+	select {
+	case got := <-files:
+		assert.Equal(t, filename, got.Path)
+		assert.Equal(t, filepath.Base(filename), got.Name)
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("timeout: file was not sent to channel")
+	}
+
+	// Отмена контекста, сканнер должен остановиться
+	cancel()
+
+	// Ждем завершения горутины
+	select {
+	case err := <-errChan:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("timeout: scanner did not stop")
+	}
+}
+
+func TestScanner_Run_SkipsExcludedFiles(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	tmpDir := t.TempDir()
+
+	// Недописанные и скрытые файлы
+	for _, name := range []string{"data.tsv.part", "data.tmp", ".data.tsv"} {
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, name), nil, 0o644))
+	}
+
+	scanInterval := 1 * time.Millisecond
+	files := make(chan *domain.File, 1)
+
+	cfg := pollConfig(tmpDir, scanInterval)
+	cfg.Exclude = []string{"*.tmp", "*.part"}
+	cfg.IgnoreHidden = true
+
+	filesProvider := NewMockFilesProvider(t)
+	filesProvider.EXPECT().
+		Files(mock.Anything, testSource).
+		Return([]*domain.File{}, nil)
+
+	// Не ожидается запросов на изменение файла
+	fileUpdater := NewMockFileUpdater(t)
+
+	scanner := pipeline.NewScanner(log, testSource, cfg, files, filesProvider, fileUpdater, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- scanner.Run(ctx)
+	}()
+
+	// Ждем окончания сканирования
+	select {
+	case got := <-files:
+		t.Fatalf("didn't expect files, got %q", got.Name)
+	case <-time.After(scanInterval * 10):
+	}
+
+	// Отмена контекста, сканнер должен остановиться
+	cancel()
+
+	// Ждем завершения горутины
+	select {
+	case err := <-errChan:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("timeout: scanner did not stop")
+	}
+}
+
+func TestScanner_Run_WaitsForDoneMarker(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	tmpDir := t.TempDir()
+
+	filename := filepath.Join(tmpDir, "data.tsv")
+	require.NoError(t, os.WriteFile(filename, []byte("n\n"), 0o644))
+
+	scanInterval := 1 * time.Millisecond
+	files := make(chan *domain.File, 1)
+
+	cfg := pollConfig(tmpDir, scanInterval)
+	cfg.Settle = config.Settle{
+		Observations:      2,
+		RequireDoneMarker: true,
+	}
+
+	filesProvider := NewMockFilesProvider(t)
+	filesProvider.EXPECT().
+		Files(mock.Anything, testSource).
+		Return([]*domain.File{}, nil)
+
+	// Ожидается запрос только на сам файл, но не на маркер
+	fileUpdater := NewMockFileUpdater(t)
+	fileUpdater.EXPECT().
+		UpdateOrCreateFile(mock.Anything, mock.MatchedBy(func(f *domain.File) bool {
+			return f.Name == filepath.Base(filename) && f.Status == domain.StatusProcessing
+		})).
+		Return(nil)
+
+	scanner := pipeline.NewScanner(log, testSource, cfg, files, filesProvider, fileUpdater, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- scanner.Run(ctx)
+	}()
+
+	// Без маркера файл не забирается
+	select {
+	case got := <-files:
+		t.Fatalf("didn't expect files, got %q", got.Name)
+	case <-time.After(scanInterval * 10):
+	}
+
+	require.NoError(t, os.WriteFile(filename+".done", nil, 0o644))
+
+	// Ждем файл в канале
+	select {
+	case got := <-files:
+		assert.Equal(t, filename, got.Path)
+		assert.Equal(t, filepath.Base(filename), got.Name)
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("timeout: file was not sent to channel")
+	}
+
+	// Отмена контекста, сканнер должен остановиться
+	cancel()
+
+	// Ждем завершения горутины
+	select {
+	case err := <-errChan:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("timeout: scanner did not stop")
+	}
+}
+
+func TestScanner_Run_Recursive(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	tmpDir := t.TempDir()
+
+	// Файл во вложенной папке и файл с неподходящим расширением
+	nestedDir := filepath.Join(tmpDir, "site-a", "2026-10")
+	require.NoError(t, os.MkdirAll(nestedDir, 0o755))
+
+	filename := filepath.Join(nestedDir, "data.tsv")
+	require.NoError(t, os.WriteFile(filename, nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(nestedDir, "notes.txt"), nil, 0o644))
+
+	scanInterval := 1 * time.Millisecond
+	files := make(chan *domain.File, 1)
+
+	cfg := pollConfig(tmpDir, scanInterval)
+	cfg.Include = []string{"*.tsv"}
+	cfg.Recursive = true
+
+	filesProvider := NewMockFilesProvider(t)
+	filesProvider.EXPECT().
+		Files(mock.Anything, testSource).
+		Return([]*domain.File{}, nil)
+
+	// Файл хранится в БД по относительному пути
+	fileUpdater := NewMockFileUpdater(t)
+	fileUpdater.EXPECT().
+		UpdateOrCreateFile(mock.Anything, mock.MatchedBy(func(f *domain.File) bool {
+			return f.Name == "site-a/2026-10/data.tsv" && f.Status == domain.StatusProcessing
+		})).
+		Return(nil)
+
+	scanner := pipeline.NewScanner(log, testSource, cfg, files, filesProvider, fileUpdater, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- scanner.Run(ctx)
+	}()
+
+	// Ждем файл в канале
+	select {
+	case got := <-files:
+		assert.Equal(t, filename, got.Path)
+		assert.Equal(t, "site-a/2026-10/data.tsv", got.Name)
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("timeout: file was not sent to channel")
+	}
+
+	// Отмена контекста, сканнер должен остановиться
+	cancel()
+
+	// Ждем завершения горутины
+	select {
+	case err := <-errChan:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("timeout: scanner did not stop")
+	}
+}
+
+func TestScanner_Run_SkipsDuplicateFiles(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	tmpDir := t.TempDir()
+
+	// Копия уже обработанного файла под другим именем
+	content := []byte("same content")
+	filename := filepath.Join(tmpDir, "copy.tsv")
+	require.NoError(t, os.WriteFile(filename, content, 0o644))
+
+	scanInterval := 1 * time.Millisecond
+	files := make(chan *domain.File, 1)
+
+	filesProvider := NewMockFilesProvider(t)
+	filesProvider.EXPECT().
+		Files(mock.Anything, testSource).
+		Return([]*domain.File{{Name: "original.tsv", Status: domain.StatusDone, Hash: sha256Hex(content)}}, nil)
+
+	// Ожидается, что копия будет помечена дубликатом
+	marked := make(chan struct{}, 1)
+	fileUpdater := NewMockFileUpdater(t)
+	fileUpdater.EXPECT().
+		UpdateOrCreateFile(mock.Anything, mock.MatchedBy(func(f *domain.File) bool {
+			return f.Name == "copy.tsv" && f.Status == domain.StatusDuplicate && f.DuplicateOf == "original.tsv"
+		})).
+		Run(func(context.Context, *domain.File) {
+			select {
+			case marked <- struct{}{}:
+			default:
+			}
+		}).
+		Return(nil)
+
+	// Дубликат архивируется так же, как обработанный файл
+	fileArchiver := NewMockFileArchiver(t)
+	fileArchiver.EXPECT().
+		Archive(mock.MatchedBy(func(f *domain.File) bool {
+			return f.Name == "copy.tsv" && f.Path == filename && f.Status == domain.StatusDuplicate
+		})).
+		Return(nil)
+
+	scanner := pipeline.NewScanner(log, testSource, pollConfig(tmpDir, scanInterval), files, filesProvider, fileUpdater, fileArchiver)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- scanner.Run(ctx)
+	}()
+
+	select {
+	case <-marked:
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("timeout: duplicate was not recorded")
+	}
+
+	// Дубликат не должен уходить на разбор
+	select {
+	case got := <-files:
+		t.Fatalf("didn't expect files, got %q", got.Name)
+	case <-time.After(scanInterval * 10):
+	}
+
+	// Отмена контекста, сканнер должен остановиться
+	cancel()
+
+	// Ждем завершения горутины
+	select {
+	case err := <-errChan:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("timeout: scanner did not stop")
+	}
+}
+
+func TestScanner_Run_ReingestsChangedFile(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	tmpDir := t.TempDir()
+
+	// Файл был обработан, а затем перезаписан новым содержимым
+	content := []byte("new content")
+	filename := filepath.Join(tmpDir, "changed.tsv")
+	require.NoError(t, os.WriteFile(filename, content, 0o644))
+
+	processedAt := time.Now().Add(-time.Hour)
+
+	cfg := pollConfig(tmpDir, 1*time.Millisecond)
+	cfg.OnChange = config.ChangePolicyReingest
+	files := make(chan *domain.File, 1)
+
+	filesProvider := NewMockFilesProvider(t)
+	filesProvider.EXPECT().
+		Files(mock.Anything, testSource).
+		Return([]*domain.File{{
+			Name:       filepath.Base(filename),
+			Status:     domain.StatusDone,
+			Size:       3,
+			ModifiedAt: &processedAt,
+			Hash:       sha256Hex([]byte("old")),
+		}}, nil)
+
+	// Ожидается повторный захват файла с новым хешем
+	fileUpdater := NewMockFileUpdater(t)
+	fileUpdater.EXPECT().
+		UpdateOrCreateFile(mock.Anything, mock.MatchedBy(func(f *domain.File) bool {
+			return f.Name == filepath.Base(filename) && f.Status == domain.StatusProcessing
+		})).
+		Return(nil)
+
+	scanner := pipeline.NewScanner(log, testSource, cfg, files, filesProvider, fileUpdater, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- scanner.Run(ctx)
+	}()
+
+	// Ждем файл в канале
+	select {
+	case got := <-files:
+		assert.Equal(t, filename, got.Path)
+		assert.Equal(t, sha256Hex(content), got.Hash)
+		assert.Equal(t, int64(len(content)), got.Size)
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("timeout: file was not sent to channel")
+	}
+
+	// Отмена контекста, сканнер должен остановиться
+	cancel()
+
+	// Ждем завершения горутины
+	select {
+	case err := <-errChan:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("timeout: scanner did not stop")
+	}
+}
+
+func TestScanner_Run_BundleMembers(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	tmpDir := t.TempDir()
+
+	// Бандл с TSV и посторонним файлом
+	bundle := createZip(t, map[string][]byte{
+		"unit7.tsv":  []byte("unit7"),
+		"readme.txt": []byte("readme"),
+	})
+	filename := filepath.Join(tmpDir, "bundle.zip")
+	require.NoError(t, os.Rename(bundle, filename))
+
+	cfg := pollConfig(tmpDir, 1*time.Millisecond)
+	cfg.Include = []string{"*.tsv"}
+	files := make(chan *domain.File, 1)
+
+	filesProvider := NewMockFilesProvider(t)
+	filesProvider.EXPECT().
+		Files(mock.Anything, testSource).
+		Return([]*domain.File{}, nil)
+
+	// Ожидается захват только TSV из бандла
+	fileUpdater := NewMockFileUpdater(t)
+	fileUpdater.EXPECT().
+		UpdateOrCreateFile(mock.Anything, mock.MatchedBy(func(f *domain.File) bool {
+			return f.Name == "bundle.zip!/unit7.tsv" && f.Status == domain.StatusProcessing
+		})).
+		Return(nil).
+		Once()
+
+	// Файл из бандла ещё не обработан, поэтому бандл не архивируется
+	fileArchiver := NewMockFileArchiver(t)
+
+	scanner := pipeline.NewScanner(log, testSource, cfg, files, filesProvider, fileUpdater, fileArchiver)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- scanner.Run(ctx)
+	}()
+
+	// Ждем файл из бандла в канале
+	select {
+	case got := <-files:
+		assert.Equal(t, filename, got.Path)
+		assert.Equal(t, "unit7.tsv", got.Member)
+		assert.Equal(t, sha256Hex([]byte("unit7")), got.Hash)
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("timeout: file was not sent to channel")
+	}
+
+	// Неизменившийся бандл повторно не раскрывается
+	select {
+	case got := <-files:
+		t.Fatalf("didn't expect files, got %q", got.Name)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Отмена контекста, сканнер должен остановиться
+	cancel()
+
+	// Ждем завершения горутины
+	select {
+	case err := <-errChan:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("timeout: scanner did not stop")
+	}
+}
+
+func TestScanner_Run_BundleArchive(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	tests := []struct {
+		name        string
+		statuses    map[string]domain.Status
+		wantArchive *domain.File
+	}{
+		{
+			name:     "all members done",
+			statuses: map[string]domain.Status{"unit7.tsv": domain.StatusDone, "unit8.tsv": domain.StatusDuplicate},
+			wantArchive: &domain.File{
+				Source: testSource,
+				Name:   "bundle.zip",
+				Status: domain.StatusDone,
+			},
+		},
+		{
+			// Ошибка любого файла переводит бандл в error, причины попадают в sidecar
+			name:     "failed member",
+			statuses: map[string]domain.Status{"unit7.tsv": domain.StatusDoneWithErrors, "unit8.tsv": domain.StatusError},
+			wantArchive: &domain.File{
+				Source:       testSource,
+				Name:         "bundle.zip",
+				Status:       domain.StatusError,
+				ErrorMessage: "unit7.tsv: unit7.tsv failed\nunit8.tsv: unit8.tsv failed",
+			},
+		},
+		{
+			name:     "member in progress",
+			statuses: map[string]domain.Status{"unit7.tsv": domain.StatusDone, "unit8.tsv": domain.StatusProcessing},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpDir := t.TempDir()
+
+			bundle := createZip(t, map[string][]byte{
+				"unit7.tsv":  []byte("unit7"),
+				"unit8.tsv":  []byte("unit8"),
+				"readme.txt": []byte("readme"),
+			})
+			filename := filepath.Join(tmpDir, "bundle.zip")
+			require.NoError(t, os.Rename(bundle, filename))
+
+			info, err := os.Stat(filename)
+			require.NoError(t, err)
+			modifiedAt := info.ModTime().UTC()
+
+			// Файлы бандла уже известны и не изменились, повторно они не захватываются
+			known := make([]*domain.File, 0, len(tt.statuses))
+			for member, status := range tt.statuses {
+				file := &domain.File{
+					Source:     testSource,
+					Name:       "bundle.zip!/" + member,
+					Status:     status,
+					ModifiedAt: &modifiedAt,
+				}
+				if status == domain.StatusError || status == domain.StatusDoneWithErrors {
+					file.ErrorMessage = member + " failed"
+				}
+				known = append(known, file)
+			}
+
+			cfg := pollConfig(tmpDir, time.Millisecond)
+			cfg.Include = []string{"*.tsv"}
+
+			scanned := make(chan struct{}, 1)
+			filesProvider := NewMockFilesProvider(t)
+			filesProvider.EXPECT().
+				Files(mock.Anything, testSource).
+				Run(func(context.Context, string) {
+					select {
+					case scanned <- struct{}{}:
+					default:
+					}
+				}).
+				Return(known, nil)
+
+			archived := make(chan *domain.File, 1)
+			fileArchiver := NewMockFileArchiver(t)
+			if tt.wantArchive != nil {
+				// Бандл архивируется один раз, хотя сканирования продолжаются
+				fileArchiver.EXPECT().
+					Archive(mock.Anything).
+					Run(func(file *domain.File) { archived <- file }).
+					Return(nil).
+					Once()
+			}
+
+			scanner := pipeline.NewScanner(log, testSource, cfg, make(chan *domain.File, 1), filesProvider, NewMockFileUpdater(t), fileArchiver)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			errChan := make(chan error, 1)
+			go func() {
+				errChan <- scanner.Run(ctx)
+			}()
+
+			if tt.wantArchive != nil {
+				select {
+				case got := <-archived:
+					tt.wantArchive.Path = filename
+					assert.Equal(t, tt.wantArchive, got)
+				case <-time.After(100 * time.Millisecond):
+					t.Fatal("timeout: bundle was not archived")
+				}
+			}
+
+			// Даём пройти ещё нескольким сканированиям
+			for range 3 {
+				select {
+				case <-scanned:
+				case <-time.After(100 * time.Millisecond):
+					t.Fatal("timeout: scanner did not rescan")
+				}
+			}
+
+			cancel()
+			require.ErrorIs(t, <-errChan, context.Canceled)
+		})
+	}
+}
+
+const testSource = "plant-a"
+
+func pollConfig(dir string, interval time.Duration) config.Scanner {
+	return config.Scanner{
+		WatchDirectory: dir,
+		WatchMode:      config.WatchModePoll,
+		ScanInterval:   interval,
+	}
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -2,44 +2,73 @@ package pipeline
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-	"path/filepath"
 	"time"
 
 	"github.com/kurochkinivan/device_reporter/internal/domain"
+	"golang.org/x/sync/errgroup"
 )
 
 type Writer struct {
-	log          *slog.Logger
-	parseResults <-chan *domain.ParseResult
-	reports      chan<- *domain.ParseResult
-	fileUpdater  FileUpdater
-	devicesSaver DevicesSaver
-	transactor   Transactor
+	log              *slog.Logger
+	workers          int
+	parseResults     <-chan *domain.ParseResult
+	reports          chan<- *domain.ParseResult
+	fileUpdater      FileUpdater
+	devicesSaver     DevicesSaver
+	rowErrorsSaver   RowErrorsSaver
+	rejectedSaver    RejectedRowsSaver
+	reportsScheduler ReportsScheduler
+	transactor       Transactor
+	fileArchiver     FileArchiver
 }
 
 func NewWriter(
 	log *slog.Logger,
+	workers int,
 	parseResults <-chan *domain.ParseResult,
 	reports chan<- *domain.ParseResult,
 	fileUpdater FileUpdater,
 	devicesSaver DevicesSaver,
+	rowErrorsSaver RowErrorsSaver,
+	rejectedSaver RejectedRowsSaver,
+	reportsScheduler ReportsScheduler,
 	transactor Transactor,
+	fileArchiver FileArchiver,
 ) *Writer {
 	return &Writer{
-		log:          log,
-		parseResults: parseResults,
-		reports:      reports,
-		fileUpdater:  fileUpdater,
-		devicesSaver: devicesSaver,
-		transactor:   transactor,
+		log:              log,
+		workers:          max(workers, 1),
+		parseResults:     parseResults,
+		reports:          reports,
+		fileUpdater:      fileUpdater,
+		devicesSaver:     devicesSaver,
+		rowErrorsSaver:   rowErrorsSaver,
+		rejectedSaver:    rejectedSaver,
+		reportsScheduler: reportsScheduler,
+		transactor:       transactor,
+		fileArchiver:     fileArchiver,
 	}
 }
 
+// Run saves parse results in the configured number of workers. A result is saved
+// by one worker, and reports is closed once every worker has stopped.
 func (w *Writer) Run(ctx context.Context) error {
 	defer close(w.reports)
 
+	erg, ctx := errgroup.WithContext(ctx)
+	for i := range w.workers {
+		erg.Go(func() error {
+			return w.work(ctx, w.log.With(slog.Int("worker", i)))
+		})
+	}
+
+	return erg.Wait()
+}
+
+func (w *Writer) work(ctx context.Context, log *slog.Logger) error {
 	for {
 		select {
 		case result, ok := <-w.parseResults:
@@ -47,19 +76,26 @@ func (w *Writer) Run(ctx context.Context) error {
 				return nil
 			}
 
-			log := w.log.With(
-				slog.String("filename", result.Filename),
-				slog.Int("devices_count", len(result.Devices)),
-			)
+			log := log.With(slog.String("filename", result.File.Name))
 
 			log.InfoContext(ctx, "received parse result")
 
-			if err := w.processParseResult(ctx, log, result); err != nil {
+			file, err := w.processParseResult(ctx, log, result)
+			if err != nil {
 				log.ErrorContext(ctx, "failed to process parse result", slog.String("err", err.Error()))
 				continue
 			}
 
-			w.reports <- result
+			// исходный файл трогаем только после коммита, иначе при ошибке записи он будет потерян
+			if err := w.fileArchiver.Archive(file); err != nil {
+				log.ErrorContext(ctx, "failed to archive file", slog.String("err", err.Error()))
+			}
+
+			select {
+			case w.reports <- result:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 
 		case <-ctx.Done():
 			return ctx.Err()
@@ -67,53 +103,146 @@ func (w *Writer) Run(ctx context.Context) error {
 	}
 }
 
-func (w *Writer) processParseResult(ctx context.Context, log *slog.Logger, result *domain.ParseResult) error {
-	switch result.Error {
-	case nil:
-		log.DebugContext(ctx, "saving parse result to database")
+// errParseFailed rolls back the devices streamed before the parser reported an error.
+var errParseFailed = errors.New("parse failed")
 
-		err := w.saveResult(ctx, result)
-		if err != nil {
-			return fmt.Errorf("failed to save result: %w", err)
-		}
+func (w *Writer) processParseResult(ctx context.Context, log *slog.Logger, result *domain.ParseResult) (*domain.File, error) {
+	log.DebugContext(ctx, "saving parse result to database")
 
-		log.DebugContext(ctx, "result saved successfully")
+	file, err := w.saveResult(ctx, log, result)
+
+	// если запись прервалась, дочитываем устройства, иначе Parser заблокируется на отправке
+	for range result.Chunks {
+	}
 
-	default:
+	switch {
+	case errors.Is(err, errParseFailed):
 		log.DebugContext(ctx, "processing error parse result")
 
-		now := time.Now()
-		err := w.fileUpdater.UpdateOrCreateFile(ctx, &domain.File{
-			Name:         filepath.Base(result.Filename),
-			Status:       domain.StatusError,
-			ErrorMessage: result.Error.Error(),
-			ProcessedAt:  &now,
-		})
+		file, err := w.saveError(ctx, result)
 		if err != nil {
-			return fmt.Errorf("failed to save parse result: %w", err)
+			return nil, fmt.Errorf("failed to save parse result: %w", err)
 		}
+
+		return file, nil
+
+	case err != nil:
+		return nil, fmt.Errorf("failed to save result: %w", err)
 	}
 
-	return nil
+	log.DebugContext(ctx, "result saved successfully", slog.Int64("devices_count", file.RowsProcessed))
+
+	return file, nil
 }
 
-func (w *Writer) saveResult(ctx context.Context, result *domain.ParseResult) error {
-	return w.transactor.WithTransaction(ctx, func(ctx context.Context) error {
-		err := w.devicesSaver.SaveDevices(ctx, result.Devices...)
+// saveResult copies the devices chunk by chunk in one transaction. Progress is written
+// outside of it, so it is visible while the file is being saved.
+func (w *Writer) saveResult(ctx context.Context, log *slog.Logger, result *domain.ParseResult) (*domain.File, error) {
+	file := *result.File
+
+	err := w.transactor.WithTransaction(ctx, func(txCtx context.Context) error {
+		// при повторной обработке изменившегося файла старые записи заменяются новыми
+		err := w.devicesSaver.DeleteDevicesByFile(txCtx, file.Source, file.Name)
 		if err != nil {
-			return fmt.Errorf("failed to save devices: %w", err)
+			return fmt.Errorf("failed to delete previous devices: %w", err)
+		}
+
+		var rows int64
+		for devices := range result.Chunks {
+			for _, d := range devices {
+				d.Source = file.Source
+				d.FileName = file.Name
+			}
+
+			err = w.devicesSaver.SaveDevices(txCtx, devices...)
+			if err != nil {
+				return fmt.Errorf("failed to save devices: %w", err)
+			}
+
+			rows += int64(len(devices))
+
+			if err := w.fileUpdater.UpdateProgress(ctx, file.Source, file.Name, rows); err != nil {
+				log.WarnContext(ctx, "failed to update progress", slog.String("err", err.Error()))
+			}
+		}
+
+		// канал закрыт, итог разбора известен
+		if result.Error != nil {
+			return errParseFailed
 		}
 
 		now := time.Now()
-		err = w.fileUpdater.UpdateOrCreateFile(ctx, &domain.File{
-			Name:        filepath.Base(result.Filename),
-			Status:      domain.StatusDone,
-			ProcessedAt: &now,
-		})
+		file.Status = domain.StatusDone
+		file.ErrorMessage = ""
+		file.ProcessedAt = &now
+		file.RowsProcessed = rows
+
+		if len(result.RejectedRows) > 0 {
+			first := result.RejectedRows[0]
+			file.Status = domain.StatusDoneWithErrors
+			file.ErrorMessage = fmt.Sprintf("rejected %d rows, first at line %d: %s",
+				len(result.RejectedRows), first.Line, first.Reason)
+		}
+
+		err = w.fileUpdater.UpdateOrCreateFile(txCtx, &file)
 		if err != nil {
 			return fmt.Errorf("failed to update file status: %w", err)
 		}
 
+		// ошибки строк прошлой версии файла заменяются ошибками текущей
+		err = w.rowErrorsSaver.ReplaceRowErrors(txCtx, file.Source, file.Name, result.RowErrors)
+		if err != nil {
+			return fmt.Errorf("failed to save row errors: %w", err)
+		}
+
+		err = w.rejectedSaver.ReplaceRejectedRows(txCtx, file.Source, file.Name, result.RejectedRows)
+		if err != nil {
+			return fmt.Errorf("failed to save rejected rows: %w", err)
+		}
+
+		// отчёты заводятся вместе с файлом: если Reporter не успеет их построить, они останутся в ожидании
+		err = w.reportsScheduler.ScheduleReports(txCtx, file.Source, file.Name)
+		if err != nil {
+			return fmt.Errorf("failed to schedule reports: %w", err)
+		}
+
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+func (w *Writer) saveError(ctx context.Context, result *domain.ParseResult) (*domain.File, error) {
+	now := time.Now()
+	file := *result.File
+	file.Status = domain.StatusError
+	file.ErrorMessage = result.Error.Error()
+	file.ProcessedAt = &now
+
+	err := w.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		err := w.fileUpdater.UpdateOrCreateFile(ctx, &file)
+		if err != nil {
+			return fmt.Errorf("failed to update file status: %w", err)
+		}
+
+		err = w.rowErrorsSaver.ReplaceRowErrors(ctx, file.Source, file.Name, result.RowErrors)
+		if err != nil {
+			return fmt.Errorf("failed to save row errors: %w", err)
+		}
+
+		err = w.rejectedSaver.ReplaceRejectedRows(ctx, file.Source, file.Name, nil)
+		if err != nil {
+			return fmt.Errorf("failed to clear rejected rows: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &file, nil
 }
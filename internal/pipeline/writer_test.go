@@ -37,9 +37,9 @@ func TestWriter_Run_ErrorIsNil(t *testing.T) {
 	}
 
 	parseResult := &domain.ParseResult{
-		Filename: "test.tsv",
-		Error:    nil,
-		Devices:  []*domain.Device{device},
+		File:   &domain.File{Source: "plant-a", Name: "test.tsv"},
+		Error:  nil,
+		Chunks: chunksOf(device),
 	}
 
 	parseResults := make(chan *domain.ParseResult, 1)
@@ -47,7 +47,11 @@ func TestWriter_Run_ErrorIsNil(t *testing.T) {
 
 	mockTransactor := NewMockTransactor(t)
 	mockDevicesSaver := NewMockDevicesSaver(t)
+	mockRowErrorsSaver := NewMockRowErrorsSaver(t)
+	mockRejectedRowsSaver := NewMockRejectedRowsSaver(t)
+	mockReportsScheduler := NewMockReportsScheduler(t)
 	mockFileUpdater := NewMockFileUpdater(t)
+	mockFileArchiver := NewMockFileArchiver(t)
 
 	mockTransactor.EXPECT().WithTransaction(mock.Anything, mock.Anything).
 		Return(nil).
@@ -55,10 +59,22 @@ func TestWriter_Run_ErrorIsNil(t *testing.T) {
 			_ = fn(ctx)
 		})
 
-	mockDevicesSaver.EXPECT().SaveDevices(mock.Anything, mock.Anything).Return(nil)
-	mockFileUpdater.EXPECT().UpdateOrCreateFile(mock.Anything, mock.Anything).Return(nil)
-
-	writer := pipeline.NewWriter(log, parseResults, reports, mockFileUpdater, mockDevicesSaver, mockTransactor)
+	mockDevicesSaver.EXPECT().DeleteDevicesByFile(mock.Anything, "plant-a", "test.tsv").Return(nil)
+	mockDevicesSaver.EXPECT().SaveDevices(mock.Anything, mock.MatchedBy(func(devices []*domain.Device) bool {
+		return len(devices) == 1 && devices[0].Source == "plant-a" && devices[0].FileName == "test.tsv"
+	})).Return(nil)
+	mockFileUpdater.EXPECT().UpdateProgress(mock.Anything, "plant-a", "test.tsv", int64(1)).Return(nil)
+	mockFileUpdater.EXPECT().UpdateOrCreateFile(mock.Anything, mock.MatchedBy(func(f *domain.File) bool {
+		return f.Status == domain.StatusDone && f.RowsProcessed == 1
+	})).Return(nil)
+	mockRowErrorsSaver.EXPECT().ReplaceRowErrors(mock.Anything, "plant-a", "test.tsv", []*domain.RowError(nil)).Return(nil)
+	mockRejectedRowsSaver.EXPECT().ReplaceRejectedRows(mock.Anything, "plant-a", "test.tsv", []*domain.RejectedRow(nil)).Return(nil)
+	mockReportsScheduler.EXPECT().ScheduleReports(mock.Anything, "plant-a", "test.tsv").Return(nil)
+	mockFileArchiver.EXPECT().Archive(mock.MatchedBy(func(f *domain.File) bool {
+		return f.Name == "test.tsv" && f.Status == domain.StatusDone
+	})).Return(nil)
+
+	writer := pipeline.NewWriter(log, 1, parseResults, reports, mockFileUpdater, mockDevicesSaver, mockRowErrorsSaver, mockRejectedRowsSaver, mockReportsScheduler, mockTransactor, mockFileArchiver)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -95,16 +111,85 @@ func TestWriter_Run_ErrorIsNil(t *testing.T) {
 	}
 }
 
+func TestWriter_Run_RejectedRows(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	device := &domain.Device{
+		N:        1,
+		UnitGUID: "01749246-95f6-57db-b7c3-2ae0e8be671f",
+		Class:    "waiting",
+	}
+	rowErrors := []*domain.RowError{{Line: 3, Column: "class", Reason: "is required"}}
+	rejectedRows := []*domain.RejectedRow{{Line: 3, Raw: "2\t01749246-95f6-57db-b7c3-2ae0e8be671f\t", Reason: "class is required"}}
+
+	parseResult := &domain.ParseResult{
+		File:         &domain.File{Source: "plant-a", Name: "test.tsv"},
+		Chunks:       chunksOf(device),
+		RowErrors:    rowErrors,
+		RejectedRows: rejectedRows,
+	}
+
+	parseResults := make(chan *domain.ParseResult, 1)
+	reports := make(chan *domain.ParseResult, 1)
+
+	mockTransactor := NewMockTransactor(t)
+	mockDevicesSaver := NewMockDevicesSaver(t)
+	mockRowErrorsSaver := NewMockRowErrorsSaver(t)
+	mockRejectedRowsSaver := NewMockRejectedRowsSaver(t)
+	mockReportsScheduler := NewMockReportsScheduler(t)
+	mockFileUpdater := NewMockFileUpdater(t)
+	mockFileArchiver := NewMockFileArchiver(t)
+
+	mockTransactor.EXPECT().WithTransaction(mock.Anything, mock.Anything).
+		Return(nil).
+		Run(func(ctx context.Context, fn func(ctx context.Context) error) {
+			_ = fn(ctx)
+		})
+
+	// валидные строки сохраняются, невалидные уходят в карантин
+	mockDevicesSaver.EXPECT().DeleteDevicesByFile(mock.Anything, "plant-a", "test.tsv").Return(nil)
+	mockDevicesSaver.EXPECT().SaveDevices(mock.Anything, []*domain.Device{device}).Return(nil)
+	mockFileUpdater.EXPECT().UpdateProgress(mock.Anything, "plant-a", "test.tsv", int64(1)).Return(nil)
+	mockFileUpdater.EXPECT().UpdateOrCreateFile(mock.Anything, mock.MatchedBy(func(f *domain.File) bool {
+		return f.Status == domain.StatusDoneWithErrors &&
+			f.ErrorMessage == "rejected 1 rows, first at line 3: class is required"
+	})).Return(nil)
+	mockRowErrorsSaver.EXPECT().ReplaceRowErrors(mock.Anything, "plant-a", "test.tsv", rowErrors).Return(nil)
+	mockRejectedRowsSaver.EXPECT().ReplaceRejectedRows(mock.Anything, "plant-a", "test.tsv", rejectedRows).Return(nil)
+	mockReportsScheduler.EXPECT().ScheduleReports(mock.Anything, "plant-a", "test.tsv").Return(nil)
+	mockFileArchiver.EXPECT().Archive(mock.MatchedBy(func(f *domain.File) bool {
+		return f.Status == domain.StatusDoneWithErrors
+	})).Return(nil)
+
+	writer := pipeline.NewWriter(log, 1, parseResults, reports, mockFileUpdater, mockDevicesSaver, mockRowErrorsSaver, mockRejectedRowsSaver, mockReportsScheduler, mockTransactor, mockFileArchiver)
+
+	parseResults <- parseResult
+	close(parseResults)
+
+	require.NoError(t, writer.Run(t.Context()))
+
+	result := <-reports
+	require.NotNil(t, result)
+	require.NoError(t, result.Error)
+}
+
 func TestWriter_Run_ErrorIsNotNil(t *testing.T) {
 	t.Parallel()
 
 	log := slog.New(slog.DiscardHandler)
 
 	parseError := errors.New("parse error")
+	rowErrors := []*domain.RowError{
+		{Line: 3, Column: "unit_guid", Reason: "is required"},
+		{Line: 5, Column: "level", Value: "high", Reason: "is not a valid int"},
+	}
 	parseResult := &domain.ParseResult{
-		Filename: "test.tsv",
-		Error:    parseError,
-		Devices:  nil,
+		File:      &domain.File{Source: "plant-a", Name: "test.tsv"},
+		Error:     parseError,
+		Chunks:    chunksOf(),
+		RowErrors: rowErrors,
 	}
 
 	parseResults := make(chan *domain.ParseResult, 1)
@@ -112,11 +197,27 @@ func TestWriter_Run_ErrorIsNotNil(t *testing.T) {
 
 	mockTransactor := NewMockTransactor(t)
 	mockDevicesSaver := NewMockDevicesSaver(t)
+	mockRowErrorsSaver := NewMockRowErrorsSaver(t)
+	mockRejectedRowsSaver := NewMockRejectedRowsSaver(t)
+	mockReportsScheduler := NewMockReportsScheduler(t)
 	mockFileUpdater := NewMockFileUpdater(t)
+	mockFileArchiver := NewMockFileArchiver(t)
+
+	mockTransactor.EXPECT().WithTransaction(mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		})
 
+	// записи прошлой версии удаляются в транзакции, которая откатится из-за ошибки разбора
+	mockDevicesSaver.EXPECT().DeleteDevicesByFile(mock.Anything, "plant-a", "test.tsv").Return(nil)
 	mockFileUpdater.EXPECT().UpdateOrCreateFile(mock.Anything, mock.Anything).Return(nil)
+	mockRowErrorsSaver.EXPECT().ReplaceRowErrors(mock.Anything, "plant-a", "test.tsv", rowErrors).Return(nil)
+	mockRejectedRowsSaver.EXPECT().ReplaceRejectedRows(mock.Anything, "plant-a", "test.tsv", []*domain.RejectedRow(nil)).Return(nil)
+	mockFileArchiver.EXPECT().Archive(mock.MatchedBy(func(f *domain.File) bool {
+		return f.Status == domain.StatusError && f.ErrorMessage == parseError.Error()
+	})).Return(nil)
 
-	writer := pipeline.NewWriter(log, parseResults, reports, mockFileUpdater, mockDevicesSaver, mockTransactor)
+	writer := pipeline.NewWriter(log, 1, parseResults, reports, mockFileUpdater, mockDevicesSaver, mockRowErrorsSaver, mockRejectedRowsSaver, mockReportsScheduler, mockTransactor, mockFileArchiver)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -166,9 +267,13 @@ func TestWriter_Run_ChannelCloses(t *testing.T) {
 
 	mockTransactor := NewMockTransactor(t)
 	mockDevicesSaver := NewMockDevicesSaver(t)
+	mockRowErrorsSaver := NewMockRowErrorsSaver(t)
+	mockRejectedRowsSaver := NewMockRejectedRowsSaver(t)
+	mockReportsScheduler := NewMockReportsScheduler(t)
 	mockFileUpdater := NewMockFileUpdater(t)
+	mockFileArchiver := NewMockFileArchiver(t)
 
-	writer := pipeline.NewWriter(log, parseResults, reports, mockFileUpdater, mockDevicesSaver, mockTransactor)
+	writer := pipeline.NewWriter(log, 1, parseResults, reports, mockFileUpdater, mockDevicesSaver, mockRowErrorsSaver, mockRejectedRowsSaver, mockReportsScheduler, mockTransactor, mockFileArchiver)
 
 	ctx, cancel := context.WithCancel(t.Context())
 	defer cancel()
@@ -187,3 +292,87 @@ func TestWriter_Run_ChannelCloses(t *testing.T) {
 		t.Fatal("timeout: error was not sent to channel")
 	}
 }
+
+func TestWriter_Run_Workers(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.DiscardHandler)
+
+	// устройства первого файла ещё не разобраны, второй файл должен сохраниться, не дожидаясь его
+	slowChunks := make(chan []*domain.Device)
+	slow := &domain.ParseResult{File: &domain.File{Source: "plant-a", Name: "slow.tsv"}, Chunks: slowChunks}
+	fast := &domain.ParseResult{File: &domain.File{Source: "plant-a", Name: "fast.tsv"}, Chunks: chunksOf()}
+
+	parseResults := make(chan *domain.ParseResult, 2)
+	reports := make(chan *domain.ParseResult, 2)
+
+	mockTransactor := NewMockTransactor(t)
+	mockDevicesSaver := NewMockDevicesSaver(t)
+	mockRowErrorsSaver := NewMockRowErrorsSaver(t)
+	mockRejectedRowsSaver := NewMockRejectedRowsSaver(t)
+	mockReportsScheduler := NewMockReportsScheduler(t)
+	mockFileUpdater := NewMockFileUpdater(t)
+	mockFileArchiver := NewMockFileArchiver(t)
+
+	mockTransactor.EXPECT().WithTransaction(mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		})
+
+	mockDevicesSaver.EXPECT().DeleteDevicesByFile(mock.Anything, "plant-a", mock.Anything).Return(nil)
+	mockFileUpdater.EXPECT().UpdateOrCreateFile(mock.Anything, mock.MatchedBy(func(f *domain.File) bool {
+		return f.Status == domain.StatusDone
+	})).Return(nil)
+	mockRowErrorsSaver.EXPECT().ReplaceRowErrors(mock.Anything, "plant-a", mock.Anything, []*domain.RowError(nil)).Return(nil)
+	mockRejectedRowsSaver.EXPECT().ReplaceRejectedRows(mock.Anything, "plant-a", mock.Anything, []*domain.RejectedRow(nil)).Return(nil)
+	mockReportsScheduler.EXPECT().ScheduleReports(mock.Anything, "plant-a", mock.Anything).Return(nil)
+	mockFileArchiver.EXPECT().Archive(mock.Anything).Return(nil)
+
+	writer := pipeline.NewWriter(log, 2, parseResults, reports, mockFileUpdater, mockDevicesSaver, mockRowErrorsSaver, mockRejectedRowsSaver, mockReportsScheduler, mockTransactor, mockFileArchiver)
+
+	parseResults <- slow
+	parseResults <- fast
+	close(parseResults)
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- writer.Run(t.Context())
+	}()
+
+	select {
+	case result := <-reports:
+		require.Equal(t, "fast.tsv", result.File.Name)
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("timeout: fast file waited for the slow one")
+	}
+
+	close(slowChunks)
+
+	select {
+	case result := <-reports:
+		require.Equal(t, "slow.tsv", result.File.Name)
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("timeout: slow file was not saved")
+	}
+
+	select {
+	case err := <-errChan:
+		require.NoError(t, err)
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("timeout: writer did not stop")
+	}
+
+	_, ok := <-reports
+	require.False(t, ok, "reports must be closed after the last worker stops")
+}
+
+// chunksOf returns a closed channel holding the devices as a single chunk, like the parser sends them.
+func chunksOf(devices ...*domain.Device) <-chan []*domain.Device {
+	chunks := make(chan []*domain.Device, 1)
+	if len(devices) > 0 {
+		chunks <- devices
+	}
+	close(chunks)
+
+	return chunks
+}
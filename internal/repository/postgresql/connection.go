@@ -17,7 +17,8 @@ const (
 	retryDelay = 5 * time.Second
 )
 
-func NewConnection(ctx context.Context, log *slog.Logger, cfg config.PostgreSQL) (*pgxpool.Pool, error) {
+// NewConnection opens a pool of poolSize connections, or of the pgx default size if it is larger.
+func NewConnection(ctx context.Context, log *slog.Logger, cfg config.PostgreSQL, poolSize int32) (*pgxpool.Pool, error) {
 	connectionURL := &url.URL{
 		Scheme:   "postgres",
 		User:     url.UserPassword(cfg.Username, cfg.Password),
@@ -26,7 +27,13 @@ func NewConnection(ctx context.Context, log *slog.Logger, cfg config.PostgreSQL)
 		RawQuery: "sslmode=disable",
 	}
 
-	pool, err := pgxpool.New(ctx, connectionURL.String())
+	poolCfg, err := pgxpool.ParseConfig(connectionURL.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pool config: %w", err)
+	}
+	poolCfg.MaxConns = max(poolCfg.MaxConns, poolSize)
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pool: %w", err)
 	}
@@ -3,6 +3,8 @@ package postgresql
 import (
 	"context"
 	"fmt"
+	"slices"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
@@ -12,6 +14,24 @@ import (
 
 const TableDevices = "devices"
 
+var devicesColumns = []string{
+	"n",
+	"mqtt",
+	"inv_id",
+	"unit_guid",
+	"msg_id",
+	"text",
+	"context",
+	"class",
+	"level",
+	"area",
+	"addr",
+	"block",
+	"type",
+	"bit",
+	"invert_bit",
+}
+
 type DevicesRepository struct {
 	pool *pgxpool.Pool
 	qb   sq.StatementBuilderType
@@ -46,23 +66,7 @@ func (r *DevicesRepository) DevicesByGUID(
 	}
 
 	sql, args, err = r.qb.
-		Select(
-			"n",
-			"mqtt",
-			"inv_id",
-			"unit_guid",
-			"msg_id",
-			"text",
-			"context",
-			"class",
-			"level",
-			"area",
-			"addr",
-			"block",
-			"type",
-			"bit",
-			"invert_bit",
-		).
+		Select(devicesColumns...).
 		From(TableDevices).
 		Where(sq.Eq{"unit_guid": guid}).
 		OrderBy("n ASC").
@@ -86,6 +90,126 @@ func (r *DevicesRepository) DevicesByGUID(
 	return devices, total, nil
 }
 
+func (r *DevicesRepository) DevicesByFile(
+	ctx context.Context,
+	source, fileName, unitGUID string,
+) ([]*domain.Device, error) {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Select(devicesColumns...).
+		From(TableDevices).
+		Where(sq.Eq{"source": source, "file_name": fileName, "unit_guid": unitGUID}).
+		OrderBy("n ASC").
+		ToSql()
+	if err != nil {
+		return nil, createQueryError(err)
+	}
+
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, executeQueryError(err)
+	}
+
+	devices, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByNameLax[domain.Device])
+	if err != nil {
+		return nil, collectRowsError(err)
+	}
+
+	return devices, nil
+}
+
+// DevicesByUnit returns the records of a unit stored in the source since the given time,
+// file by file in the order the files were saved. A zero since returns all records.
+func (r *DevicesRepository) DevicesByUnit(
+	ctx context.Context,
+	source, unitGUID string,
+	since time.Time,
+) ([]*domain.Device, error) {
+	db := extractDB(ctx, r.pool)
+
+	query := r.qb.
+		Select(slices.Concat(devicesColumns, []string{"source", "file_name"})...).
+		From(TableDevices).
+		Where(sq.Eq{"source": source, "unit_guid": unitGUID}).
+		OrderBy("created_at ASC", "file_name ASC", "n ASC")
+
+	if !since.IsZero() {
+		query = query.Where(sq.GtOrEq{"created_at": since})
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, createQueryError(err)
+	}
+
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, executeQueryError(err)
+	}
+
+	devices, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByNameLax[domain.Device])
+	if err != nil {
+		return nil, collectRowsError(err)
+	}
+
+	return devices, nil
+}
+
+// UnitsByFile returns the units of a file with the counts of their records in it.
+func (r *DevicesRepository) UnitsByFile(ctx context.Context, source, fileName string) ([]*domain.FleetUnit, error) {
+	return r.fleetUnits(ctx, sq.Eq{"source": source, "file_name": fileName})
+}
+
+// UnitsByInvID returns the units of an inventory ID with the counts of their records
+// in every stored file of the source.
+func (r *DevicesRepository) UnitsByInvID(ctx context.Context, source, invID string) ([]*domain.FleetUnit, error) {
+	return r.fleetUnits(ctx, sq.Eq{"source": source, "inv_id": invID})
+}
+
+// fleetUnits counts the records of the matching units and joins the latest report of each.
+func (r *DevicesRepository) fleetUnits(ctx context.Context, where sq.Eq) ([]*domain.FleetUnit, error) {
+	db := extractDB(ctx, r.pool)
+
+	units := r.qb.
+		Select(
+			"source",
+			"unit_guid",
+			"inv_id",
+			"COUNT(*) AS records",
+			"COUNT(*) FILTER (WHERE class = 'alarm') AS alarms",
+			"COUNT(*) FILTER (WHERE class = 'warning') AS warnings",
+		).
+		From(TableDevices).
+		Where(where).
+		GroupBy("source", "unit_guid", "inv_id")
+
+	sql, args, err := r.qb.
+		Select("u.unit_guid", "u.inv_id", "u.records", "u.alarms", "u.warnings", "v.version AS report_version", "v.files AS report_files").
+		FromSelect(units, "u").
+		JoinClause(fmt.Sprintf(
+			"LEFT JOIN LATERAL (SELECT version, files FROM %s WHERE source = u.source AND unit_guid = u.unit_guid ORDER BY version DESC LIMIT 1) v ON TRUE",
+			TableReportVersions,
+		)).
+		OrderBy("u.inv_id ASC", "u.unit_guid ASC").
+		ToSql()
+	if err != nil {
+		return nil, createQueryError(err)
+	}
+
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, executeQueryError(err)
+	}
+
+	fleet, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByNameLax[domain.FleetUnit])
+	if err != nil {
+		return nil, collectRowsError(err)
+	}
+
+	return fleet, nil
+}
+
 func (r *DevicesRepository) SaveDevices(ctx context.Context, devices ...*domain.Device) error {
 	db := extractDB(ctx, r.pool)
 
@@ -105,6 +229,8 @@ func (r *DevicesRepository) SaveDevices(ctx context.Context, devices ...*domain.
 		"type",
 		"bit",
 		"invert_bit",
+		"source",
+		"file_name",
 	}, pgx.CopyFromSlice(len(devices), func(i int) ([]any, error) {
 		return []any{
 			devices[i].N,
@@ -122,6 +248,8 @@ func (r *DevicesRepository) SaveDevices(ctx context.Context, devices ...*domain.
 			devices[i].Type,
 			devices[i].Bit,
 			devices[i].InvertBit,
+			devices[i].Source,
+			devices[i].FileName,
 		}, nil
 	}))
 	if err != nil {
@@ -134,3 +262,22 @@ func (r *DevicesRepository) SaveDevices(ctx context.Context, devices ...*domain.
 
 	return nil
 }
+
+func (r *DevicesRepository) DeleteDevicesByFile(ctx context.Context, source, fileName string) error {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Delete(TableDevices).
+		Where(sq.Eq{"source": source, "file_name": fileName}).
+		ToSql()
+	if err != nil {
+		return createQueryError(err)
+	}
+
+	_, err = db.Exec(ctx, sql, args...)
+	if err != nil {
+		return executeQueryError(err)
+	}
+
+	return nil
+}
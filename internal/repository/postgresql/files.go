@@ -2,6 +2,7 @@ package postgresql
 
 import (
 	"context"
+	"errors"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
@@ -11,6 +12,19 @@ import (
 
 const TableFiles = "files"
 
+var filesColumns = []string{
+	"source",
+	"name",
+	"status",
+	"processed_at",
+	"error_message",
+	"size",
+	"modified_at",
+	"sha256",
+	"duplicate_of",
+	"rows_processed",
+}
+
 type FilesRepository struct {
 	pool *pgxpool.Pool
 	qb   sq.StatementBuilderType
@@ -23,17 +37,13 @@ func NewFilesRepository(pool *pgxpool.Pool) *FilesRepository {
 	}
 }
 
-func (r *FilesRepository) Files(ctx context.Context) ([]*domain.File, error) {
+func (r *FilesRepository) Files(ctx context.Context, source string) ([]*domain.File, error) {
 	db := extractDB(ctx, r.pool)
 
 	sql, args, err := r.qb.
-		Select(
-			"name",
-			"status",
-			"processed_at",
-			"error_message",
-		).
+		Select(filesColumns...).
 		From(TableFiles).
+		Where(sq.Eq{"source": source}).
 		ToSql()
 	if err != nil {
 		return nil, createQueryError(err)
@@ -52,27 +62,86 @@ func (r *FilesRepository) Files(ctx context.Context) ([]*domain.File, error) {
 	return files, nil
 }
 
+func (r *FilesRepository) File(ctx context.Context, source, name string) (*domain.File, error) {
+	return r.file(ctx, sq.Eq{"source": source, "name": name})
+}
+
+// FileByHash returns a file with the given content hash that is not itself a duplicate.
+func (r *FilesRepository) FileByHash(ctx context.Context, source, hash string) (*domain.File, error) {
+	return r.file(ctx, sq.And{
+		sq.Eq{"source": source, "sha256": hash},
+		sq.NotEq{"status": domain.StatusDuplicate},
+	})
+}
+
+func (r *FilesRepository) file(ctx context.Context, where sq.Sqlizer) (*domain.File, error) {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Select(filesColumns...).
+		From(TableFiles).
+		Where(where).
+		OrderBy("name ASC").
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return nil, createQueryError(err)
+	}
+
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, executeQueryError(err)
+	}
+
+	file, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByNameLax[domain.File])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrFileNotFound
+		}
+		return nil, collectRowsError(err)
+	}
+
+	return file, nil
+}
+
 func (r *FilesRepository) UpdateOrCreateFile(ctx context.Context, file *domain.File) error {
 	db := extractDB(ctx, r.pool)
 
 	sql, args, err := r.qb.
 		Insert(TableFiles).
 		Columns(
+			"source",
 			"name",
 			"status",
 			"error_message",
 			"processed_at",
+			"size",
+			"modified_at",
+			"sha256",
+			"duplicate_of",
+			"rows_processed",
 		).
 		Values(
+			file.Source,
 			file.Name,
 			file.Status,
 			file.ErrorMessage,
 			file.ProcessedAt,
+			file.Size,
+			file.ModifiedAt,
+			file.Hash,
+			file.DuplicateOf,
+			file.RowsProcessed,
 		).
-		Suffix(`ON CONFLICT (name) DO UPDATE SET 
+		Suffix(`ON CONFLICT (source, name) DO UPDATE SET 
 			status = EXCLUDED.status, 
 			error_message = EXCLUDED.error_message, 
-			processed_at = EXCLUDED.processed_at
+			processed_at = EXCLUDED.processed_at,
+			size = EXCLUDED.size,
+			modified_at = EXCLUDED.modified_at,
+			sha256 = EXCLUDED.sha256,
+			duplicate_of = EXCLUDED.duplicate_of,
+			rows_processed = EXCLUDED.rows_processed
 		`).
 		ToSql()
 	if err != nil {
@@ -87,6 +156,26 @@ func (r *FilesRepository) UpdateOrCreateFile(ctx context.Context, file *domain.F
 	return nil
 }
 
+func (r *FilesRepository) UpdateProgress(ctx context.Context, source, name string, rowsProcessed int64) error {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Update(TableFiles).
+		Set("rows_processed", rowsProcessed).
+		Where(sq.Eq{"source": source, "name": name}).
+		ToSql()
+	if err != nil {
+		return createQueryError(err)
+	}
+
+	_, err = db.Exec(ctx, sql, args...)
+	if err != nil {
+		return executeQueryError(err)
+	}
+
+	return nil
+}
+
 func (r *FilesRepository) ResetProcessingFiles(ctx context.Context) error {
 	db := extractDB(ctx, r.pool)
 
@@ -0,0 +1,75 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+)
+
+const TableRejectedRows = "rejected_rows"
+
+type RejectedRowsRepository struct {
+	pool *pgxpool.Pool
+	qb   sq.StatementBuilderType
+}
+
+func NewRejectedRowsRepository(pool *pgxpool.Pool) *RejectedRowsRepository {
+	return &RejectedRowsRepository{
+		pool: pool,
+		qb:   sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *RejectedRowsRepository) ReplaceRejectedRows(
+	ctx context.Context,
+	source, fileName string,
+	rows []*domain.RejectedRow,
+) error {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Delete(TableRejectedRows).
+		Where(sq.Eq{"source": source, "file_name": fileName}).
+		ToSql()
+	if err != nil {
+		return createQueryError(err)
+	}
+
+	_, err = db.Exec(ctx, sql, args...)
+	if err != nil {
+		return executeQueryError(err)
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	copied, err := db.CopyFrom(ctx, pgx.Identifier{TableRejectedRows}, []string{
+		"source",
+		"file_name",
+		"line",
+		"raw",
+		"reason",
+	}, pgx.CopyFromSlice(len(rows), func(i int) ([]any, error) {
+		return []any{
+			source,
+			fileName,
+			rows[i].Line,
+			rows[i].Raw,
+			rows[i].Reason,
+		}, nil
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to save rejected rows: %w", err)
+	}
+
+	if copied != int64(len(rows)) {
+		return fmt.Errorf("failed to save rejected rows: copied %d rows, expected %d", copied, len(rows))
+	}
+
+	return nil
+}
@@ -0,0 +1,448 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+)
+
+const (
+	TableReports        = "reports"
+	TableReportJobs     = "report_jobs"
+	TableReportVersions = "report_versions"
+)
+
+var reportsColumns = []string{
+	"source",
+	"file_name",
+	"unit_guid",
+	"version",
+	"files",
+	"status",
+	"error_message",
+	"generated_at",
+}
+
+var reportVersionsColumns = []string{
+	"source",
+	"unit_guid",
+	"version",
+	"file_name",
+	"files",
+	"generated_at",
+}
+
+var reportJobsColumns = []string{
+	"id",
+	"source",
+	"file_name",
+	"attempts",
+	"last_error",
+	"created_at",
+}
+
+// unfinishedReports matches reports the Reporter has to generate again.
+var unfinishedReports = sq.Eq{"status": []domain.ReportStatus{domain.ReportStatusPending, domain.ReportStatusError}}
+
+type ReportsRepository struct {
+	pool *pgxpool.Pool
+	qb   sq.StatementBuilderType
+}
+
+func NewReportsRepository(pool *pgxpool.Pool) *ReportsRepository {
+	return &ReportsRepository{
+		pool: pool,
+		qb:   sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *ReportsRepository) Reports(
+	ctx context.Context,
+	source, fileName string,
+	limit, offset uint64,
+) ([]*domain.Report, int, error) {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Select("COUNT(*)").
+		From(TableReports).
+		Where(sq.Eq{"source": source, "file_name": fileName}).
+		ToSql()
+	if err != nil {
+		return nil, -1, createQueryError(err)
+	}
+
+	var total int
+	if err := db.QueryRow(ctx, sql, args...).Scan(&total); err != nil {
+		return nil, -1, scanRowError(err)
+	}
+
+	sql, args, err = r.qb.
+		Select(reportsColumns...).
+		From(TableReports).
+		Where(sq.Eq{"source": source, "file_name": fileName}).
+		OrderBy("unit_guid ASC").
+		Limit(limit).
+		Offset(offset).
+		ToSql()
+	if err != nil {
+		return nil, -1, createQueryError(err)
+	}
+
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, -1, executeQueryError(err)
+	}
+
+	reports, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByNameLax[domain.Report])
+	if err != nil {
+		return nil, -1, collectRowsError(err)
+	}
+
+	return reports, total, nil
+}
+
+// ScheduleReports replaces the reports of a file with pending ones, one per unit_guid of its devices,
+// and enqueues a report job for them. Called in the transaction that saves the file.
+func (r *ReportsRepository) ScheduleReports(ctx context.Context, source, fileName string) error {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Delete(TableReports).
+		Where(sq.Eq{"source": source, "file_name": fileName}).
+		ToSql()
+	if err != nil {
+		return createQueryError(err)
+	}
+
+	_, err = db.Exec(ctx, sql, args...)
+	if err != nil {
+		return executeQueryError(err)
+	}
+
+	// вложенный запрос собирается с "?", плейсхолдеры нумерует внешний
+	devices := sq.
+		Select("DISTINCT source", "file_name", "unit_guid").
+		Column("?", domain.ReportStatusPending).
+		From(TableDevices).
+		Where(sq.Eq{"source": source, "file_name": fileName}).
+		Where(sq.NotEq{"unit_guid": nil})
+
+	sql, args, err = r.qb.
+		Insert(TableReports).
+		Columns("source", "file_name", "unit_guid", "status").
+		Select(devices).
+		ToSql()
+	if err != nil {
+		return createQueryError(err)
+	}
+
+	_, err = db.Exec(ctx, sql, args...)
+	if err != nil {
+		return executeQueryError(err)
+	}
+
+	sql, args, err = r.qb.
+		Insert(TableReportJobs).
+		Columns("source", "file_name").
+		Values(source, fileName).
+		ToSql()
+	if err != nil {
+		return createQueryError(err)
+	}
+
+	_, err = db.Exec(ctx, sql, args...)
+	if err != nil {
+		return executeQueryError(err)
+	}
+
+	return nil
+}
+
+// PendingReports returns the reports of a file that are not generated yet or failed.
+func (r *ReportsRepository) PendingReports(ctx context.Context, source, fileName string) ([]*domain.Report, error) {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Select(reportsColumns...).
+		From(TableReports).
+		Where(sq.Eq{"source": source, "file_name": fileName}).
+		Where(unfinishedReports).
+		OrderBy("unit_guid ASC").
+		ToSql()
+	if err != nil {
+		return nil, createQueryError(err)
+	}
+
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, executeQueryError(err)
+	}
+
+	reports, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByNameLax[domain.Report])
+	if err != nil {
+		return nil, collectRowsError(err)
+	}
+
+	return reports, nil
+}
+
+func (r *ReportsRepository) UpdateReport(ctx context.Context, report *domain.Report) error {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Update(TableReports).
+		SetMap(map[string]any{
+			"version":       report.Version,
+			"files":         reportFiles(report.Files),
+			"status":        report.Status,
+			"error_message": report.ErrorMessage,
+			"generated_at":  report.GeneratedAt,
+		}).
+		Where(sq.Eq{
+			"source":    report.Source,
+			"file_name": report.FileName,
+			"unit_guid": report.UnitGUID,
+		}).
+		ToSql()
+	if err != nil {
+		return createQueryError(err)
+	}
+
+	tag, err := db.Exec(ctx, sql, args...)
+	if err != nil {
+		return executeQueryError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("report of %s in %s/%s does not exist", report.UnitGUID, report.Source, report.FileName)
+	}
+
+	return nil
+}
+
+// ReportVersions returns the history of a unit's reports, the latest first.
+func (r *ReportsRepository) ReportVersions(
+	ctx context.Context,
+	source, unitGUID string,
+	limit, offset uint64,
+) ([]*domain.ReportVersion, int, error) {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Select("COUNT(*)").
+		From(TableReportVersions).
+		Where(sq.Eq{"source": source, "unit_guid": unitGUID}).
+		ToSql()
+	if err != nil {
+		return nil, -1, createQueryError(err)
+	}
+
+	var total int
+	if err := db.QueryRow(ctx, sql, args...).Scan(&total); err != nil {
+		return nil, -1, scanRowError(err)
+	}
+
+	sql, args, err = r.qb.
+		Select(reportVersionsColumns...).
+		From(TableReportVersions).
+		Where(sq.Eq{"source": source, "unit_guid": unitGUID}).
+		OrderBy("version DESC").
+		Limit(limit).
+		Offset(offset).
+		ToSql()
+	if err != nil {
+		return nil, -1, createQueryError(err)
+	}
+
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, -1, executeQueryError(err)
+	}
+
+	versions, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByNameLax[domain.ReportVersion])
+	if err != nil {
+		return nil, -1, collectRowsError(err)
+	}
+
+	return versions, total, nil
+}
+
+// NextReportVersion returns the number the next report of the unit gets.
+func (r *ReportsRepository) NextReportVersion(ctx context.Context, source, unitGUID string) (int, error) {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Select("COALESCE(MAX(version), 0) + 1").
+		From(TableReportVersions).
+		Where(sq.Eq{"source": source, "unit_guid": unitGUID}).
+		ToSql()
+	if err != nil {
+		return 0, createQueryError(err)
+	}
+
+	var version int
+	if err := db.QueryRow(ctx, sql, args...).Scan(&version); err != nil {
+		return 0, scanRowError(err)
+	}
+
+	return version, nil
+}
+
+func (r *ReportsRepository) AddReportVersion(ctx context.Context, version *domain.ReportVersion) error {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Insert(TableReportVersions).
+		Columns(reportVersionsColumns...).
+		Values(
+			version.Source,
+			version.UnitGUID,
+			version.Version,
+			version.FileName,
+			reportFiles(version.Files),
+			version.GeneratedAt,
+		).
+		ToSql()
+	if err != nil {
+		return createQueryError(err)
+	}
+
+	_, err = db.Exec(ctx, sql, args...)
+	if err != nil {
+		return executeQueryError(err)
+	}
+
+	return nil
+}
+
+// MoveReportFile records that a report file of the source was moved from one path to another.
+func (r *ReportsRepository) MoveReportFile(ctx context.Context, source, from, to string) error {
+	db := extractDB(ctx, r.pool)
+
+	files := `(
+		SELECT jsonb_agg(CASE WHEN f->>'path' = $2 THEN jsonb_set(f, '{path}', to_jsonb($3::text)) ELSE f END ORDER BY i)
+		FROM jsonb_array_elements(files) WITH ORDINALITY AS t(f, i)
+	)`
+	where := `source = $1 AND files @> jsonb_build_array(jsonb_build_object('path', $2::text))`
+
+	// путь меняется и в истории, и в отчётах файлов одним запросом, чтобы они не разошлись
+	sql := fmt.Sprintf(
+		"WITH versions AS (UPDATE %s SET files = %s WHERE %s) UPDATE %s SET files = %s WHERE %s",
+		TableReportVersions, files, where,
+		TableReports, files, where,
+	)
+
+	_, err := db.Exec(ctx, sql, source, from, to)
+	if err != nil {
+		return executeQueryError(err)
+	}
+
+	return nil
+}
+
+// DueReportJobs returns up to limit jobs that are due and have attempts left, oldest first.
+func (r *ReportsRepository) DueReportJobs(ctx context.Context, limit uint64, maxAttempts int) ([]*domain.ReportJob, error) {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Select(reportJobsColumns...).
+		From(TableReportJobs).
+		Where(sq.Expr("available_at <= NOW()")).
+		Where(sq.Lt{"attempts": maxAttempts}).
+		OrderBy("id ASC").
+		Limit(limit).
+		ToSql()
+	if err != nil {
+		return nil, createQueryError(err)
+	}
+
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, executeQueryError(err)
+	}
+
+	jobs, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByNameLax[domain.ReportJob])
+	if err != nil {
+		return nil, collectRowsError(err)
+	}
+
+	return jobs, nil
+}
+
+func (r *ReportsRepository) AckReportJob(ctx context.Context, id int64) error {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Delete(TableReportJobs).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return createQueryError(err)
+	}
+
+	_, err = db.Exec(ctx, sql, args...)
+	if err != nil {
+		return executeQueryError(err)
+	}
+
+	return nil
+}
+
+// RetryReportJob counts a failed attempt and postpones the job until retryAt.
+func (r *ReportsRepository) RetryReportJob(ctx context.Context, id int64, retryAt time.Time, reason string) error {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Update(TableReportJobs).
+		Set("attempts", sq.Expr("attempts + 1")).
+		Set("last_error", reason).
+		Set("available_at", retryAt).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return createQueryError(err)
+	}
+
+	_, err = db.Exec(ctx, sql, args...)
+	if err != nil {
+		return executeQueryError(err)
+	}
+
+	return nil
+}
+
+// ResetReportJobs makes every job due again with its attempts restored.
+func (r *ReportsRepository) ResetReportJobs(ctx context.Context) error {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Update(TableReportJobs).
+		Set("attempts", 0).
+		Set("available_at", sq.Expr("NOW()")).
+		ToSql()
+	if err != nil {
+		return createQueryError(err)
+	}
+
+	_, err = db.Exec(ctx, sql, args...)
+	if err != nil {
+		return executeQueryError(err)
+	}
+
+	return nil
+}
+
+// reportFiles keeps a report without files an empty JSON array rather than NULL.
+func reportFiles(files []*domain.ReportFile) []*domain.ReportFile {
+	if files == nil {
+		return []*domain.ReportFile{}
+	}
+
+	return files
+}
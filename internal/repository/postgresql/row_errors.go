@@ -0,0 +1,131 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kurochkinivan/device_reporter/internal/domain"
+)
+
+const TableRowErrors = "row_errors"
+
+type RowErrorsRepository struct {
+	pool *pgxpool.Pool
+	qb   sq.StatementBuilderType
+}
+
+func NewRowErrorsRepository(pool *pgxpool.Pool) *RowErrorsRepository {
+	return &RowErrorsRepository{
+		pool: pool,
+		qb:   sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *RowErrorsRepository) RowErrors(
+	ctx context.Context,
+	source, fileName string,
+	limit, offset uint64,
+) ([]*domain.RowError, int, error) {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Select("COUNT(*)").
+		From(TableRowErrors).
+		Where(sq.Eq{"source": source, "file_name": fileName}).
+		ToSql()
+	if err != nil {
+		return nil, -1, createQueryError(err)
+	}
+
+	var total int
+	if err := db.QueryRow(ctx, sql, args...).Scan(&total); err != nil {
+		return nil, -1, scanRowError(err)
+	}
+
+	sql, args, err = r.qb.
+		Select(
+			"line",
+			"column_name",
+			"value",
+			"reason",
+			"rule",
+		).
+		From(TableRowErrors).
+		Where(sq.Eq{"source": source, "file_name": fileName}).
+		OrderBy("line ASC", "id ASC").
+		Limit(limit).
+		Offset(offset).
+		ToSql()
+	if err != nil {
+		return nil, -1, createQueryError(err)
+	}
+
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, -1, executeQueryError(err)
+	}
+
+	rowErrors, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByNameLax[domain.RowError])
+	if err != nil {
+		return nil, -1, collectRowsError(err)
+	}
+
+	return rowErrors, total, nil
+}
+
+func (r *RowErrorsRepository) ReplaceRowErrors(
+	ctx context.Context,
+	source, fileName string,
+	rowErrors []*domain.RowError,
+) error {
+	db := extractDB(ctx, r.pool)
+
+	sql, args, err := r.qb.
+		Delete(TableRowErrors).
+		Where(sq.Eq{"source": source, "file_name": fileName}).
+		ToSql()
+	if err != nil {
+		return createQueryError(err)
+	}
+
+	_, err = db.Exec(ctx, sql, args...)
+	if err != nil {
+		return executeQueryError(err)
+	}
+
+	if len(rowErrors) == 0 {
+		return nil
+	}
+
+	copied, err := db.CopyFrom(ctx, pgx.Identifier{TableRowErrors}, []string{
+		"source",
+		"file_name",
+		"line",
+		"column_name",
+		"value",
+		"reason",
+		"rule",
+	}, pgx.CopyFromSlice(len(rowErrors), func(i int) ([]any, error) {
+		return []any{
+			source,
+			fileName,
+			rowErrors[i].Line,
+			rowErrors[i].Column,
+			rowErrors[i].Value,
+			rowErrors[i].Reason,
+			rowErrors[i].Rule,
+		}, nil
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to save row errors: %w", err)
+	}
+
+	if copied != int64(len(rowErrors)) {
+		return fmt.Errorf("failed to save row errors: copied %d rows, expected %d", copied, len(rowErrors))
+	}
+
+	return nil
+}